@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// WithArgsFiles makes the program replace arguments of the form "@path" with
+// the list of arguments read from the file at path, before they get dispatched
+// to the commands, so the file may contain the name of a sub-command of a
+// CommandSet as well as its options:
+//
+//	$ cat args.txt
+//	deploy --region us-west-2
+//	  --replicas 3
+//	$ prog @args.txt
+//
+// The files are read like the ones of commands with ArgsFiles enabled, and the
+// same restriction applies to fields loading their values from files (see the
+// "file" struct tag), which must use the --flag=@path form.
+func WithArgsFiles() ExecOption {
+	return func(o *execOptions) { o.argsFiles = true }
+}
+
+// expandArgsFiles replaces each argument of the form "@path" with the list of
+// arguments read from the file at path. Arguments found after a "--" separator
+// are not expanded.
+func expandArgsFiles(args []string) ([]string, error) {
+	var expanded []string
+
+	for i, arg := range args {
+		if isCommandSeparator(arg) {
+			return append(expanded, args[i:]...), nil
+		}
+
+		if len(arg) < 2 || arg[0] != '@' {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		b, err := os.ReadFile(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := splitArgs(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("reading arguments from %q: %w", arg[1:], err)
+		}
+
+		expanded = append(expanded, a...)
+	}
+
+	return expanded, nil
+}
+
+// splitArgs splits s into a list of arguments, following a subset of the rules
+// applied by POSIX shells: arguments are separated by white spaces (including
+// new lines), may be quoted with single or double quotes to contain spaces,
+// and characters may be escaped with a backslash outside of single quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var inArg bool
+	var quote rune
+	var escape bool
+
+	for _, c := range s {
+		switch {
+		case escape:
+			arg.WriteRune(c)
+			escape = false
+
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+
+		case quote == '"':
+			switch c {
+			case '"':
+				quote = 0
+			case '\\':
+				escape = true
+			default:
+				arg.WriteRune(c)
+			}
+
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+
+		case c == '\\':
+			escape, inArg = true, true
+
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	switch {
+	case quote != 0:
+		return nil, errors.New("unterminated quoted string")
+	case escape:
+		return nil, errors.New("unterminated escape sequence")
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"", nil},
+		{"   \n\t", nil},
+		{"a b c", []string{"a", "b", "c"}},
+		{"--name Luke\n--planet Tatooine\n", []string{"--name", "Luke", "--planet", "Tatooine"}},
+		{`"hello world" 'it''s'`, []string{"hello world", "its"}},
+		{`"say \"hi\"" 'a\b'`, []string{`say "hi"`, `a\b`}},
+		{`hello\ world ""`, []string{"hello world", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			out, err := splitArgs(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out, test.out) {
+				t.Errorf("got %q, want %q", out, test.out)
+			}
+		})
+	}
+}
+
+func TestSplitArgsError(t *testing.T) {
+	for _, in := range []string{`"hello`, `'hello`, `hello\`} {
+		if _, err := splitArgs(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// WithBugReport makes the program ask users to report the bugs that they run
+// into at url, when a command panics or returns an error wrapped by Unexpected.
+// The message is rendered by BugReportTemplate after the error, and includes
+// the version of the program, the platform it runs on, and the path of the
+// command which failed, for example:
+//
+//	This is a bug in prog, please report it at:
+//	  https://github.com/example/prog/issues/new
+//
+//	Include this information in the report:
+//	  version: v1.2.3
+//	  go:      go1.21.0 linux/amd64
+//	  command: prog deploy
+//
+// The arguments of the command are not included by default, since they may
+// carry sensitive values, see WithBugReportArgs.
+//
+// The option enables the recovery of panics, as WithPanicRecovery does.
+func WithBugReport(url string) ExecOption {
+	return func(o *execOptions) {
+		o.bugReportURL = url
+		o.recoverPanics = true
+	}
+}
+
+// WithBugReportArgs includes the arguments that the program was called with in
+// the bug reports enabled by WithBugReport.
+func WithBugReportArgs() ExecOption {
+	return func(o *execOptions) { o.bugReportArgs = true }
+}
+
+// BugReport carries the information rendered by BugReportTemplate.
+type BugReport struct {
+	// The address where bugs are reported, set with WithBugReport.
+	URL string
+	// The name of the program, and its version (see WithVersion).
+	Program string
+	Version string
+	// The revision of the commit that the program was built from, which is
+	// empty if it is unknown.
+	Commit string
+	// The version of the Go runtime, and the platform the program runs on.
+	GoVersion string
+	OS        string
+	Arch      string
+	// The path of the command which failed, like "prog deploy".
+	Command string
+	// The arguments of the program, which are only set with the option
+	// WithBugReportArgs.
+	Args []string
+	// The error that the bug is reported for.
+	Err error
+}
+
+// BugReportTemplate is the template rendering the bug reports printed by the
+// programs called with WithBugReport. It may be replaced to customize the
+// messages, for example to link to an issue form prefilled with the report.
+var BugReportTemplate = template.Must(template.New("bug-report").Parse(`
+This is a bug in {{.Program}}, please report it at:
+  {{.URL}}
+
+Include this information in the report:
+  version: {{.Version}}{{if .Commit}}
+  commit:  {{.Commit}}{{end}}
+  go:      {{.GoVersion}} {{.OS}}/{{.Arch}}{{if .Command}}
+  command: {{.Command}}{{end}}{{if .Args}}
+  args:   {{range .Args}} {{printf "%q" .}}{{end}}{{end}}
+`))
+
+// Unexpected wraps err to mark it as an unexpected error, which reveals a bug
+// of the program rather than a failure of the command, like a broken internal
+// invariant. The program prints the bug report enabled by WithBugReport after
+// these errors. Unexpected returns nil when err is nil.
+func Unexpected(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unexpectedError{err}
+}
+
+type unexpectedError struct{ err error }
+
+func (e *unexpectedError) Error() string { return e.err.Error() }
+
+func (e *unexpectedError) Unwrap() error { return e.err }
+
+// writeBugReport renders BugReportTemplate for err to w.
+func writeBugReport(w io.Writer, o *execOptions, program string, args []string, err error) {
+	var version string
+	if o.version != nil {
+		version = *o.version
+	}
+	version, commit, _ := buildInfo(version)
+
+	if program == "" {
+		program = filepath.Base(os.Args[0])
+	}
+
+	report := BugReport{
+		URL:       o.bugReportURL,
+		Program:   program,
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Err:       err,
+	}
+	if path := o.commandPath(); len(path) != 0 {
+		report.Command = strings.Join(path, " ")
+	}
+	if o.bugReportArgs {
+		report.Args = args
+	}
+
+	BugReportTemplate.Execute(w, report)
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBugReport(t *testing.T) {
+	tests := []struct {
+		scenario string
+		cmd      Function
+		options  []ExecOption
+		report   bool
+	}{
+		{
+			scenario: "panic",
+			cmd:      Command(func(struct{}) { panic("oops") }),
+			report:   true,
+		},
+		{
+			scenario: "unexpected error",
+			cmd:      Command(func(struct{}) error { return Unexpected(errors.New("oops")) }),
+			report:   true,
+		},
+		{
+			scenario: "unexpected error with arguments",
+			cmd:      Command(func(struct{}) error { return Unexpected(errors.New("oops")) }),
+			options:  []ExecOption{WithBugReportArgs()},
+			report:   true,
+		},
+		{
+			scenario: "expected error",
+			cmd:      Command(func(struct{}) error { return errors.New("oops") }),
+			report:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			stderr := new(strings.Builder)
+			options := append([]ExecOption{
+				WithBugReport("https://example.com/issues"),
+				WithVersion("v1.2.3"),
+				WithStderr(stderr),
+			}, test.options...)
+
+			cmd := NamedCommand("prog", CommandSet{"deploy": test.cmd})
+			call(context.TODO(), cmd, []string{"deploy"}, makeExecOptions(options))
+			output := stderr.String()
+
+			if !test.report {
+				if strings.Contains(output, "report") {
+					t.Errorf("unexpected bug report:\n%s", output)
+				}
+				return
+			}
+
+			for _, s := range []string{
+				"This is a bug in prog, please report it at:\n  https://example.com/issues\n",
+				"  version: v1.2.3\n",
+				"  command: prog deploy\n",
+			} {
+				if !strings.Contains(output, s) {
+					t.Errorf("missing %q in the bug report:\n%s", s, output)
+				}
+			}
+
+			withArgs := len(test.options) != 0
+			if strings.Contains(output, "  args:    \"deploy\"\n") != withArgs {
+				t.Errorf("the arguments were not reported as expected:\n%s", output)
+			}
+		})
+	}
+}
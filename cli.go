@@ -3,18 +3,51 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Err is used by the Exec and Call functions to print out errors returned by
 // the commands they call out to.
 var Err io.Writer = os.Stderr
 
+// Out is used by the Exec and Call functions to print the output of commands:
+// the help messages that users requested with -h or --help, the values
+// returned by commands, and the version of programs. It is also the default
+// Stdout of the Streams passed to commands, and the output of the printers
+// returned by Format and FormatList when they are given a nil writer.
+//
+// Out is nil by default, so the output is written to the os.Stdout of the time
+// it is printed, which Go examples replace while they run. Tests and examples
+// can set Out to capture the output of programs without replacing os.Stdout.
+var Out io.Writer
+
+// Stdout returns the writer that programs print their output to, which is Out
+// unless it is nil, in which case it is the current os.Stdout.
+func Stdout() io.Writer {
+	if Out != nil {
+		return Out
+	}
+	return os.Stdout
+}
+
+// Stderr returns the writer that programs print their errors to, which is Err
+// unless it is nil, in which case it is os.Stderr.
+func Stderr() io.Writer {
+	if Err != nil {
+		return Err
+	}
+	return os.Stderr
+}
+
 // The Function interface is implemented by commands that may be invoked with
 // argument and environment variable lists.
 //
@@ -43,10 +76,217 @@ func Exec(cmd Function) {
 
 // ExecContext calls Exec but with a specified context.Context.
 func ExecContext(ctx context.Context, cmd Function) {
+	os.Exit(exec(ctx, cmd, nil))
+}
+
+// ExecWith is like Exec, but accepts a list of options to configure how the
+// program is executed.
+//
+// The ExecWith function never returns, unless the function set by WithExit
+// does.
+func ExecWith(cmd Function, options ...ExecOption) {
+	o := makeExecOptions(options)
+	o.exitProgram(exec(context.TODO(), cmd, o))
+}
+
+// ExecOption is the type of functional options accepted by ExecWith.
+type ExecOption func(*execOptions)
+
+// WithArgs sets the command-line arguments that the program is called with, in
+// place of os.Args[1:] (or of the arguments passed to CallWith). The name of
+// the program is still taken from os.Args[0].
+func WithArgs(args ...string) ExecOption {
+	return func(o *execOptions) { o.args = append([]string{}, args...) }
+}
+
+// WithEnv sets the environment variables that the program is called with, in
+// place of os.Environ(). The variables are formatted as "KEY=VALUE", and are
+// filtered by the prefix of the program like the ones of the process. The
+// variables configuring the package, like NO_COLOR, COLUMNS, and
+// XDG_CONFIG_HOME, are looked up in env as well.
+func WithEnv(env ...string) ExecOption {
+	return func(o *execOptions) { o.env = append([]string{}, env...) }
+}
+
+// WithEnvLookup sets the function looking up the environment variables of the
+// program, in place of os.Environ(), for example to load the variables from a
+// secret manager. The function receives the full names of the variables,
+// including the prefix of the program, and returns false when a variable is
+// not set.
+//
+// When combined with WithEnv, the variables of the list take precedence over
+// the ones returned by the function.
+func WithEnvLookup(lookup func(name string) (string, bool)) ExecOption {
+	return func(o *execOptions) { o.envLookup = lookup }
+}
+
+// WithCaseInsensitiveEnv makes the program match the names of environment
+// variables regardless of their case, so PROG_FLAG may also be set as
+// prog_flag or PROG_flag. This is always the case on Windows, where the names
+// of environment variables are case-insensitive.
+func WithCaseInsensitiveEnv() ExecOption {
+	return func(o *execOptions) { o.envFold = true }
+}
+
+// WithExit sets the function that ExecWith calls with the exit code of the
+// program, in place of os.Exit.
+func WithExit(exit func(int)) ExecOption {
+	return func(o *execOptions) { o.exit = exit }
+}
+
+// WithStdout sets the writer that the program prints its output to, in place
+// of Out. It is also the default Stdout of the Streams passed to commands.
+func WithStdout(w io.Writer) ExecOption {
+	return func(o *execOptions) { o.stdout = w }
+}
+
+// WithStderr sets the writer that the program prints its errors to, in place
+// of Err. It is also the default Stderr of the Streams passed to commands.
+func WithStderr(w io.Writer) ExecOption {
+	return func(o *execOptions) { o.stderr = w }
+}
+
+// WithEnvPrefix sets the prefix of the environment variables that the program
+// loads its configuration from. This option takes precedence over prefixes
+// set in commands, and over the default prefix derived from the program name.
+//
+// An underscore is added to separate the prefix from the names of the
+// variables, so a prefix of "MYAPP" matches variables like MYAPP_VERBOSE. When
+// the prefix is empty, variables are matched without any prefix.
+func WithEnvPrefix(prefix string) ExecOption {
+	return func(o *execOptions) { o.envPrefix = &prefix }
+}
+
+// WithConfigDiscovery enables the automatic discovery of configuration files
+// for commands which were not given one explicitly (see the "config" struct
+// tag in Command). The file is searched in these locations, the first one
+// found being loaded:
+//
+//	$XDG_CONFIG_HOME/<program>/config.yaml
+//	~/.config/<program>/config.yaml
+//
+// The files may also have a ".yml" or ".json" extension.
+func WithConfigDiscovery() ExecOption {
+	return func(o *execOptions) { o.configDiscovery = true }
+}
+
+// WithCommandPrefixes allows the commands of command sets to be called with an
+// unambiguous prefix of their name, for example "prog stat" calls the "status"
+// command if no other commands start with "stat". An error listing the
+// candidates is returned when the prefix matches more than one command.
+func WithCommandPrefixes() ExecOption {
+	return func(o *execOptions) { o.commandPrefixes = true }
+}
+
+// WithNestedEnvPrefixes scopes environment variables to the commands of
+// command sets: in addition to the variables prefixed with the program name,
+// the commands accept variables prefixed with the path of command names which
+// led to them. For example, the --flag option of "prog sub" may be set with
+// PROG_SUB_FLAG, which takes precedence over PROG_FLAG. This avoids collisions
+// between commands which have flags of the same name.
+func WithNestedEnvPrefixes() ExecOption {
+	return func(o *execOptions) { o.nestedEnv = true }
+}
+
+// execOptions carries the settings of a program execution down the tree of
+// commands that it calls, via the context.
+type execOptions struct {
+	// origin is the context that the program was called with, before the
+	// options were attached to it.
+	origin          context.Context
+	program         string
+	args            []string
+	env             []string
+	envLookup       func(string) (string, bool)
+	envFold         bool
+	exit            func(int)
+	stdout          io.Writer
+	stderr          io.Writer
+	signals         []os.Signal
+	shutdownTimeout time.Duration
+	envPrefix       *string
+	configDiscovery bool
+	version         *string
+	commandPrefixes bool
+	nestedEnv       bool
+	timeout         bool
+	verbosity       bool
+	profiling       bool
+	printConfig     bool
+	bugReportURL    string
+	bugReportArgs   bool
+	recoverPanics   bool
+	updateCheck     func(context.Context, string) (string, error)
+	updateInterval  time.Duration
+	completion      bool
+	argsFiles       bool
+	usagePrinter    UsagePrinter
+	errorPrinter    ErrorPrinter
+	color           bool
+	streams         Streams
+	tracer          Tracer
+	reporter        func(Report)
+	middleware      []func(Function) Function
+	// path is the path of the last command dispatched to, which is recorded
+	// for the reporter, bug reports, and the messages of the shutdown timeout.
+	// It holds a []string, and is read by the signal handler concurrently with
+	// the command. It is a pointer so the copies of the options made by Repl
+	// share it, since an atomic.Value must not be copied once used.
+	path *atomic.Value
+	// environPrefix is the prefix stripped from the names of the environment
+	// variables passed to the program.
+	environPrefix string
+}
+
+type execOptionsKey struct{}
+
+// commandPath returns the path of the last command that the program dispatched
+// to, starting with the program name.
+func (o *execOptions) commandPath() []string {
+	if o.path == nil {
+		return nil
+	}
+	path, _ := o.path.Load().([]string)
+	return path
+}
+
+// outputs returns the writers that the program prints its output and errors
+// to, which default to Stdout() and Stderr().
+func (o *execOptions) outputs() (stdout, stderr io.Writer) {
+	stdout, stderr = o.stdout, o.stderr
+	if stdout == nil {
+		stdout = Stdout()
+	}
+	if stderr == nil {
+		stderr = Stderr()
+	}
+	return stdout, stderr
+}
+
+func makeExecOptions(options []ExecOption) *execOptions {
+	o := &execOptions{path: new(atomic.Value)}
+	for _, opt := range options {
+		opt(o)
+	}
+	return o
+}
+
+// execOptionsOf returns the options attached to ctx, or a zero value if there
+// were none.
+func execOptionsOf(ctx context.Context) *execOptions {
+	if ctx != nil {
+		if o, ok := ctx.Value(execOptionsKey{}).(*execOptions); ok {
+			return o
+		}
+	}
+	return &execOptions{origin: ctx, path: new(atomic.Value)}
+}
+
+func exec(ctx context.Context, cmd Function, options *execOptions) int {
 	name := filepath.Base(os.Args[0])
 	args := os.Args[1:]
 	prog := NamedCommand(name, cmd)
-	os.Exit(CallContext(ctx, prog, args...))
+	return call(ctx, prog, args, options)
 }
 
 // Call calls cmd with args and environment variables prefixed with the
@@ -72,41 +312,326 @@ func Call(cmd Function, args ...string) int {
 
 // CallContext calls Call but with a specified context.Context.
 func CallContext(ctx context.Context, cmd Function, args ...string) int {
-	prefix := strings.ToUpper(snakecase(nameOf(cmd)))
-	if prefix != "" {
-		prefix = prefix + "_"
+	return call(ctx, cmd, args, nil)
+}
+
+// CallErr is like CallContext, but returns the error of the command instead of
+// printing it to Err, for programs which embed commands and handle their errors
+// themselves. The error is a *Help when users requested the help of the
+// command, or a *Usage when the command line was invalid, which can be printed
+// with "%v" to show the messages that Call would print.
+//
+// The exit code is the one that Call would return, for example it is zero when
+// help was requested, or the code carried by errors created with Exit.
+func CallErr(ctx context.Context, cmd Function, args ...string) (int, error) {
+	return callErr(ctx, cmd, args, nil)
+}
+
+func call(ctx context.Context, cmd Function, args []string, options *execOptions) int {
+	var updates <-chan string
+	if options != nil {
+		if _, stderr := options.outputs(); updateCheckEnabled(options, stderr) {
+			updates = startUpdateCheck(ctx, options, nameOf(cmd))
+		}
 	}
 
-	code, err := cmd.Call(ctx, args, environ(prefix))
+	code, err := callErr(ctx, cmd, args, options)
+	if options == nil {
+		options = execOptionsOf(ctx)
+	}
+	if options.args != nil {
+		args = options.args
+	}
+	stdout, stderr := options.outputs()
+	program := options.program
+	if program == "" {
+		program = nameOf(cmd)
+	}
 
-	switch err.(type) {
+	switch x := callError(err).(type) {
 	case nil:
 	case *Help, *Usage:
-		fmt.Fprintln(Err, err)
+		// Help requested by users is printed to the standard output so it
+		// can be piped to other programs, only usage errors are failures.
+		w := stderr
+		if _, ok := x.(*Help); ok {
+			w = stdout
+		}
+		if options.usagePrinter != nil {
+			options.usagePrinter(w, x)
+			break
+		}
+		c := colors(options.color && options.useColors(w, args))
+		fmt.Fprintf(w, c.verb(options.helpWidth(w))+"\n", x)
+	case *panicError:
+		fmt.Fprintf(stderr, "%s\n\n%s", x, x.stack)
+		if options.bugReportURL != "" {
+			writeBugReport(stderr, options, program, args, err)
+		}
 	default:
-		if err != nil {
-			errorLogger := log.New(Err, "", log.LstdFlags)
-			errorLogger.Print(err)
-			code = 1
+		if err.Error() != "" {
+			printError := options.errorPrinter
+			if printError == nil {
+				printError = PrintError
+			}
+			printError(stderr, program, err)
+		}
+		var unexpected *unexpectedError
+		if options.bugReportURL != "" && errors.As(err, &unexpected) {
+			writeBugReport(stderr, options, program, args, err)
 		}
 	}
 
+	if updates != nil {
+		printUpdateNotice(stderr, updates)
+	}
 	return code
 }
 
-func environ(prefix string) []string {
-	env := os.Environ()
+// callErr calls cmd like call, but returns the error of the command instead of
+// printing it. The exit code is the one that the program terminates with.
+func callErr(ctx context.Context, cmd Function, args []string, options *execOptions) (int, error) {
+	// The context is left untouched when no options were set, so commands
+	// receive the exact context that the program was called with.
+	if options != nil {
+		options.origin = ctx
+		options.program = nameOf(cmd)
+		ctx = context.WithValue(ctx, execOptionsKey{}, options)
+		if options.completion {
+			cmd = withCompletionCommand(cmd, options.program)
+		}
+		if options.version != nil {
+			cmd = withVersion(cmd, options.program, *options.version)
+		}
+		if options.timeout {
+			cmd = withTimeoutFlag(cmd)
+		}
+		if options.verbosity {
+			cmd = withVerbosityFlags(cmd)
+		}
+		if options.profiling {
+			cmd = withProfilingFlags(cmd)
+		}
+		if options.printConfig {
+			cmd = withPrintConfigFlag(cmd)
+		}
+		if options.color {
+			cmd = withColorFlag(cmd)
+		}
+		for _, m := range options.middleware {
+			cmd = m(cmd)
+		}
+		// Completions are answered before calling the middleware, which may
+		// have side effects or print to the outputs of the program.
+		if options.completion {
+			cmd = withCompletion(cmd)
+		}
+		if len(options.signals) != 0 {
+			var stop func()
+			ctx, stop = notifySignals(ctx, options)
+			defer stop()
+		}
+	} else {
+		options = execOptionsOf(ctx)
+	}
+
+	prefix, ok := envPrefixOf(cmd)
+	if options.envPrefix != nil {
+		prefix, ok = *options.envPrefix, true
+	}
+	if !ok {
+		prefix = strings.ToUpper(snakecase(nameOf(cmd)))
+	}
+	if prefix = strings.TrimSuffix(prefix, "_"); prefix != "" {
+		prefix = prefix + "_"
+	}
+	options.environPrefix = prefix
+
+	if options.args != nil {
+		args = options.args
+	}
+	if options.argsFiles {
+		a, err := expandArgsFiles(args)
+		if err != nil {
+			return 1, &Usage{Cmd: cmd, Err: err}
+		}
+		args = a
+	}
+	env := options.env
+	if env == nil && options.envLookup == nil {
+		env = os.Environ()
+	}
+
+	start := time.Now()
+	env = environ(env, prefix, options.caseInsensitiveEnv())
+	run := func(ctx context.Context) (int, error) {
+		if options.recoverPanics {
+			return callRecover(ctx, cmd, args, env)
+		}
+		return cmd.Call(ctx, args, env)
+	}
+
+	var code int
+	var err error
+	if commandsOf(cmd) == nil {
+		// The program is made of a single command, which is recorded in a
+		// span like the commands that command sets dispatch to.
+		code, err = trace(ctx, run)
+	} else {
+		code, err = run(ctx)
+	}
+
+	switch x := callError(err).(type) {
+	case nil:
+	case *Help:
+		// Help requested by users is not a failure.
+		code = 0
+	case *Usage:
+	case *panicError:
+		code = x.ExitCode()
+	default:
+		code = 1
+		var e ExitCoder
+		if errors.As(err, &e) {
+			code = e.ExitCode()
+		}
+	}
+
+	if options.reporter != nil {
+		options.reporter(Report{
+			Path:     options.commandPath(),
+			Duration: time.Since(start),
+			Code:     code,
+			Err:      err,
+			Category: errorCategoryOf(err),
+		})
+	}
+
+	return code, err
+}
+
+// lookupEnvContext looks up the variable name in env, then with the function
+// set by WithEnvLookup on the program called with ctx. The function is called
+// with the prefix of the program, and with the nested prefixes of commands
+// first when they are enabled, like the variables of env.
+func lookupEnvContext(ctx context.Context, name string, env []string) (string, bool) {
+	o := execOptionsOf(ctx)
+	if v, ok := lookupEnv(name, env, o.caseInsensitiveEnv()); ok {
+		return v, true
+	}
+
+	if o.envLookup == nil {
+		return "", false
+	}
+
+	scopes := envScopesOf(ctx)
+	for i := len(scopes); i >= 0; i-- {
+		prefix := o.environPrefix
+		for _, scope := range scopes[:i] {
+			prefix += strings.ToUpper(snakecase(scope)) + "_"
+		}
+		if v, ok := o.envLookup(prefix + name); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// callError returns the *Help, *Usage, or panic error wrapped in err, so they
+// are handled the same when commands wrap them with fmt.Errorf and %w. Other
+// errors are returned unchanged.
+func callError(err error) error {
+	var help *Help
+	if errors.As(err, &help) {
+		return help
+	}
+	var usage *Usage
+	if errors.As(err, &usage) {
+		return usage
+	}
+	var panicked *panicError
+	if errors.As(err, &panicked) {
+		return panicked
+	}
+	return err
+}
+
+// caseInsensitiveEnv returns true if the names of environment variables must
+// be matched regardless of their case, which is always true on Windows.
+func (o *execOptions) caseInsensitiveEnv() bool {
+	return o.envFold || runtime.GOOS == "windows"
+}
+
+// programEnv returns the environment variables of the program, which are the
+// ones set with WithEnv, or the ones of the process.
+func (o *execOptions) programEnv() []string {
+	if o.env != nil {
+		return o.env
+	}
+	return os.Environ()
+}
+
+// getenv returns the value of the environment variable name of the program,
+// which is used for the variables configuring the package, like NO_COLOR.
+func (o *execOptions) getenv(name string) string {
+	v, _ := lookupEnv(name, o.programEnv(), o.caseInsensitiveEnv())
+	return v
+}
+
+// environ returns the variables of env which start with prefix, with the
+// prefix removed. The case of the prefix is ignored if fold is true.
+func environ(env []string, prefix string, fold bool) []string {
 	ret := make([]string, 0, len(env))
 
 	for _, e := range env {
-		if strings.HasPrefix(e, prefix) {
-			ret = append(ret, strings.TrimPrefix(e, prefix))
+		if name, ok := cutEnvPrefix(e, prefix, fold); ok {
+			ret = append(ret, name)
 		}
 	}
 
 	return ret
 }
 
+// Parse decodes args and env into the configuration struct pointed to by dst,
+// applying the same rules as commands created by Command: flags are declared
+// with struct tags, values missing from the command line are loaded from the
+// environment variables in env (formatted as "KEY=VALUE", without prefix), and
+// fields which were not set are assigned their default values.
+//
+// This makes it possible to reuse the decoding of commands for other purposes
+// than invoking functions, like loading the configuration of a daemon in a
+// test. The struct pointed to by dst is overwritten entirely.
+//
+// The returned error is a *Usage if the arguments were invalid, or a *Help if
+// args contained -h or --help.
+//
+// The function panics if dst is not a pointer to a struct.
+func Parse(dst interface{}, args, env []string) error {
+	_, err := assignCommand("cli.Parse", dst).Call(context.TODO(), args, env)
+	return err
+}
+
+// assignCommand returns a command which assigns the configuration it receives
+// to the struct pointed to by dst. The function panics if dst is not a pointer
+// to a struct, using caller as prefix of the panic message.
+//
+// The command accepts a context.Context, so it may be called with the context
+// of programs whose commands use one.
+func assignCommand(caller string, dst interface{}) *CommandFunc {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("%s: destination must be a pointer to a struct, got %T", caller, dst))
+	}
+
+	t := reflect.FuncOf([]reflect.Type{contextType, v.Elem().Type()}, nil, false)
+	f := reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		v.Elem().Set(in[1])
+		return nil
+	})
+
+	return &CommandFunc{Func: f.Interface(), internal: true}
+}
+
 // Help values are returned by commands to indicate to the caller that it was
 // called with a configuration that requested a help message rather than
 // executing the command. This type satisfies the error interface.
@@ -125,8 +650,7 @@ func (h *Help) Error() string { return fmt.Sprintf("help: %s", h.Cmd) }
 func (h *Help) Format(w fmt.State, v rune) {
 	switch v {
 	case 's':
-		printUsage(w, h.Cmd)
-		printHelp(w, h.Cmd)
+		writeHelp(w, h.Cmd, nil, colorsOf(w), helpWidthOf(w))
 	case 'v':
 		if w.Flag('#') {
 			io.WriteString(w, "cli.Help{")
@@ -134,8 +658,7 @@ func (h *Help) Format(w fmt.State, v rune) {
 			io.WriteString(w, "}")
 			return
 		}
-		printUsage(w, h.Cmd)
-		printHelp(w, h.Cmd)
+		writeHelp(w, h.Cmd, nil, colorsOf(w), helpWidthOf(w))
 	default:
 		// fall back to default struct formatter. TODO this does not handle
 		// flags
@@ -146,6 +669,10 @@ func (h *Help) Format(w fmt.State, v rune) {
 // Usage values are returned by commands to indicate that the combination of
 // arguments and environment variables they were called with was invalid. This
 // type satisfies the error interface.
+//
+// When the command line has problems, Err carries all of them; programs can
+// use errors.As to look for values like *ErrUnknownFlag or *ErrMissingRequired
+// instead of matching error messages.
 type Usage struct {
 	Cmd Function
 	Err error
@@ -165,36 +692,8 @@ func (u *Usage) Format(w fmt.State, v rune) {
 		return
 	}
 	// TODO: better detection/printing based on the requested format string.
-	if u.Cmd != nil {
-		printUsage(w, u.Cmd)
-		printHelp(w, u.Cmd)
-	}
-	if u.Err != nil {
-		printError(w, u.Err)
-	}
+	writeHelp(w, u.Cmd, u.Err, colorsOf(w), helpWidthOf(w))
 }
 
 // Unwrap satisfies the errors wrapper interface.
 func (u *Usage) Unwrap() error { return u.Err }
-
-func printUsage(w io.Writer, cmd Function) {
-	const format = `
-Usage:
-  %s
-
-`
-	fmt.Fprintf(w, format, cmd)
-}
-
-func printHelp(w io.Writer, cmd Function) {
-	fmt.Fprintf(w, "%v", cmd)
-}
-
-func printError(w io.Writer, err error) {
-	const format = `
-Error:
-  %s
-
-`
-	fmt.Fprintf(w, format, err)
-}
@@ -3,15 +3,22 @@ package cli_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/segmentio/cli"
+	"github.com/segmentio/cli/human"
 )
 
 func ExampleCommand_bool() {
@@ -250,6 +257,91 @@ func ExampleCommand_binaryUnmarshaler() {
 	// http://www.segment.com/
 }
 
+func ExampleCommand_textUnmarshaler_slice() {
+	type config struct {
+		Addr  net.IP        `flag:"--addr" default:"127.0.0.1"`
+		Peers []net.IP      `flag:"--peer"`
+		Sizes []human.Bytes `flag:"--size"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Addr, config.Peers, config.Sizes)
+	})
+
+	cli.Call(cmd, "--peer", "10.0.0.1", "--peer", "10.0.0.2", "--size", "1KiB", "--size", "2MiB")
+	// Output: 127.0.0.1 [10.0.0.1 10.0.0.2] [1Ki 2Mi]
+}
+
+func ExampleCommand_array() {
+	type config struct {
+		Point [2]int    `flag:"--point" default:"0,0"`
+		Range [2]string `arg:"range"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Point, config.Range)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "a", "b")
+	cli.Call(cmd, "--point", "1,2", "a", "b")
+	cli.Call(cmd, "--point", "3", "--point", "4", "a", "b")
+	cli.Call(cmd, "--point", "1,2,3", "a", "b")
+	// Output:
+	// [0 0] [a b]
+	// [1 2] [a b]
+	// [3 4] [a b]
+	//
+	// Usage:
+	//   [options] <range>
+	//
+	// Arguments:
+	//   range  string[2]
+	//
+	// Options:
+	//   -h, --help          Show this help message
+	//       --point int[2]  (default: 0,0)
+	//
+	// Error:
+	//   decoding "--point": expected 2 values but got 3
+}
+
+func ExampleCommand_array_positional() {
+	type noflags struct{}
+
+	cmd := cli.Command(func(_ noflags, size [2]int, name string) {
+		fmt.Println(size, name)
+	})
+
+	cli.Call(cmd, "640", "480", "screen")
+	// Output: [640 480] screen
+}
+
+func ExampleCommand_bytes() {
+	type config struct {
+		Key  []byte `flag:"--key" default:"-"`
+		Salt []byte `flag:"--salt" encoding:"hex" default:"-"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Printf("%q %q\n", config.Key, config.Salt)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--key", "aGVsbG8=", "--salt", "776f726c64")
+	cli.Call(cmd, "--help")
+	// Output:
+	// "hello" "world"
+	//
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help        Show this help message
+	//       --key base64
+	//       --salt hex
+}
+
 func ExampleCommand_default() {
 	type config struct {
 		Path string `flag:"-p,--path" default:"file.txt" env:"-"`
@@ -263,6 +355,48 @@ func ExampleCommand_default() {
 	// Output: file.txt
 }
 
+func ExampleCommand_hidedefault() {
+	type config struct {
+		Token string `flag:"--token" help:"API token" default:"s3cr3t" secret:"true"`
+		Host  string `flag:"--host" help:"API host" default:"localhost" hidedefault:"true"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Token, config.Host)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd)
+	cli.Call(cmd, "--help")
+	// Output:
+	// s3cr3t localhost
+	//
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help          Show this help message
+	//       --host string   API host
+	//       --token string  API token
+}
+
+func ExampleCommand_default_slice() {
+	type config struct {
+		Hosts []string `flag:"--host" default:"a.local, b.local"`
+		Ports []int    `flag:"--port" default:"80;443" sep:";"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Hosts, config.Ports)
+	})
+
+	cli.Call(cmd)
+	cli.Call(cmd, "--host", "c.local")
+	// Output:
+	// [a.local b.local] [80 443]
+	// [c.local] [80 443]
+}
+
 func ExampleCommand_required() {
 	type config struct {
 		Path string `flag:"-p,--path" env:"-"`
@@ -280,10 +414,116 @@ func ExampleCommand_required() {
 	//
 	// Options:
 	//   -h, --help         Show this help message
-	//   -p, --path string
+	//   -p, --path string  (required)
+	//
+	// Error:
+	//   missing required flag: "--path"
+}
+
+func ExampleCommand_choices() {
+	type config struct {
+		Format string `flag:"-f,--format" help:"Output format" choices:"text,json,yaml" default:"text"`
+		Level  string `arg:"level" choices:"debug,info"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Format, config.Level)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--format", "json", "info")
+	cli.Call(cmd, "--format", "xml", "info")
+	// Output:
+	// json info
+	//
+	// Usage:
+	//   [options] <debug|info>
+	//
+	// Arguments:
+	//   level  (debug|info)
+	//
+	// Options:
+	//   -f, --format (text|json|yaml)  Output format (default: text)
+	//   -h, --help                     Show this help message
+	//
+	// Error:
+	//   decoding "--format": invalid value "xml", expected one of: text, json, yaml
+}
+
+func ExampleCommand_placeholder() {
+	type config struct {
+		Path    string        `flag:"-p,--path" placeholder:"FILE" help:"Path to the input" default:"-"`
+		Timeout time.Duration `flag:"--timeout" placeholder:"DURATION" default:"1m"`
+	}
+
+	cmd := cli.Command(func(config config) {})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help              Show this help message
+	//   -p, --path FILE         Path to the input
+	//       --timeout DURATION  (default: 1m)
+}
+
+func ExampleCommand_groups() {
+	type config struct {
+		Verbose bool   `flag:"-v,--verbose" help:"Enable verbose output"`
+		Host    string `flag:"--host" help:"Address to listen on" group:"Networking" default:"localhost"`
+		Timeout string `flag:"--read-timeout" help:"Timeout of reads" group:"Networking" default:"-"`
+		Dir     string `flag:"-d,--data-dir" help:"Path to the data" group:"Storage" default:"-"`
+	}
+
+	cmd := cli.Command(func(config config) {})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help                 Show this help message
+	//   -v, --verbose              Enable verbose output
+	//
+	//   Networking:
+	//       --host string          Address to listen on (default: localhost)
+	//       --read-timeout string  Timeout of reads
+	//
+	//   Storage:
+	//   -d, --data-dir string      Path to the data
+}
+
+func ExampleCommand_errors() {
+	type config struct {
+		Path  string `flag:"-p,--path" env:"-"`
+		Count int    `flag:"-n,--count" default:"1"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Path, config.Count)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--cuont", "2", "-n", "two")
+	// Output:
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -n, --count int    (default: 1)
+	//   -h, --help         Show this help message
+	//   -p, --path string  (required)
 	//
 	// Error:
+	//   unrecognized option: "--cuont" (did you mean "--count"?)
 	//   missing required flag: "--path"
+	//   decoding "--count": strconv.ParseInt: parsing "two": invalid syntax
+	//   too many positional arguments: ["2"]
 }
 
 func ExampleCommand_environment() {
@@ -304,400 +544,1706 @@ func ExampleCommand_environment() {
 	// Output: hello world
 }
 
-func ExampleCommand_positional_arguments() {
-	type config struct{}
+func ExampleCommand_environment_list() {
+	type config struct {
+		Token string `flag:"--token" env:"NEW_TOKEN,OLD_TOKEN" default:"-"`
+	}
 
-	cmd := cli.Command(func(config config, x, y int) {
-		fmt.Println(x, y)
-	})
+	cmd := cli.NamedCommand("prog", cli.Command(func(config config) {
+		fmt.Println(config.Token)
+	}))
 
-	cli.Call(cmd, "10", "42")
-	// Output: 10 42
+	os.Setenv("PROG_OLD_TOKEN", "old")
+	cli.Call(cmd)
+
+	os.Setenv("PROG_NEW_TOKEN", "new")
+	cli.Call(cmd)
+	// Output:
+	// old
+	// new
 }
 
-func ExampleCommand_positional_arguments_slice() {
-	type config struct{}
+func ExampleCommand_boolean() {
+	type config struct {
+		Verbose bool `flag:"-v,--verbose"`
+	}
 
-	cmd := cli.Command(func(config config, paths []string) {
-		fmt.Println(paths)
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Verbose)
 	})
 
-	cli.Call(cmd, "file1.txt", "file2.txt", "file3.txt")
-	// Output: [file1.txt file2.txt file3.txt]
+	cli.Call(cmd, "--verbose=yes")
+	cli.Call(cmd, "--verbose=off")
+	// Output:
+	// true
+	// false
 }
 
-func ExampleCommand_with_sub_command() {
-	type config struct{}
+func ExampleCommand_human() {
+	type config struct {
+		MaxEvents int    `flag:"--max-events" human:"true" default:"1K"`
+		Size      uint64 `flag:"--size" human:"true" default:"-"`
+	}
 
-	cmd := cli.Command(func(config config, sub ...string) {
-		fmt.Println(sub)
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.MaxEvents, config.Size)
 	})
 
-	cli.Call(cmd, "--", "curl", "https://segment.com")
-	// Output: [curl https://segment.com]
+	cli.Call(cmd)
+	cli.Call(cmd, "--max-events", "10K", "--size", "2Mi")
+	// Output:
+	// 1000 0
+	// 10000 2097152
 }
 
-func ExampleCommand_context() {
-	ctx := context.Background()
+func ExampleCommand_human_duration() {
+	type config struct {
+		Timeout time.Duration `flag:"--timeout" default:"1m30s"`
+		Retry   time.Duration `flag:"--retry" human:"false" default:"1s"`
+	}
 
-	cmd := cli.Command(func(ctx context.Context) {
-		if ctx == context.TODO() {
-			fmt.Println("context.TODO()")
-		} else {
-			fmt.Println("context.Background()")
-		}
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Timeout, config.Retry)
 	})
 
+	cli.Err = os.Stdout
 	cli.Call(cmd)
-	cli.CallContext(ctx, cmd)
+	cli.Call(cmd, "--timeout", "2 days")
+	cli.Call(cmd, "--timeout", "1w", "--retry", "1 day")
 	// Output:
-	// context.TODO()
-	// context.Background()
+	// 1m30s 1s
+	// 48h0m0s 1s
+	//
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help              Show this help message
+	//       --retry duration    (default: 1s)
+	//       --timeout duration  (default: 1m30s)
+	//
+	// Error:
+	//   decoding "--retry": time: unknown unit " day" in duration "1 day"
 }
 
-func ExampleCommand_context_config() {
-	ctx := context.TODO()
-
-	type config struct{}
+func ExampleCommand_environment_merge() {
+	type config struct {
+		Tags  []string `flag:"--tag" sep:","`
+		Peers []string `flag:"--peer" sep:"," merge:"append"`
+	}
 
-	cmd := cli.Command(func(ctx context.Context, config config) {
-		fmt.Println("hello world")
-	})
+	cmd := cli.NamedCommand("prog", cli.Command(func(config config) {
+		fmt.Println(config.Tags, config.Peers)
+	}))
 
-	cli.CallContext(ctx, cmd)
-	// Output: hello world
+	os.Setenv("PROG_TAG", "a,b")
+	os.Setenv("PROG_PEER", "host-1, host-2")
+	cli.Call(cmd)
+	cli.Call(cmd, "--tag", "c", "--peer", "host-3")
+	// Output:
+	// [a b] [host-1 host-2]
+	// [c] [host-1 host-2 host-3]
 }
 
-func ExampleCommand_context_args() {
-	ctx := context.TODO()
-
-	type config struct{}
+func ExampleCommandFunc_abbrevFlags() {
+	type config struct {
+		Verbose bool   `flag:"--verbose"`
+		Name    string `flag:"--name" default:"-"`
+	}
 
-	cmd := cli.Command(func(ctx context.Context, config config, args []string) {
-		fmt.Println(args)
-	})
+	cmd := &cli.CommandFunc{
+		AbbrevFlags: true,
+		Func: func(config config) {
+			fmt.Println(config.Verbose, config.Name)
+		},
+	}
 
-	cli.CallContext(ctx, cmd, "hello", "world")
-	// Output: [hello world]
+	cli.Call(cmd, "--verb", "--na", "Luke")
+	// Output: true Luke
 }
 
-func ExampleCommandSet() {
-	help := cli.Command(func() {
-		fmt.Println("help")
-	})
-
-	this := cli.Command(func() {
-		fmt.Println("this")
-	})
+func ExampleCommandFunc_flagSet() {
+	type config struct {
+		Name string `flag:"--name" default:"Luke"`
+	}
 
-	that := cli.Command(func() {
-		fmt.Println("that")
-	})
+	fs := flag.NewFlagSet("glog", flag.ContinueOnError)
+	verbosity := fs.Int("v", 0, "Log level for verbose logs")
+	logDir := fs.String("log_dir", "", "Write log files in this `directory`")
 
-	cmd := cli.CommandSet{
-		"help": help,
-		"do": cli.CommandSet{
-			"this": this,
-			"that": that,
+	cmd := &cli.CommandFunc{
+		FlagSet: fs,
+		Func: func(config config) {
+			fmt.Println(config.Name, *verbosity, *logDir)
 		},
 	}
 
-	cli.Call(cmd, "help")
-	cli.Call(cmd, "do", "this")
-	cli.Call(cmd, "do", "that")
-
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-v", "2", "-log_dir", "/tmp")
+	cli.Call(cmd, "--help")
 	// Output:
-	// help
-	// this
-	// that
+	// Luke 2 /tmp
+	//
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help               Show this help message
+	//       --log_dir directory  Write log files in this directory
+	//       --name string        (default: Luke)
+	//   -v  int                  Log level for verbose logs
 }
 
-func ExampleCommandSet_usage_text() {
-	help := cli.Command(func() {
+func ExampleFlagInfo() {
+	type config struct {
+		Name  string `flag:"-n,--name" default:"Luke"`
+		Level int    `flag:"--level" default:"1"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.Command(func(config config, info cli.FlagInfo) {
+		fmt.Println(info.Flags(), info.Provided("-n"), info.Source("--level"))
+	}))
+
+	os.Setenv("PROG_LEVEL", "2")
+	defer os.Unsetenv("PROG_LEVEL")
+
+	cli.Call(cmd)
+	cli.Call(cmd, "-n", "Leia")
+	// Output:
+	// [] false environment
+	// [--name] true environment
+}
+
+func ExampleCommandFunc_transformArgs() {
+	type config struct {
+		Output string `flag:"-o,--output" default:"text"`
+	}
+
+	cmd := &cli.CommandFunc{
+		// Support the legacy --json flag, which was replaced by --output.
+		TransformArgs: func(args []string) []string {
+			for i, arg := range args {
+				if arg == "--json" {
+					args[i] = "--output=json"
+				}
+			}
+			return args
+		},
+		Func: func(config config) {
+			fmt.Println(config.Output)
+		},
+	}
+
+	cli.Call(cmd, "--json")
+	// Output: json
+}
+
+func ExampleCommandFunc_optionalSeparator() {
+	type config struct {
+		Verbose bool `flag:"-v,--verbose"`
+	}
+
+	cmd := &cli.CommandFunc{
+		OptionalSeparator: true,
+		OptionsFirst:      true,
+		Func: func(config config, command ...string) {
+			fmt.Println(config.Verbose, command)
+		},
+	}
+
+	cli.Call(cmd, "-v", "echo", "hi")
+	cli.Call(cmd, "--", "ls", "-l")
+	cli.Call(cmd, "grep", "-r", "TODO")
+	// Output:
+	// true [echo hi]
+	// false [ls -l]
+	// false [grep -r TODO]
+}
+
+func ExampleCommandFunc_envPrefix() {
+	type config struct {
+		String string `flag:"-f,--flag" default:"-"`
+	}
+
+	// The environment prefix does not depend on the program name when it is
+	// set explicitly on the command.
+	cmd := cli.NamedCommand("renamed", &cli.CommandFunc{
+		EnvPrefix: "APP",
+		Func: func(config config) {
+			fmt.Println(config.String)
+		},
+	})
+
+	os.Setenv("APP_FLAG", "hello world")
+	cli.Call(cmd)
+	// Output: hello world
+}
+
+func ExampleCommand_positional_arguments() {
+	type config struct{}
+
+	cmd := cli.Command(func(config config, x, y int) {
+		fmt.Println(x, y)
+	})
+
+	cli.Call(cmd, "10", "42")
+	// Output: 10 42
+}
+
+func ExampleCommand_positional_arguments_slice() {
+	type config struct{}
+
+	cmd := cli.Command(func(config config, paths []string) {
+		fmt.Println(paths)
+	})
+
+	cli.Call(cmd, "file1.txt", "file2.txt", "file3.txt")
+	// Output: [file1.txt file2.txt file3.txt]
+}
+
+func ExampleCommand_positional_arguments_struct() {
+	type config struct {
+		Source string   `arg:"source" help:"Path to copy files from"`
+		Target []string `arg:"target" help:"Paths to copy files to"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Source, config.Target)
+	})
+
+	cli.Call(cmd, "a", "b", "c")
+	cli.Call(cmd, "a")
+	// Output:
+	// a [b c]
+	// a []
+}
+
+func ExampleCommand_positional_arguments_struct_usage() {
+	type config struct {
+		Count  int    `arg:"1" default:"1"`
+		Source string `arg:"0"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Source, config.Count)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "file.txt", "42")
+	cli.Call(cmd)
+	// Output:
+	// file.txt 42
+	//
+	// Usage:
+	//   [options] <source> [count]
+	//
+	// Arguments:
+	//   source  string
+	//   count   int     (default: 1)
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	// Error:
+	//   missing required argument: "source"
+}
+
+func ExampleCommand_positional_arguments_count() {
+	type config struct {
+		Files []string `arg:"files" help:"Files to remove" min:"1" max:"2"`
+	}
+
+	cmd := cli.Command(func(config config) {
+		fmt.Println(config.Files)
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "a", "b")
+	cli.Call(cmd)
+	// Output:
+	// [a b]
+	//
+	// Usage:
+	//   [options] <files>...
+	//
+	// Arguments:
+	//   files  string...  Files to remove
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	// Error:
+	//   not enough values for argument "files", expected at least 1 but got 0
+}
+
+func TestCommandArgumentsCount(t *testing.T) {
+	type config struct {
+		Files []string `arg:"files" min:"1" max:"2"`
+	}
+
+	cmd := cli.Command(func(config config) {})
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{}, 1},
+		{[]string{"a"}, 0},
+		{[]string{"a", "b"}, 0},
+		{[]string{"a", "b", "c"}, 1},
+	}
+
+	for _, test := range tests {
+		code, err := cmd.Call(context.TODO(), test.args, nil)
+		if code != test.code {
+			t.Errorf("%q: wrong exit code: got %d, want %d (%v)", test.args, code, test.code, err)
+		}
+	}
+}
+
+func ExampleCommandFunc_optionsFirst() {
+	type config struct {
+		Verbose bool     `flag:"-v,--verbose"`
+		Command []string `arg:"command"`
+	}
+
+	cmd := &cli.CommandFunc{
+		OptionsFirst: true,
+		Func: func(config config) {
+			fmt.Println(config.Verbose, config.Command)
+		},
+	}
+
+	cli.Call(cmd, "-v", "mybinary", "--its-flag", "-v")
+	// Output: true [mybinary --its-flag -v]
+}
+
+func TestCommandErrors(t *testing.T) {
+	type config struct {
+		Path  string `flag:"-p,--path" env:"-"`
+		Count int    `flag:"-n,--count" default:"1"`
+	}
+
+	cmd := cli.Command(func(config config) {})
+	_, err := cmd.Call(context.TODO(), []string{"--cuont", "-p", "file", "extra", "-n"}, nil)
+
+	var unknown *cli.ErrUnknownFlag
+	if !errors.As(err, &unknown) || unknown.Flag != "--cuont" {
+		t.Errorf("expected an unknown flag error for --cuont, got %v", unknown)
+	}
+
+	var missing *cli.ErrMissingValue
+	if !errors.As(err, &missing) || missing.Flag != "-n" {
+		t.Errorf("expected a missing value error for -n, got %v", missing)
+	}
+
+	var tooMany *cli.ErrTooManyArgs
+	if !errors.As(err, &tooMany) || !reflect.DeepEqual(tooMany.Args, []string{"extra"}) {
+		t.Errorf("expected a too many arguments error for [extra], got %v", tooMany)
+	}
+
+	_, err = cmd.Call(context.TODO(), nil, nil)
+
+	var required *cli.ErrMissingRequired
+	if !errors.As(err, &required) || required.Flag != "--path" || required.Positional {
+		t.Errorf("expected a missing required flag error for --path, got %v", required)
+	}
+}
+
+func ExampleParse() {
+	type config struct {
+		Name    string `flag:"-n,--name" default:"Luke"`
+		Surname string `flag:"--surname" default:"-"`
+		Level   int    `flag:"--level" default:"1"`
+	}
+
+	var c config
+	err := cli.Parse(&c, []string{"--level", "42"}, []string{"SURNAME=Skywalker"})
+	fmt.Println(c.Name, c.Surname, c.Level, err)
+
+	err = cli.Parse(&c, []string{"--level", "high"}, nil)
+	fmt.Println(err != nil)
+	// Output:
+	// Luke Skywalker 42 <nil>
+	// true
+}
+
+func TestCommandArgsFiles(t *testing.T) {
+	type config struct {
+		Name  string   `flag:"--name"`
+		Files []string `arg:"files"`
+	}
+
+	path := t.TempDir() + "/args.txt"
+	if err := os.WriteFile(path, []byte("--name 'Luke Skywalker'\nb.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var result config
+	cmd := &cli.CommandFunc{
+		ArgsFiles: true,
+		Func: func(config config) {
+			result = config
+		},
+	}
+
+	if _, err := cmd.Call(context.TODO(), []string{"a.txt", "@" + path, "c.txt"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := config{Name: "Luke Skywalker", Files: []string{"a.txt", "b.txt", "c.txt"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	if _, err := cmd.Call(context.TODO(), []string{"@" + path + ".missing"}, nil); err == nil {
+		t.Error("expected an error reading a missing file")
+	}
+}
+
+func TestWithArgsFiles(t *testing.T) {
+	type config struct {
+		Region string `flag:"--region"`
+	}
+
+	path := t.TempDir() + "/args.txt"
+	if err := os.WriteFile(path, []byte("deploy --region us-west-2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var region string
+	cmd := cli.CommandSet{
+		"deploy": cli.Command(func(config config, args []string) {
+			region = config.Region + " " + strings.Join(args, " ")
+		}),
+	}
+
+	if code := cli.CallWith(cmd, []string{"@" + path, "app"}, cli.WithArgsFiles()); code != 0 {
+		t.Fatal("exit code:", code)
+	}
+	if region != "us-west-2 app" {
+		t.Errorf("wrong arguments: %q", region)
+	}
+
+	stderr := new(strings.Builder)
+	if code := cli.CallWith(cmd, []string{"@" + path + ".missing"}, cli.WithArgsFiles(), cli.WithStderr(stderr)); code == 0 {
+		t.Error("expected an error reading a missing file")
+	}
+}
+
+func TestCommandFileValue(t *testing.T) {
+	type config struct {
+		Cert string   `flag:"--cert" file:"true"`
+		Keys []string `flag:"--key" file:"true"`
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/cert.pem", []byte("-----BEGIN CERTIFICATE-----\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/key", []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var result config
+	cmd := cli.Command(func(config config) {
+		result = config
+	})
+
+	args := []string{"--cert", "@" + dir + "/cert.pem", "--key=@" + dir + "/key", "--key", "plain"}
+	if _, err := cmd.Call(context.TODO(), args, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := config{Cert: "-----BEGIN CERTIFICATE-----", Keys: []string{"secret", "plain"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	if _, err := cmd.Call(context.TODO(), []string{"--cert", "@" + dir + "/missing"}, nil); err == nil {
+		t.Error("expected an error reading a missing file")
+	}
+
+	// The "-" path reads the standard input of the program.
+	stdin := cli.WithStreams(cli.Streams{Stdin: strings.NewReader("from stdin\n")})
+	if code := cli.CallWith(cmd, []string{"--cert", "@-"}, stdin); code != 0 {
+		t.Fatalf("wrong exit code: %d", code)
+	}
+	if result.Cert != "from stdin" {
+		t.Errorf("wrong value read from stdin: %q", result.Cert)
+	}
+}
+
+func ExampleCommand_with_sub_command() {
+	type config struct{}
+
+	cmd := cli.Command(func(config config, sub ...string) {
+		fmt.Println(sub)
+	})
+
+	cli.Call(cmd, "--", "curl", "https://segment.com")
+	// Output: [curl https://segment.com]
+}
+
+func ExampleCommand_context() {
+	ctx := context.Background()
+
+	cmd := cli.Command(func(ctx context.Context) {
+		if ctx == context.TODO() {
+			fmt.Println("context.TODO()")
+		} else {
+			fmt.Println("context.Background()")
+		}
+	})
+
+	cli.Call(cmd)
+	cli.CallContext(ctx, cmd)
+	// Output:
+	// context.TODO()
+	// context.Background()
+}
+
+func ExampleCommand_context_config() {
+	ctx := context.TODO()
+
+	type config struct{}
+
+	cmd := cli.Command(func(ctx context.Context, config config) {
+		fmt.Println("hello world")
+	})
+
+	cli.CallContext(ctx, cmd)
+	// Output: hello world
+}
+
+func ExampleCommand_context_args() {
+	ctx := context.TODO()
+
+	type config struct{}
+
+	cmd := cli.Command(func(ctx context.Context, config config, args []string) {
+		fmt.Println(args)
+	})
+
+	cli.CallContext(ctx, cmd, "hello", "world")
+	// Output: [hello world]
+}
+
+func ExampleCommandSet() {
+	help := cli.Command(func() {
 		fmt.Println("help")
 	})
 
-	doc := cli.Command(func() {
-		fmt.Println("doc")
-	})
+	this := cli.Command(func() {
+		fmt.Println("this")
+	})
+
+	that := cli.Command(func() {
+		fmt.Println("that")
+	})
+
+	cmd := cli.CommandSet{
+		"help": help,
+		"do": cli.CommandSet{
+			"this": this,
+			"that": that,
+		},
+	}
+
+	cli.Call(cmd, "help")
+	cli.Call(cmd, "do", "this")
+	cli.Call(cmd, "do", "that")
+
+	// Output:
+	// help
+	// this
+	// that
+}
+
+func ExampleCommandSet_usage_text() {
+	help := cli.Command(func() {
+		fmt.Println("help")
+	})
+
+	doc := cli.Command(func() {
+		fmt.Println("doc")
+	})
+
+	cover := cli.Command(func() {
+		fmt.Println("cover")
+	})
+
+	cmd := cli.CommandSet{
+		"help": help,
+		"tool": cli.CommandSet{
+			"_": &cli.CommandFunc{
+				Help: "run specified go tool",
+			},
+			"cover": cover,
+			"doc":   doc,
+		},
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--help")
+
+	// Output:
+	// Usage:
+	//   [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   help
+	//   tool  run specified go tool
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+type greeter struct {
+	greeting string
+}
+
+func (g *greeter) SayHello(config struct {
+	Name string `flag:"--name" default:"Luke"`
+}) {
+	fmt.Println(g.greeting, config.Name)
+}
+
+func (g *greeter) SayGoodbye(ctx context.Context, config struct{}, names ...string) error {
+	fmt.Println("goodbye", names)
+	return nil
+}
+
+func (g *greeter) String() string { return g.greeting }
+
+func ExampleCommandsOf() {
+	cmd := cli.CommandsOf(&greeter{greeting: "hello"})
+
+	cli.Call(cmd, "say-hello", "--name", "Leia")
+	cli.Call(cmd, "say-goodbye", "--", "Han", "Chewie")
+	fmt.Println(len(cmd))
+	// Output:
+	// hello Leia
+	// goodbye [Han Chewie]
+	// 2
+}
+
+type store struct {
+	conn string
+}
+
+func (s *store) Setup(ctx context.Context) error {
+	s.conn = "connected"
+	fmt.Println("setup")
+	return nil
+}
+
+func (s *store) Teardown(err error) {
+	fmt.Println("teardown:", err)
+}
+
+func (s *store) Get(config struct{}, key string) error {
+	fmt.Println("get", key, s.conn)
+	return nil
+}
+
+func (s *store) Put(config struct{}, key string) error {
+	return fmt.Errorf("cannot put %s", key)
+}
+
+func ExampleCommandsOf_lifecycle() {
+	cli.Err = os.Stdout
+	cmd := cli.CommandsOf(new(store))
+
+	cli.Call(cmd, "get", "name")
+	cli.Call(cmd, "put", "--help")
+	fmt.Println(len(cmd))
+	// Output:
+	// setup
+	// get name connected
+	// teardown: <nil>
+	//
+	// Usage:
+	//   put [options] [string]
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	// 2
+}
+
+func ExampleCommandOf() {
+	type config struct {
+		Name string `flag:"--name" default:"Luke"`
+	}
+
+	cmd := cli.CommandOf(func(ctx context.Context, config config) error {
+		fmt.Println("hello", config.Name)
+		return nil
+	})
+
+	cli.Call(cmd, "--name", "Leia")
+	// Output: hello Leia
+}
+
+func ExampleCommandWithArgsOf() {
+	type config struct {
+		Verbose bool `flag:"-v,--verbose"`
+	}
+
+	cmd := cli.CommandWithArgsOf(func(ctx context.Context, config config, files []string) error {
+		fmt.Println(config.Verbose, files)
+		return nil
+	})
+
+	cli.Call(cmd, "-v", "a.txt", "b.txt")
+	// Output: true [a.txt b.txt]
+}
+
+func TestCommandOfPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected CommandOf to panic with a non-struct configuration type")
+		}
+	}()
+	cli.CommandOf(func(ctx context.Context, config int) error { return nil })
+}
+
+func TestCommandSetUsage(t *testing.T) {
+	doc := cli.Command(func() {
+		fmt.Println("doc")
+	})
+
+	cover := cli.Command(func() {
+		fmt.Println("cover")
+	})
+
+	cmd := cli.CommandSet{
+		"tool": cli.CommandSet{
+			"_": &cli.CommandFunc{
+				Help: "run specified go tool",
+			},
+			"cover": cover,
+			"doc":   doc,
+		},
+	}
+	var buf bytes.Buffer
+	cli.Err = &buf
+	cli.Call(cmd, "tool")
+	want := `
+Usage:
+  tool [command] [-h] [--help] ...
+
+Commands:
+  cover
+  doc
+
+Options:
+  -h, --help  Show this help message
+
+Error:
+  missing command
+
+
+`
+	if buf.String() != want {
+		t.Errorf("subcommand: got\n%q\n\n want\n%q", buf.String(), want)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cmd := cli.CommandSet{
+		"spans":  nil,
+		"traces": nil,
+		"values": nil,
+	}
+	var buf bytes.Buffer
+	cli.Err = &buf
+	cli.Call(cmd, "span")
+	want := `unknown command: "span". Did you mean "spans"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("levenshtein: should have gotten cmd suggestion, got %q", buf.String())
+	}
+}
+
+func ExampleCommandSet_option_before_command() {
+	type config struct {
+		String string `flag:"-f,--flag" default:"-"`
+	}
+
+	sub := cli.Command(func(config config) {
+		fmt.Println(config.String)
+	})
+
+	cmd := cli.CommandSet{
+		"sub": sub,
+	}
+
+	cli.Call(cmd, "-f=hello", "sub")
+
+	// Output:
+	// hello
+}
+
+func ExampleCommandSet_option_after_command() {
+	type config struct {
+		String string `flag:"-f,--flag" default:"-"`
+	}
+
+	sub := cli.Command(func(config config) {
+		fmt.Println(config.String)
+	})
+
+	cmd := cli.CommandSet{
+		"sub": sub,
+	}
+
+	cli.Call(cmd, "sub", "-f=hello")
+
+	// Output:
+	// hello
+}
+
+func ExampleCommand_help() {
+	type config struct {
+		Path  string `flag:"--path"     help:"Path to some file" default:"file" env:"-"`
+		Debug bool   `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	cmd := cli.CommandSet{
+		"do": cli.Command(func(config config) {
+			// ...
+		}),
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "do", "-h")
+
+	// Output:
+	// Usage:
+	//   do [options]
+	//
+	// Options:
+	//   -d, --debug        Enable debug mode
+	//   -h, --help         Show this help message
+	//       --path string  Path to some file (default: file)
+}
+
+func ExampleCommand_helpContext() {
+	type config struct {
+		Path  string `flag:"--path"     help:"Path to some file" default:"file" env:"-"`
+		Debug bool   `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	cmd := cli.CommandSet{
+		"do": cli.Command(func(ctx context.Context, config config) {
+			// ...
+		}),
+	}
+
+	cli.Err = os.Stdout
+	cli.CallContext(context.Background(), cmd, "do", "-h")
+
+	// Output:
+	// Usage:
+	//   do [options]
+	//
+	// Options:
+	//   -d, --debug        Enable debug mode
+	//   -h, --help         Show this help message
+	//       --path string  Path to some file (default: file)
+}
+
+func ExampleCommand_usage() {
+	type config struct {
+		Count int  `flag:"-n"         help:"Number of things"  default:"1"`
+		Debug bool `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	cmd := cli.CommandSet{
+		"do": cli.Command(func(config config) {
+			// ...
+		}),
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "do", "-n", "abc")
+
+	// Output:
+	// Usage:
+	//   do [options]
+	//
+	// Options:
+	//   -d, --debug  Enable debug mode
+	//   -h, --help   Show this help message
+	//   -n  int      Number of things (default: 1)
+	//
+	// Error:
+	//   decoding "-n": strconv.ParseInt: parsing "abc": invalid syntax
+}
+
+func ExampleCommandSet_help() {
+	type thisConfig struct {
+		_     struct{} `help:"Call this command"`
+		Path  string   `flag:"-p,--path"  help:"Path to some file" default:"file" env:"-"`
+		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	type thatConfig struct {
+		_     struct{} `help:"Call that command"`
+		Count int      `flag:"-n"         help:"Number of things"  default:"1"`
+		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	cmd := cli.CommandSet{
+		"do": cli.CommandSet{
+			"this": cli.Command(func(config thisConfig) {
+				// ...
+			}),
+			"that": cli.Command(func(config thatConfig) {
+				// ...
+			}),
+		},
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "do", "--help")
+
+	// Output:
+	// Usage:
+	//   do [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   that  Call that command
+	//   this  Call this command
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+func ExampleCommandSet_helpCommand() {
+	type config struct {
+		Force bool `flag:"-f,--force" help:"Overwrite existing files"`
+	}
+
+	cmd := cli.CommandSet{
+		"_": &cli.CommandFunc{
+			Help: "Manage files",
+			Desc: "Files are stored in the current directory.",
+		},
+		"copy": &cli.CommandFunc{
+			Help: "Copy files",
+			Desc: "Copies files from a source to a destination.",
+			Func: func(config config) {},
+		},
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "help", "copy")
+	// Output:
+	// Usage:
+	//   copy [options]
+	//
+	//   Copies files from a source to a destination.
+	//
+	// Options:
+	//   -f, --force  Overwrite existing files
+	//   -h, --help   Show this help message
+}
+
+func ExampleCommandSet_help2() {
+	type thisConfig struct {
+		_     struct{} `help:"Call this command"`
+		Path  string   `flag:"-p,--path"  help:"Path to some file" default:"file" env:"-"`
+		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	type thatConfig struct {
+		_     struct{} `help:"Call that command"`
+		Count int      `flag:"-n"         help:"Number of things"  default:"1"`
+		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+	}
+
+	cmd := cli.CommandSet{
+		"do": cli.CommandSet{
+			"this": cli.Command(func(config thisConfig) {
+				// ...
+			}),
+			"that": cli.Command(func(config thatConfig) {
+				// ...
+			}),
+		},
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "do", "this", "-h")
+
+	// Output:
+	// Usage:
+	//   do this [options]
+	//
+	// Options:
+	//   -d, --debug        Enable debug mode
+	//   -h, --help         Show this help message
+	//   -p, --path string  Path to some file (default: file)
+}
+
+func ExampleInCategory() {
+	type config struct{}
+
+	cmd := cli.CommandSet{
+		"run": cli.Command(func(config) {
+			// ...
+		}),
+		"container": cli.InCategory("Management Commands", cli.CommandSet{
+			"_": &cli.CommandFunc{Help: "Manage containers"},
+		}),
+		"image": cli.InCategory("Management Commands", cli.CommandSet{
+			"_": &cli.CommandFunc{Help: "Manage images"},
+		}),
+		"trace": cli.InCategory("Debug Commands", &cli.CommandFunc{
+			Help: "Trace the execution of a container",
+			Func: func(config) {},
+		}),
+	}
 
-	cover := cli.Command(func() {
-		fmt.Println("cover")
-	})
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--help")
+
+	// Output:
+	// Usage:
+	//   [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   run
+	//
+	// Debug Commands:
+	//   trace  Trace the execution of a container
+	//
+	// Management Commands:
+	//   container  Manage containers
+	//   image      Manage images
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+func ExampleLazy() {
+	type config struct{}
 
 	cmd := cli.CommandSet{
-		"help": help,
-		"tool": cli.CommandSet{
-			"_": &cli.CommandFunc{
-				Help: "run specified go tool",
-			},
-			"cover": cover,
-			"doc":   doc,
+		"fast": cli.Command(func(config) {
+			fmt.Println("fast")
+		}),
+		"slow": cli.Lazy(func() cli.Function {
+			fmt.Println("constructing slow")
+			return cli.Command(func(config) {
+				fmt.Println("slow")
+			})
+		}),
+	}
+
+	cli.Call(cmd, "fast")
+	cli.Call(cmd, "slow")
+	cli.Call(cmd, "slow")
+	// Output:
+	// fast
+	// constructing slow
+	// slow
+	// slow
+}
+
+func ExamplePersistent() {
+	type globals struct {
+		Region string `flag:"-r,--region" help:"Region to send requests to" default:"us-west-2"`
+	}
+
+	type config struct{}
+
+	var g globals
+
+	cmd := cli.Persistent(&g, cli.CommandSet{
+		"s3": cli.CommandSet{
+			"ls": cli.Command(func(config) {
+				fmt.Println("listing buckets in", g.Region)
+			}),
 		},
+	})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "s3", "ls")
+	cli.Call(cmd, "--region", "us-east-1", "s3", "ls")
+	cli.Call(cmd, "s3", "ls", "-r=eu-west-1")
+	cli.Call(cmd, "s3", "--help")
+
+	// Output:
+	// listing buckets in us-west-2
+	// listing buckets in us-east-1
+	// listing buckets in eu-west-1
+	//
+	// Usage:
+	//   s3 [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   ls
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	// Global Options:
+	//   -r, --region string  Region to send requests to (default: us-west-2)
+}
+
+func ExampleWrap() {
+	type config struct {
+		_ struct{} `help:"Say hello"`
+	}
+
+	logging := func(next cli.Function) cli.Function {
+		return cli.CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			fmt.Println("calling with", args)
+			return next.Call(ctx, args, env)
+		})
+	}
+
+	cmd := cli.CommandSet{
+		"hello": cli.Command(func(config) {
+			fmt.Println("hello")
+		}),
 	}
+	cmd.Use(logging)
 
 	cli.Err = os.Stdout
+	cli.Call(cmd, "hello")
 	cli.Call(cmd, "--help")
 
 	// Output:
+	// calling with []
+	// hello
+	//
 	// Usage:
 	//   [command] [-h] [--help] ...
 	//
 	// Commands:
-	//   help
-	//   tool  run specified go tool
+	//   hello  Say hello
 	//
 	// Options:
 	//   -h, --help  Show this help message
 }
 
-func TestCommandSetUsage(t *testing.T) {
-	doc := cli.Command(func() {
-		fmt.Println("doc")
+func ExamplePreRun() {
+	type config struct {
+		Name string `flag:"--name"`
+	}
+
+	cmd := cli.PreRun(func(ctx context.Context, path []string) error {
+		fmt.Println("running", strings.Join(path, " "))
+		return nil
+	}, cli.CommandSet{
+		"hello": cli.Command(func(config config) {
+			fmt.Println("hello", config.Name)
+		}),
 	})
 
-	cover := cli.Command(func() {
-		fmt.Println("cover")
+	cli.Err = os.Stdout
+	cli.Call(cli.NamedCommand("prog", cmd), "hello", "--name", "Luke")
+	cli.Call(cli.NamedCommand("prog", cmd), "hello")
+
+	// Output:
+	// running prog hello
+	// hello Luke
+	//
+	// Usage:
+	//   prog hello [options]
+	//
+	// Options:
+	//   -h, --help         Show this help message
+	//       --name string  (required)
+	//
+	// Error:
+	//   missing required flag: "--name"
+}
+
+func ExampleCommand_output() {
+	type config struct{}
+
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	cmd := cli.Command(func(config config) ([]item, error) {
+		return []item{{1, "Luke"}, {2, "Leia"}}, nil
 	})
 
-	cmd := cli.CommandSet{
-		"tool": cli.CommandSet{
-			"_": &cli.CommandFunc{
-				Help: "run specified go tool",
-			},
-			"cover": cover,
-			"doc":   doc,
-		},
+	cli.Call(cmd)
+	cli.Call(cmd, "-o", "json")
+	cli.Call(cmd, "-o", "csv")
+	cli.Call(cmd, "-o", "html")
+	cli.Call(cmd, "-o", "json=.[].name")
+	// Output:
+	// ID  NAME
+	// 1   Luke
+	// 2   Leia
+	// [
+	//   {
+	//     "id": 1,
+	//     "name": "Luke"
+	//   },
+	//   {
+	//     "id": 2,
+	//     "name": "Leia"
+	//   }
+	// ]
+	// id,name
+	// 1,Luke
+	// 2,Leia
+	// <table>
+	//   <thead>
+	//     <tr><th>id</th><th>name</th></tr>
+	//   </thead>
+	//   <tbody>
+	//     <tr><td>1</td><td>Luke</td></tr>
+	//     <tr><td>2</td><td>Leia</td></tr>
+	//   </tbody>
+	// </table>
+	// Luke
+	// Leia
+}
+
+func ExampleStreams() {
+	type config struct {
+		Name string `flag:"--name" default:"World"`
 	}
-	var buf bytes.Buffer
-	cli.Err = &buf
-	cli.Call(cmd, "tool")
-	want := `
-Usage:
-  tool [command] [-h] [--help] ...
 
-Commands:
-  cover
-  doc
+	cmd := cli.Command(func(config config, s cli.Streams) {
+		fmt.Fprintf(s.Stdout, "Hello %s!\n", config.Name)
+	})
 
-Options:
-  -h, --help  Show this help message
+	var out bytes.Buffer
+	cli.CallWith(cmd, []string{"--name", "Luke"}, cli.WithStreams(cli.Streams{Stdout: &out}))
+	fmt.Printf("%q\n", out.String())
+	// Output: "Hello Luke!\n"
+}
 
-Error:
-  missing command
+func ExampleCommand_variadic_only() {
+	cmd := cli.Command(func(args ...string) {
+		fmt.Printf("%q\n", args)
+	})
 
+	cli.Call(cmd)
+	cli.Call(cmd, "git", "log", "--oneline", "--", "README.md")
+	// Output:
+	// []
+	// ["git" "log" "--oneline" "--" "README.md"]
+}
 
-`
-	if buf.String() != want {
-		t.Errorf("subcommand: got\n%q\n\n want\n%q", buf.String(), want)
+func ExampleCommandFunc_examples() {
+	type config struct {
+		Recursive bool `flag:"-r,--recursive" help:"Copy directories recursively"`
+	}
+
+	cmd := &cli.CommandFunc{
+		Help: "Copy files",
+		Func: func(config config, src, dst string) {},
+		Examples: `$ copy file.txt backup.txt
+$ copy -r src/ dst/`,
 	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options] [string] [string]
+	//
+	// Options:
+	//   -h, --help       Show this help message
+	//   -r, --recursive  Copy directories recursively
+	//
+	// Examples:
+	//   $ copy file.txt backup.txt
+	//   $ copy -r src/ dst/
 }
 
-func TestLevenshtein(t *testing.T) {
-	cmd := cli.CommandSet{
-		"spans":  nil,
-		"traces": nil,
-		"values": nil,
+func ExampleCommandFunc_args() {
+	cmd := &cli.CommandFunc{
+		Func: func(config struct{}, src string, dst []string) {},
+		Args: []cli.Arg{
+			{Name: "src", Help: "Path to copy files from"},
+			{Name: "dst", Help: "Paths to copy files to"},
+		},
 	}
-	var buf bytes.Buffer
-	cli.Err = &buf
-	cli.Call(cmd, "span")
-	want := `unknown command: "span". Did you mean "spans"`
-	if !strings.Contains(buf.String(), want) {
-		t.Errorf("levenshtein: should have gotten cmd suggestion, got %q", buf.String())
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options] [src] [dst...]
+	//
+	// Arguments:
+	//   src  string     Path to copy files from
+	//   dst  string...  Paths to copy files to
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+func ExampleCommandFunc_footer() {
+	cmd := &cli.CommandFunc{
+		Func: func(config struct{}) {},
+		Footer: `Exit codes:
+  0  the files were copied
+  1  some files could not be copied
+
+See https://example.com/docs/copy for more details.`,
 	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	//   Exit codes:
+	//     0  the files were copied
+	//     1  some files could not be copied
+	//
+	//   See https://example.com/docs/copy for more details.
 }
 
-func ExampleCommandSet_option_before_command() {
+func ExampleCommandFunc_template() {
 	type config struct {
-		String string `flag:"-f,--flag" default:"-"`
+		Recursive bool `flag:"-r,--recursive" help:"Copy directories recursively"`
 	}
 
-	sub := cli.Command(func(config config) {
-		fmt.Println(config.String)
-	})
+	cmd := &cli.CommandFunc{
+		Func:     func(config config, src, dst string) {},
+		Examples: `$ copy -r src/ dst/`,
+		// Examples come first, and the help ends with a link to the docs.
+		Template: template.Must(template.New("copy").Parse(`{{.Header "Examples:"}}
+{{.Examples}}
+{{.Header "Options:"}}
+{{.Options}}
+See https://example.com/docs/copy for more details.
+`)),
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "-h")
+	// Output:
+	// Usage:
+	//   [options] [string] [string]
+	//
+	// Examples:
+	//   $ copy -r src/ dst/
+	//
+	// Options:
+	//   -h, --help       Show this help message
+	//   -r, --recursive  Copy directories recursively
+	//
+	// See https://example.com/docs/copy for more details.
+}
+
+func ExampleDeprecated() {
+	type config struct{}
 
 	cmd := cli.CommandSet{
-		"sub": sub,
+		"get": &cli.CommandFunc{
+			Help: "Get the resource",
+			Func: func(config) { fmt.Println("get") },
+		},
+		"fetch": &cli.CommandFunc{
+			Help:       "Fetch the resource",
+			Func:       func(config) { fmt.Println("fetch") },
+			Deprecated: `use "get" instead`,
+		},
+		"legacy": cli.Deprecated(`use "get" instead`, cli.CommandSet{
+			"fetch": cli.Command(func(config) { fmt.Println("legacy fetch") }),
+		}),
 	}
 
-	cli.Call(cmd, "-f=hello", "sub")
+	cli.Err = os.Stdout
+	cli.Call(cmd, "fetch")
+	cli.Call(cmd, "legacy", "fetch")
+	cli.Call(cmd, "--help")
+	// Output:
+	// warning: "fetch" is deprecated, use "get" instead
+	// fetch
+	// warning: "legacy" is deprecated, use "get" instead
+	// legacy fetch
+	//
+	// Usage:
+	//   [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   fetch   Fetch the resource (deprecated)
+	//   get     Get the resource
+	//   legacy  (deprecated)
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+func ExampleGroup() {
+	type config struct{}
 
+	cmd := cli.CommandSet{
+		"container": cli.Group{
+			Help: "Manage containers",
+			Desc: "Containers are isolated processes running from images.",
+			Commands: cli.CommandSet{
+				"start": &cli.CommandFunc{
+					Help: "Start a container",
+					Func: func(config) {},
+				},
+			},
+		},
+	}
+
+	cli.Err = os.Stdout
+	fmt.Printf("%x\n", cmd["container"])
+	cli.Call(cmd, "container", "--help")
 	// Output:
-	// hello
+	// Manage containers
+	//
+	// Usage:
+	//   container [command] [-h] [--help] ...
+	//
+	//   Containers are isolated processes running from images.
+	//
+	// Commands:
+	//   start  Start a container
+	//
+	// Options:
+	//   -h, --help  Show this help message
 }
 
-func ExampleCommandSet_option_after_command() {
+func ExampleRepl() {
 	type config struct {
-		String string `flag:"-f,--flag" default:"-"`
+		_    struct{} `help:"Say hello"`
+		Name string   `flag:"--name" default:"World"`
 	}
 
-	sub := cli.Command(func(config config) {
-		fmt.Println(config.String)
+	cmds := cli.CommandSet{
+		"hello": cli.Command(func(config config, s cli.Streams) {
+			fmt.Fprintf(s.Stdout, "Hello %s!\n", config.Name)
+		}),
+	}
+
+	input := strings.NewReader(`hello
+hello --name "Luke Skywalker"
+help
+exit
+`)
+
+	cli.Repl(context.TODO(), cmds, cli.ReplPrompt("$ "), cli.ReplStreams(cli.Streams{
+		Stdin:  input,
+		Stdout: os.Stdout,
+	}))
+	// Output:
+	// $ Hello World!
+	// $ Hello Luke Skywalker!
+	// $ Usage:
+	//   [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   hello  Say hello
+	//
+	// Options:
+	//   -h, --help  Show this help message
+	//
+	// $
+}
+
+func ExampleChain() {
+	type config struct {
+		Tag string `flag:"--tag"`
+	}
+
+	build := cli.Command(func(config config) {
+		fmt.Println("building", config.Tag)
+	})
+
+	push := cli.Command(func(config config) error {
+		if config.Tag == "latest" {
+			return cli.Exit(2, nil)
+		}
+		fmt.Println("pushing", config.Tag)
+		return nil
 	})
 
-	cmd := cli.CommandSet{
-		"sub": sub,
-	}
-
-	cli.Call(cmd, "sub", "-f=hello")
+	cmd := cli.Chain(build, push)
 
+	fmt.Println(cli.Call(cmd, "--tag", "v1"))
+	fmt.Println(cli.Call(cmd, "--tag", "latest"))
 	// Output:
-	// hello
+	// building v1
+	// pushing v1
+	// 0
+	// building latest
+	// 2
 }
 
-func ExampleCommand_help() {
-	type config struct {
-		Path  string `flag:"--path"     help:"Path to some file" default:"file" env:"-"`
-		Debug bool   `flag:"-d,--debug" help:"Enable debug mode"`
+func ExampleChain_flags() {
+	type buildConfig struct {
+		Tag     string `flag:"--tag" help:"Tag of the image"`
+		NoCache bool   `flag:"--no-cache" help:"Do not use the build cache"`
 	}
 
-	cmd := cli.CommandSet{
-		"do": cli.Command(func(config config) {
-			// ...
-		}),
+	type pushConfig struct {
+		Tag      string `flag:"--tag" help:"Tag of the image"`
+		Registry string `flag:"--registry" help:"Registry to push the image to" default:"docker.io"`
 	}
 
-	cli.Err = os.Stdout
-	cli.Call(cmd, "do", "-h")
+	build := cli.Command(func(config buildConfig) {
+		fmt.Println("building", config.Tag, "without cache:", config.NoCache)
+	})
+
+	push := cli.Command(func(config pushConfig) {
+		fmt.Println("pushing", config.Tag, "to", config.Registry)
+	})
+
+	cmd := cli.NamedCommand("build-and-push", cli.Chain(build, push))
 
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--tag", "v1", "--no-cache", "--registry", "ghcr.io")
+	cli.Call(cmd, "--help")
 	// Output:
+	// building v1 without cache: true
+	// pushing v1 to ghcr.io
+	//
 	// Usage:
-	//   do [options]
+	//   build-and-push [options]
 	//
 	// Options:
-	//   -d, --debug        Enable debug mode
-	//   -h, --help         Show this help message
-	//       --path string  Path to some file (default: file)
+	//   -h, --help             Show this help message
+	//       --no-cache         Do not use the build cache
+	//       --registry string  Registry to push the image to (default: docker.io)
+	//       --tag string       Tag of the image (required)
 }
 
-func ExampleCommand_helpContext() {
+func ExampleArgAlias() {
 	type config struct {
-		Path  string `flag:"--path"     help:"Path to some file" default:"file" env:"-"`
-		Debug bool   `flag:"-d,--debug" help:"Enable debug mode"`
+		Output string `flag:"--output" default:"-"`
 	}
 
-	cmd := cli.CommandSet{
-		"do": cli.Command(func(ctx context.Context, config config) {
-			// ...
-		}),
-	}
+	cmd := cli.Command(func(config config) {
+		fmt.Println("output:", config.Output)
+	})
 
-	cli.Err = os.Stdout
-	cli.CallContext(context.Background(), cmd, "do", "-h")
+	legacy := cli.ArgAlias(cmd, func(args []string) []string {
+		for i, arg := range args {
+			args[i] = strings.Replace(arg, "--out-file", "--output", 1)
+		}
+		return args
+	})
 
+	cli.Call(legacy, "--out-file", "a.txt")
+	cli.Call(legacy, "--out-file=b.txt")
 	// Output:
-	// Usage:
-	//   do [options]
-	//
-	// Options:
-	//   -d, --debug        Enable debug mode
-	//   -h, --help         Show this help message
-	//       --path string  Path to some file (default: file)
+	// output: a.txt
+	// output: b.txt
 }
 
-func ExampleCommand_usage() {
+func ExampleInvoke() {
 	type config struct {
-		Count int  `flag:"-n"         help:"Number of things"  default:"1"`
-		Debug bool `flag:"-d,--debug" help:"Enable debug mode"`
-	}
-
-	cmd := cli.CommandSet{
-		"do": cli.Command(func(config config) {
-			// ...
-		}),
+		Count int `flag:"-n,--count" default:"1"`
 	}
 
-	cli.Err = os.Stdout
-	cli.Call(cmd, "do", "-n", "abc")
+	cmd := cli.NamedCommand("repeat", cli.Command(func(config config, word string) {
+		fmt.Println(strings.Repeat(word, config.Count))
+	}))
 
+	cli.Invoke(context.TODO(), cmd, config{Count: 3}, "ha")
+	cli.Invoke(context.TODO(), cmd, &config{Count: 2}, "ho")
 	// Output:
-	// Usage:
-	//   do [options]
-	//
-	// Options:
-	//   -d, --debug  Enable debug mode
-	//   -h, --help   Show this help message
-	//   -n int       Number of things (default: 1)
-	//
-	// Error:
-	//   decoding "-n": strconv.ParseInt: parsing "abc": invalid syntax
+	// hahaha
+	// hoho
 }
 
-func ExampleCommandSet_help() {
-	type thisConfig struct {
-		_     struct{} `help:"Call this command"`
-		Path  string   `flag:"-p,--path"  help:"Path to some file" default:"file" env:"-"`
-		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
-	}
-
-	type thatConfig struct {
-		_     struct{} `help:"Call that command"`
-		Count int      `flag:"-n"         help:"Number of things"  default:"1"`
-		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+func ExampleCatalog() {
+	cli.Messages = cli.Catalog{
+		"Usage:":                  "Utilisation :",
+		"Options:":                "Options :",
+		"Error:":                  "Erreur :",
+		"Show this help message":  "Afficher ce message d'aide",
+		"unrecognized option: %q": "option inconnue : %q",
 	}
+	defer func() { cli.Messages = nil }()
 
-	cmd := cli.CommandSet{
-		"do": cli.CommandSet{
-			"this": cli.Command(func(config thisConfig) {
-				// ...
-			}),
-			"that": cli.Command(func(config thatConfig) {
-				// ...
-			}),
-		},
-	}
+	cmd := cli.Command(func(struct{}) {})
 
 	cli.Err = os.Stdout
-	cli.Call(cmd, "do", "--help")
-
+	cli.Call(cmd, "--verbose")
 	// Output:
-	// Usage:
-	//   do [command] [-h] [--help] ...
+	// Utilisation :
+	//   [options]
 	//
-	// Commands:
-	//   that  Call that command
-	//   this  Call this command
+	// Options :
+	//   -h, --help  Afficher ce message d'aide
 	//
-	// Options:
-	//   -h, --help  Show this help message
+	// Erreur :
+	//   option inconnue : "--verbose"
 }
 
-func ExampleCommandSet_help2() {
-	type thisConfig struct {
-		_     struct{} `help:"Call this command"`
-		Path  string   `flag:"-p,--path"  help:"Path to some file" default:"file" env:"-"`
-		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+func TestCatalogCommands(t *testing.T) {
+	cli.Messages = cli.Catalog{
+		"unknown command: %q": "commande inconnue : %q",
+		"unknown command: %q. Did you mean %q? Use --help to see all commands": "commande inconnue : %q. Vouliez-vous dire %q ? Utilisez --help pour voir toutes les commandes",
+		"Global Options:":               "Options globales :",
+		"Commands":                      "Commandes",
+		"error":                         "erreur",
+		"unsupported output format: %q": "format de sortie non supporté : %q",
 	}
+	defer func() { cli.Messages = nil }()
 
-	type thatConfig struct {
-		_     struct{} `help:"Call that command"`
-		Count int      `flag:"-n"         help:"Number of things"  default:"1"`
-		Debug bool     `flag:"-d,--debug" help:"Enable debug mode"`
+	type globals struct {
+		Verbose bool `flag:"-v,--verbose"`
 	}
 
-	cmd := cli.CommandSet{
-		"do": cli.CommandSet{
-			"this": cli.Command(func(config thisConfig) {
-				// ...
-			}),
-			"that": cli.Command(func(config thatConfig) {
-				// ...
-			}),
-		},
+	cmd := cli.Persistent(new(globals), cli.CommandSet{
+		"start": cli.Command(func(struct{}) {}),
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"nope"}, `commande inconnue : "nope"`},
+		{[]string{"stat"}, `commande inconnue : "stat". Vouliez-vous dire "start" ? Utilisez --help pour voir toutes les commandes`},
+		{[]string{"start", "--help"}, "Options globales :"},
+		{[]string{"--help"}, "Commandes:"},
 	}
 
-	cli.Err = os.Stdout
-	cli.Call(cmd, "do", "this", "-h")
+	for _, test := range tests {
+		_, err := cmd.Call(context.TODO(), test.args, nil)
+		if s := fmt.Sprintf("%v", err); !strings.Contains(s, test.want) {
+			t.Errorf("%q: message not translated:\n%s\nwant: %q", test.args, s, test.want)
+		}
+	}
 
-	// Output:
-	// Usage:
-	//   do this [options]
-	//
-	// Options:
-	//   -d, --debug        Enable debug mode
-	//   -h, --help         Show this help message
-	//   -p, --path string  Path to some file (default: file)
+	const unsupported = `format de sortie non supporté : "xml"`
+	if _, err := cli.Format("xml", io.Discard); err == nil || !strings.Contains(err.Error(), unsupported) {
+		t.Errorf("Format: message not translated: %v", err)
+	}
+	if _, err := cli.FormatList("xml", io.Discard); err == nil || !strings.Contains(err.Error(), unsupported) {
+		t.Errorf("FormatList: message not translated: %v", err)
+	}
+
+	stderr := new(bytes.Buffer)
+	cli.Repl(context.TODO(), cli.CommandSet{
+		"fail": cli.Command(func(struct{}) error { return errors.New("boom") }),
+	}, cli.ReplStreams(cli.Streams{
+		Stdin:  strings.NewReader("fail\n"),
+		Stdout: io.Discard,
+		Stderr: stderr,
+	}))
+	if s := stderr.String(); s != "erreur: boom\n" {
+		t.Errorf("Repl: message not translated: %q", s)
+	}
 }
 
 func ExampleCommand_spacesInFlag() {
@@ -772,3 +2318,303 @@ func TestUsageFmt(t *testing.T) {
 		t.Errorf("Sprintf(%%#v, got %q, want %q", got, want)
 	}
 }
+
+func ExampleDescribe() {
+	type config struct {
+		Format string `flag:"-f,--format" help:"Output format" choices:"text,json" default:"text"`
+		Name   string `arg:"name" help:"Name of the resource"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.CommandSet{
+		"get": &cli.CommandFunc{
+			Help: "Get a resource",
+			Func: func(config config) {},
+		},
+	})
+
+	e := json.NewEncoder(os.Stdout)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+	e.Encode(cli.Describe(cmd))
+	// Output:
+	// {
+	//   "name": "prog",
+	//   "usage": "prog [command] [-h] [--help] ...",
+	//   "commands": [
+	//     {
+	//       "name": "get",
+	//       "usage": "prog get [options] <name>",
+	//       "help": "Get a resource",
+	//       "arguments": [
+	//         {
+	//           "name": "name",
+	//           "type": "string",
+	//           "help": "Name of the resource"
+	//         }
+	//       ],
+	//       "options": [
+	//         {
+	//           "flags": [
+	//             "-f",
+	//             "--format"
+	//           ],
+	//           "type": "(text|json)",
+	//           "help": "Output format",
+	//           "default": "text",
+	//           "env": [
+	//             "PROG_FORMAT"
+	//           ],
+	//           "choices": [
+	//             "text",
+	//             "json"
+	//           ]
+	//         },
+	//         {
+	//           "flags": [
+	//             "-h",
+	//             "--help"
+	//           ],
+	//           "help": "Show this help message"
+	//         }
+	//       ]
+	//     }
+	//   ]
+	// }
+}
+
+func ExampleCommand_helpVerbose() {
+	type config struct {
+		Host  string `flag:"--host" help:"Address of the server" default:"localhost"`
+		Port  int    `flag:"--port" help:"Port of the server" default:"8080"`
+		Token string `flag:"--token" help:"Authentication token" default:"-" secret:"true"`
+		Debug bool   `flag:"--debug" help:"Enable debug logs"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.Command(func(config config) {}))
+
+	os.Setenv("PROG_PORT", "9090")
+	os.Setenv("PROG_TOKEN", "hunter2")
+	defer os.Unsetenv("PROG_PORT")
+	defer os.Unsetenv("PROG_TOKEN")
+
+	cli.Err = os.Stdout
+	cli.CallWith(cmd, []string{"--help-verbose", "--debug"})
+	// Output:
+	// Usage:
+	//   prog [options]
+	//
+	// Options:
+	//       --debug         Enable debug logs (value: true, from command line)
+	//   -h, --help          Show this help message
+	//       --host string   Address of the server (value: localhost, from default)
+	//       --port int      Port of the server (value: 9090, from environment variable PROG_PORT)
+	//       --token string  Authentication token (value: ******, from environment variable PROG_TOKEN)
+}
+
+func ExampleHidden() {
+	cmd := cli.CommandSet{
+		"get": &cli.CommandFunc{
+			Help: "Get a resource",
+			Func: func(struct{}) {},
+		},
+		"debug": cli.Hidden(&cli.CommandFunc{
+			Help: "Print debug information",
+			Func: func(struct{}) {},
+		}),
+	}
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--help-all")
+	// Output:
+	// Usage:
+	//   [command] [-h] [--help] ...
+	//
+	// Commands:
+	//   debug  Print debug information (hidden)
+	//   get    Get a resource
+	//
+	// Options:
+	//   -h, --help  Show this help message
+}
+
+func ExampleCommand_helpAll() {
+	type config struct {
+		Name  string `flag:"--name" help:"Name of the resource" default:"-"`
+		Trace bool   `flag:"--trace" help:"Trace the requests" hidden:"true"`
+	}
+
+	cmd := cli.Command(func(config config) {})
+
+	cli.Err = os.Stdout
+	cli.Call(cmd, "--help-all")
+	// Output:
+	// Usage:
+	//   [options]
+	//
+	// Options:
+	//   -h, --help          Show this help message
+	//       --help-all      Show this help message with the hidden options (hidden)
+	//       --help-verbose  Show this help message with the values of the options and their sources (hidden)
+	//       --name string   Name of the resource
+	//       --trace         Trace the requests (hidden)
+}
+
+func ExampleShortUsage() {
+	type config struct {
+		Verbose bool `flag:"-v,--verbose" help:"Enable verbose mode"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.CommandSet{
+		"get": cli.Command(func(config config) {}),
+	})
+
+	cli.Err = os.Stdout
+	cli.CallWith(cmd, []string{"get", "--verbos"}, cli.WithUsagePrinter(cli.ShortUsage))
+	// Output:
+	// error: unrecognized option: "--verbos" (did you mean "--verbose"?)
+	// Run "prog get --help" for usage.
+}
+
+func ExampleCommandSet_flagHint() {
+	type deployConfig struct {
+		Region string `flag:"--region" help:"Region to deploy to" default:"us-west-2"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.CommandSet{
+		"deploy": cli.Command(func(config deployConfig) {}),
+		"status": cli.Command(func(struct{}) {}),
+	})
+
+	cli.Err = os.Stdout
+	cli.CallWith(cmd, []string{"status", "--region=eu-west-1"}, cli.WithUsagePrinter(cli.ShortUsage))
+	// Output:
+	// error: unrecognized option: "--region" (--region is a flag of "prog deploy")
+	// Run "prog status --help" for usage.
+}
+
+func ExampleExecWith() {
+	type config struct {
+		Name     string `flag:"--name" env:"NAME" default:"World"`
+		Greeting string `flag:"--greeting" env:"GREETING" default:"Hello"`
+	}
+
+	cmd := cli.Command(func(config config, s cli.Streams) error {
+		fmt.Fprintf(s.Stdout, "%s %s!\n", config.Greeting, config.Name)
+		return errors.New("done")
+	})
+
+	var stdout, stderr bytes.Buffer
+	cli.ExecWith(cmd,
+		cli.WithArgs("--name", "Luke"),
+		cli.WithEnv("GREET_GREETING=Hi"),
+		cli.WithEnvPrefix("GREET"),
+		cli.WithStdout(&stdout),
+		cli.WithStderr(&stderr),
+		cli.WithExit(func(code int) { fmt.Println("exit code:", code) }),
+	)
+
+	fmt.Printf("%q\n", stdout.String())
+	fmt.Println(strings.HasSuffix(stderr.String(), " done\n"))
+	// Output:
+	// exit code: 1
+	// "Hi Luke!\n"
+	// true
+}
+
+func ExamplePrintError() {
+	cmd := cli.NamedCommand("prog", cli.Command(func(struct{}) error {
+		return errors.New("something went wrong")
+	}))
+
+	cli.Err = os.Stdout
+	cli.Call(cmd)
+	// Output: prog: error: something went wrong
+}
+
+func ExampleWithErrorPrinter() {
+	cmd := cli.NamedCommand("prog", cli.Command(func(struct{}) error {
+		return cli.Exit(3, errors.New("something went wrong"))
+	}))
+
+	cli.Err = os.Stdout
+	code := cli.CallWith(cmd, nil, cli.WithErrorPrinter(func(w io.Writer, program string, err error) {
+		fmt.Fprintf(w, "[%s] %v\n", program, err)
+	}))
+	fmt.Println("exit code:", code)
+	// Output:
+	// [prog] something went wrong
+	// exit code: 3
+}
+
+func ExampleExitf() {
+	cmd := cli.NamedCommand("prog", cli.Command(func(config struct{}, name string) error {
+		return cli.Exitf(2, "%s: not found", name)
+	}))
+
+	cli.Err = os.Stdout
+	code := cli.Call(cmd, "config.yaml")
+	fmt.Println("exit code:", code)
+	// Output:
+	// prog: error: config.yaml: not found
+	// exit code: 2
+}
+
+func ExampleCallErr() {
+	cmd := cli.NamedCommand("prog", cli.Command(func(config struct{}, name string) error {
+		return cli.Exitf(2, "%s: not found", name)
+	}))
+
+	code, err := cli.CallErr(context.TODO(), cmd, "config.yaml")
+	fmt.Printf("%d: %v\n", code, err)
+
+	code, err = cli.CallErr(context.TODO(), cmd, "--nope")
+	var usage *cli.Usage
+	fmt.Printf("%d: %t\n", code, errors.As(err, &usage))
+	// Output:
+	// 2: config.yaml: not found
+	// 1: true
+}
+
+func ExampleVerbosity() {
+	type config struct {
+		Name string `flag:"--name" default:"-"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.Command(func(ctx context.Context, config config) {
+		fmt.Println("verbosity:", cli.Verbosity(ctx))
+	}))
+
+	options := cli.WithVerbosityFlags()
+	cli.CallWith(cmd, nil, options)
+	cli.CallWith(cmd, []string{"-vv", "--name", "Luke"}, options)
+	cli.CallWith(cmd, []string{"-v", "--verbose"}, options)
+	cli.CallWith(cmd, []string{"--quiet"}, options)
+	// Output:
+	// verbosity: 0
+	// verbosity: 2
+	// verbosity: 2
+	// verbosity: -1
+}
+
+func ExampleWithPrintConfigFlag() {
+	type config struct {
+		Name    string `flag:"--name" default:"World"`
+		Token   string `flag:"--token" secret:"true" default:"-"`
+		Region  string `flag:"--region"`
+		Verbose bool   `flag:"-v,--verbose"`
+	}
+
+	cmd := cli.NamedCommand("prog", cli.Command(func(config config) {
+		fmt.Println("the command is not called")
+	}))
+
+	cli.CallWith(cmd, []string{"--print-config", "--name", "Luke"},
+		cli.WithPrintConfigFlag(),
+		cli.WithEnv("PROG_TOKEN=hunter2"),
+	)
+	// Output:
+	// --name     Luke       (from command line)
+	// --region   (not set)
+	// --token    ******     (from environment variable PROG_TOKEN)
+	// --verbose  (not set)
+}
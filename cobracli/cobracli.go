@@ -0,0 +1,109 @@
+// Package cobracli provides adapters between the commands of the cli package
+// and those of github.com/spf13/cobra, allowing programs to mount commands of
+// one package in the command tree of the other while migrating between them.
+//
+// The package is a module of its own, so programs which do not use it do not
+// depend on cobra.
+package cobracli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/segmentio/cli"
+	"github.com/spf13/cobra"
+)
+
+// FromCobra returns a cli.Function which executes the cobra command c with the
+// arguments it is called with. Since cobra always executes commands from the
+// root of their tree, c should be a root command (one which was not added to
+// a parent).
+//
+// Errors of the cobra command are returned with an exit code of 1. If the
+// command does not silence its errors, cobra has already printed them, and
+// the returned error is nil to avoid reporting them twice.
+func FromCobra(c *cobra.Command) cli.Function {
+	return &cobraFunction{cmd: c}
+}
+
+type cobraFunction struct {
+	cmd *cobra.Command
+}
+
+func (f *cobraFunction) Call(ctx context.Context, args, env []string) (int, error) {
+	if args == nil {
+		args = []string{} // cobra would use os.Args[1:] otherwise
+	}
+	f.cmd.SetArgs(args)
+
+	if err := f.cmd.ExecuteContext(ctx); err != nil {
+		if !f.cmd.SilenceErrors {
+			err = nil
+		}
+		return 1, err
+	}
+
+	return 0, nil
+}
+
+// Format satisfies the fmt.Formatter interface, so the cobra command can be
+// described in the help of the cli package. The 's' verb prints the usage line
+// without the command name, 'x' prints the short description, and 'v' prints
+// the usage message of the cobra command.
+func (f *cobraFunction) Format(w fmt.State, v rune) {
+	switch v {
+	case 's':
+		use := f.cmd.UseLine()
+		if i := strings.IndexByte(use, ' '); i >= 0 {
+			io.WriteString(w, use[i+1:])
+		}
+	case 'v':
+		io.WriteString(w, f.cmd.UsageString())
+	case 'x':
+		io.WriteString(w, f.cmd.Short)
+	}
+}
+
+// ToCobra returns a cobra command which calls fn with the arguments that it is
+// executed with. The name of the cobra command is the name of fn, which should
+// therefore be created with cli.NamedCommand.
+//
+// Flags are not parsed by cobra, they are passed to fn which handles them
+// itself, including -h and --help. When fn returns a non-zero exit code, the
+// cobra command returns an error carrying it, which has an ExitCode method.
+//
+// Like with cli.CallContext, a context given to the cobra command with
+// ExecuteContext is passed to fn, which must then accept it.
+func ToCobra(fn cli.Function) *cobra.Command {
+	return &cobra.Command{
+		Use:                fmt.Sprintf("%s", fn),
+		Short:              fmt.Sprintf("%x", fn),
+		DisableFlagParsing: true,
+		SilenceErrors:      true,
+		SilenceUsage:       true,
+		RunE: func(c *cobra.Command, args []string) error {
+			ctx := c.Context()
+			// Cobra uses a background context when the command was not
+			// executed with one, which the cli package would reject for
+			// functions that do not accept a context.
+			if ctx == context.Background() {
+				ctx = context.TODO()
+			}
+			if code := cli.CallContext(ctx, fn, args...); code != 0 {
+				return &exitError{code: code}
+			}
+			return nil
+		},
+	}
+}
+
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
+// ExitCode returns the exit code of the cli.Function which failed.
+func (e *exitError) ExitCode() int { return e.code }
@@ -0,0 +1,49 @@
+package cobracli_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/segmentio/cli"
+	"github.com/segmentio/cli/cobracli"
+	"github.com/spf13/cobra"
+)
+
+func ExampleFromCobra() {
+	var name string
+
+	legacy := &cobra.Command{
+		Use:   "legacy",
+		Short: "A command written with cobra",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println("hello", name, args)
+		},
+	}
+	legacy.Flags().StringVar(&name, "name", "Luke", "Someone's name")
+
+	cmd := cli.CommandSet{
+		"legacy": cobracli.FromCobra(legacy),
+	}
+
+	cli.Call(cmd, "legacy", "--name", "Leia", "Organa")
+	// Output: hello Leia [Organa]
+}
+
+func ExampleToCobra() {
+	type config struct {
+		Name string `flag:"--name" default:"Luke"`
+	}
+
+	root := &cobra.Command{Use: "prog"}
+	root.AddCommand(cobracli.ToCobra(cli.NamedCommand("hello", &cli.CommandFunc{
+		Help: "Greets someone",
+		Func: func(config config) {
+			fmt.Println("hello", config.Name)
+		},
+	})))
+
+	cli.Err = os.Stdout
+	root.SetArgs([]string{"hello", "--name", "Han"})
+	root.Execute()
+	// Output: hello Han
+}
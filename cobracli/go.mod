@@ -0,0 +1,16 @@
+module github.com/segmentio/cli/cobracli
+
+go 1.19
+
+require (
+	github.com/segmentio/cli v0.0.0-00010101000000-000000000000
+	github.com/spf13/cobra v1.9.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/segmentio/cli => ../
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WithColor enables the coloring of help and error messages with ANSI escape
+// sequences: section headers are printed in bold, the names of flags and
+// commands in cyan, and the errors of the Error section in red.
+//
+// Colors are only used when the messages are printed to a terminal, and may be
+// disabled by setting the NO_COLOR environment variable of the program to a
+// non-empty value (see no-color.org), or with the --no-color option that this
+// option adds to the program.
+func WithColor() ExecOption {
+	return func(o *execOptions) { o.color = true }
+}
+
+type colorConfig struct {
+	NoColor bool `flag:"--no-color" help:"Disable colors in help and error messages" env:"-"`
+}
+
+// withColorFlag returns a version of cmd which accepts the --no-color option.
+func withColorFlag(cmd Function) Function {
+	return Persistent(new(colorConfig), cmd)
+}
+
+// useColors returns true if the help and error messages of a program called
+// with args, which are printed to w, should be colorized. The command line is
+// inspected directly since the --no-color option is not parsed when help is
+// requested.
+func (o *execOptions) useColors(w io.Writer, args []string) bool {
+	if o.getenv("NO_COLOR") != "" {
+		return false
+	}
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return isTerminal(w)
+		case arg == "--no-color":
+			return false
+		case strings.HasPrefix(arg, "--no-color="):
+			if noColor, err := strconv.ParseBool(arg[len("--no-color="):]); err == nil && noColor {
+				return false
+			}
+		}
+	}
+	return isTerminal(w)
+}
+
+// colors is the palette of help and error messages. When it is true, its
+// methods wrap text in ANSI escape sequences, otherwise they return the text
+// unchanged.
+//
+// Colors are enabled by formatting Help and Usage values with the "+" flag, as
+// in "%+v", which is forwarded to the formatters of commands.
+type colors bool
+
+func colorsOf(w fmt.State) colors { return colors(w.Flag('+')) }
+
+// header paints the header of a section, which is translated by Messages.
+func (c colors) header(s string) string { return c.bold(tr(s)) }
+
+func (c colors) bold(s string) string { return c.paint("1", s) }
+
+func (c colors) flag(s string) string { return c.paint("36", s) }
+
+func (c colors) err(s string) string { return c.paint("31", s) }
+
+// paint wraps s in the escape sequences setting and resetting the color code.
+// The escape sequences take no space on terminals, so they are not counted by
+// displayWidth when aligning the columns of help messages.
+func (c colors) paint(code, s string) string {
+	if !c {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// verb returns the format string of values formatted with the palette, and
+// wrapped to width columns if it is positive.
+func (c colors) verb(width int) string {
+	verb := "%"
+	if c {
+		verb += "+"
+	}
+	if width > 0 {
+		verb += strconv.Itoa(width)
+	}
+	return verb + "v"
+}
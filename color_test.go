@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestColoredHelp(t *testing.T) {
+	type config struct {
+		Name    string `flag:"-n,--name" help:"Name of the user" default:"Luke"`
+		Verbose bool   `flag:"--verbose" help:"Enable verbose output"`
+	}
+
+	cmd := Command(func(config config) {})
+	_, err := cmd.Call(context.TODO(), []string{"--name"}, nil)
+
+	plain := fmt.Sprintf("%v", err)
+	colored := fmt.Sprintf("%+v", err)
+
+	for _, s := range []string{
+		"\x1b[1mUsage:\x1b[0m",
+		"\x1b[1mOptions:\x1b[0m",
+		"\x1b[1mError:\x1b[0m",
+		"\x1b[36m-n, \x1b[0m",
+		"\x1b[31mmissing option value: \"--name\"\x1b[0m",
+	} {
+		if !strings.Contains(colored, s) {
+			t.Errorf("colored message does not contain %q:\n%s", s, colored)
+		}
+	}
+
+	// Once the escape sequences are removed, the colored message must be
+	// identical to the plain one, which means that columns remain aligned.
+	escapes := regexp.MustCompile("\x1b\\[[0-9]+m")
+	if s := escapes.ReplaceAllString(colored, ""); s != plain {
+		t.Errorf("colored message is not aligned:\n%s\nwant:\n%s", s, plain)
+	}
+}
+
+func TestUseColors(t *testing.T) {
+	// The outputs are not terminals when running tests, so the conditions
+	// which disable colors are checked on a program which would otherwise use
+	// them.
+	o := makeExecOptions([]ExecOption{WithEnv("NO_COLOR=1")})
+	if o.useColors(Err, nil) {
+		t.Error("colors are used when NO_COLOR is set")
+	}
+
+	o = makeExecOptions([]ExecOption{WithEnv()})
+	for _, args := range [][]string{{"--no-color"}, {"sub", "--no-color=true"}} {
+		if o.useColors(Err, args) {
+			t.Errorf("colors are used with %q", args)
+		}
+	}
+}
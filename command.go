@@ -1,16 +1,16 @@
 package cli
 
 import (
-	"bytes"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"reflect"
 	"sort"
 	"strings"
-	"text/tabwriter"
-	"unicode/utf8"
+	"text/template"
 )
 
 // Command constructs a Function which delegates to the Go function passed as
@@ -40,16 +40,42 @@ import (
 //		...
 //	})
 //
-// Five keys are recognized in the struct tags: "flag", "env", "help",
-// "default", and "hidden".
+// The keys recognized in the struct tags are "flag", "arg", "env", "help",
+// "default", "hidden", "hidedefault", "secret", "min", "max", "file", "config",
+// "human", "sep", "merge", "encoding", "choices", "placeholder", "group", and
+// "complete".
 //
 // The "flag" struct tag is a comma-separated list of command line flags that
-// map to the field. This tag is required.
+// map to the field. This tag is required, unless the field is a positional
+// argument declared with the "arg" tag.
+//
+// The "arg" struct tag declares that the field receives a positional argument
+// instead of a flag. The tag value is the name of the argument shown in the
+// usage and help messages; arguments are expected in the order they are
+// declared in the struct. A numeric tag value (e.g. arg:"0") sets the position
+// of the argument explicitly, and names it after the field. Arguments without
+// a default value are required. The last argument may be a slice, in which
+// case it receives all remaining positional values:
+//
+//	type config struct {
+//		Source string   `arg:"source" help:"Path to copy files from"`
+//		Target []string `arg:"target" help:"Paths to copy files to" min:"1"`
+//	}
+//
+// The "min" and "max" struct tags bound the number of values that a slice
+// field accepts, whether it is a flag or a positional argument. A usage error
+// is returned when the count of values is out of bounds.
 //
 // The "env" struct tag optionally specifies the name of an environment variable
 // whose value may provide a field value. When the tag is not specified, then
 // environment variables corresponding to long command line flags may provide
-// field values. A tag value of "-" disables this default behavior.
+// field values. A tag value of "-" disables this default behavior. The tag may
+// also be a comma-separated list of names, which are looked up in order; this
+// is useful to retain backward compatibility when renaming variables:
+//
+//	type config struct {
+//		Token string `flag:"--token" env:"API_TOKEN,LEGACY_TOKEN"`
+//	}
 //
 // The "help" struct tag is a human-readable message describing what the field is
 // used for.
@@ -57,12 +83,115 @@ import (
 // The "default" struct tag provides the default value of the field when the
 // argument was missing from the call to the command. Any flag which has no
 // default value and isn't a boolean or a slice type must be passed when calling
-// the command, otherwise a usage error is returned. The special default value
+// the command, otherwise a usage error is returned; such flags are annotated
+// with "(required)" in the help message. The special default value
 // "-" can be used to indicate that the option is not required and should assume
-// its zero-value when omitted.
+// its zero-value when omitted. The default value of slice fields is a list of
+// values separated by commas, or by the separator set with the "sep" tag.
+//
+// Boolean flags accept the values "true", "false", "yes", "no", "on", "off",
+// "enabled", and "disabled", as well as the other forms recognized by
+// strconv.ParseBool, which is convenient when they are set from environment
+// variables.
+//
+// Fields of type []bool are boolean flags which may be repeated, each of their
+// occurrences appending a value to the slice. The short form of these flags
+// may be repeated in a single argument, so "-vv" is equivalent to "-v -v":
+//
+//	type config struct {
+//		Verbose []bool `flag:"-v,--verbose" help:"Increase verbosity"`
+//	}
+//
+// The "human" struct tag is a Boolean which lets integer fields (and slices of
+// integers) accept the human-friendly representations of counts and sizes
+// supported by the human package, like "10K" or "2Mi", while still storing the
+// values in plain integer types.
+//
+// Fields of type []byte are decoded from base64 values, or from hexadecimal
+// values when their "encoding" tag is set to "hex".
+//
+// Fixed-size array fields (e.g. [2]int) must receive exactly as many values as
+// their length, either by repeating the flag or as a single value separated by
+// commas (or by the "sep" tag), like "--point 1,2". Array arguments consume as
+// many positional values as their length.
+//
+// Fields of type time.Duration accept the human-friendly representations of
+// durations, like "2 days" or "1w", in addition to the ones supported by
+// time.ParseDuration. Setting the "human" tag to false on those fields
+// restricts them to the standard library format.
+//
+// The "sep" struct tag of slice fields sets a separator used to split the value
+// of their environment variable into a list. By default, values given on the
+// command line replace the ones from the environment; setting the "merge" tag
+// to "append" combines them instead, the values from the environment coming
+// first:
+//
+//	type config struct {
+//		Tags []string `flag:"--tag" sep:"," merge:"append"`
+//	}
+//
+// The "choices" struct tag is a comma-separated list of the values accepted by
+// the field, any other value resulting in a usage error. The choices of list
+// fields apply to each of their elements, after the values were split on their
+// separator. The choices are shown in the help message, for example as
+// "--format (text|json|yaml)", and are proposed when the value is completed in
+// a shell:
+//
+//	type config struct {
+//		Format string `flag:"--format" choices:"text,json,yaml" default:"text"`
+//	}
+//
+// The "placeholder" struct tag is the name of the value of the field shown in
+// the help message, in place of the name of its type, for example "--path
+// FILE" instead of "--path string":
+//
+//	type config struct {
+//		Path string `flag:"--path" placeholder:"FILE" help:"Path to the input"`
+//	}
+//
+// The "group" struct tag is the name of a subsection of the options in the help
+// message, which helps navigating the help of commands with many flags; the
+// options without a group are listed first:
+//
+//	type config struct {
+//		Host string `flag:"--host" group:"Networking" default:"localhost"`
+//		Port int    `flag:"--port" group:"Networking" default:"8080"`
+//	}
+//
+// The "complete" struct tag is the name of a function registered with
+// RegisterCompletion, which proposes values for the field when its value is
+// completed in a shell.
 //
 // The "hidden" struct flag is a Boolean indicating if the field should be
-// excluded from help text, essentially making it undocumented.
+// excluded from help text, essentially making it undocumented. Hidden fields
+// are still listed, and marked as hidden, when the help is requested with the
+// --help-all option.
+//
+// The "hidedefault" struct tag is a Boolean indicating that the default value
+// of the field is applied but not shown in the help text. The "secret" tag has
+// the same effect, and marks values which must not be displayed, like tokens
+// baked into builds.
+//
+// The "file" struct tag is a Boolean indicating that values of the field may
+// be loaded from files: a value of the form "@path" is replaced by the content
+// of the file at path, and "@-" reads the value from the standard input of the
+// program (see Streams). This is useful to pass secrets or large payloads
+// without going through shell interpolation:
+//
+//	type config struct {
+//		Cert string `flag:"--cert" help:"PEM-encoded certificate" file:"true"`
+//	}
+//
+// The "config" struct tag is a Boolean indicating that the string field holds
+// the path to a configuration file, which provides values for the options
+// that were neither passed on the command line nor set in the environment.
+// The file is a YAML (or JSON) object where each key is the name of a long
+// flag without its leading "--":
+//
+//	type config struct {
+//		Config string `flag:"-c,--config" help:"Path to a configuration file" config:"true" default:"-"`
+//		Name   string `flag:"--name" default:"Luke"`
+//	}
 //
 // If the struct contains a field named `_`, the command will look for a "help"
 // struct tag to define its own help message. Note that the type of the field
@@ -70,7 +199,14 @@ import (
 //
 // The command always injects and handles the -h and --help flags, which can be
 // used to request that call to the command return a help error to describe the
-// configuration options of the command.
+// configuration options of the command. They are listed with the options in
+// help messages, unless HideHelpFlag is set.
+//
+// The --help-verbose flag prints the help message as well, but the options
+// are annotated with the values that the command would receive and where
+// they come from (the command line, an environment variable, a configuration
+// file, or the default value), which helps debugging the configuration of
+// programs. The values of options with a "secret" tag are masked.
 //
 // Every flag starting with a "--" may also be configured via an environment
 // variable. The environment variable is matched by converting the flag name to
@@ -78,8 +214,9 @@ import (
 // environment variables must specify a struct tag env:"-" to disable the
 // feature.
 //
-// Each extra argument to the function is interpreted as a positional argument
-// and decoded as such, for example:
+// Instead of using "arg" tags in the configuration struct, each extra argument
+// to the function may also be interpreted as a positional argument and decoded
+// as such, for example:
 //
 //	// This command expects two integers as positional arguments.
 //	cmd := cli.Command(func(config config, x, y int) {
@@ -89,10 +226,38 @@ import (
 // The last positional argument may be a slice, which consumes as many values as
 // remained on the command invocation.
 //
+// Parameters of type FlagInfo do not consume positional arguments, they receive
+// the record of where the values of the options came from (the command line,
+// environment variables, a configuration file, or default values). Parameters
+// of type Streams do not either, they receive the standard input and outputs
+// of the program. Parameters of the types registered with Provide are set to
+// the values returned by their provider.
+//
 // An extra variadic string parameter may be accepted by the function, which
 // receives any extra arguments found after a "--" separator. This mechanism is
 // often used by programs that spawn other programs to define the limits between
-// the arguments of the first program, and the second command.
+// the arguments of the first program, and the second command. The separator
+// may be made optional with the OptionalSeparator field of CommandFunc.
+//
+// When the variadic string parameter is the only one (except for an optional
+// initial context.Context), the function receives all the arguments as they
+// were given, without parsing options or requiring a separator. This is useful
+// for thin wrappers which forward their arguments to another program:
+//
+//	cmd := cli.Command(func(args ...string) {
+//		...
+//	})
+//
+// The function may return nothing, an error, or an exit code and an error. It
+// may also return a value of any other type and an error, in which case the
+// command accepts an extra -o or --output option selecting the format (text,
+// json, json=<path>, jsonl, yaml, csv, or html) that the value is printed in
+// to the standard output of the program (see Streams), when no errors were
+// returned; slices are printed as lists (see Format and FormatList):
+//
+//	cmd := cli.Command(func(config config) ([]item, error) {
+//		...
+//	})
 //
 // If the command is called with an invalid set of arguments, it returns a
 // non-zero code and a usage error which describes the issue.
@@ -107,6 +272,49 @@ type CommandFunc struct {
 	// A full description of the command.
 	Desc string
 
+	// Examples of invocations of the command, shown in an "Examples" section
+	// after the options in the help message. Each line is indented, so lines
+	// starting with "$ " can show commands followed by their output:
+	//
+	//	Examples: `$ prog copy --recursive src/ dst/`,
+	Examples string
+
+	// An optional footer printed at the end of the help message, after the
+	// options and examples, like references to other commands, links to the
+	// documentation, or a table of exit codes. Each line is indented, but not
+	// wrapped, so the lines of tables remain aligned.
+	Footer string
+
+	// An optional function proposing values to complete the positional
+	// arguments of the command which are not declared in the configuration
+	// struct (see the "complete" struct tag for the other ones).
+	CompleteArgs CompletionFunc
+
+	// Optional names and help messages of the positional arguments which are
+	// received by the parameters of the function following its configuration,
+	// in order. They are shown in the usage line and in the "Arguments"
+	// section of the help message (see the "arg" struct tag for arguments
+	// declared in the configuration struct):
+	//
+	//	cmd := &cli.CommandFunc{
+	//		Func: func(config config, src, dst string) { ... },
+	//		Args: []cli.Arg{
+	//			{Name: "src", Help: "Path to copy files from"},
+	//			{Name: "dst", Help: "Path to copy files to"},
+	//		},
+	//	}
+	Args []Arg
+
+	// An optional template rendering the body of the help message of the
+	// command, in place of CommandTemplate.
+	Template *template.Template
+
+	// When set, the command is deprecated: a warning including the message is
+	// printed each time it runs, and it is annotated as deprecated in the list
+	// of commands of its command set. The message should tell users what to
+	// use instead, for example "use \"prog get\" instead".
+	Deprecated string
+
 	// The function that the command calls out to when invoked.
 	//
 	// See Command for details about the accepted signatures.
@@ -116,6 +324,67 @@ type CommandFunc struct {
 	// the default one that shows the types (but not names) of arguments.
 	Usage string
 
+	// When set to true, options must be placed before positional arguments on
+	// the command line: parsing of options stops at the first positional
+	// argument, and all arguments that follow are treated as positional, even
+	// if they start with a "-". This is useful for wrapper commands which pass
+	// arguments through to other programs, for example:
+	//
+	//	$ prog run mybinary --its-flag
+	OptionsFirst bool
+
+	// When set to true, the variadic string parameter of the function also
+	// receives the positional arguments left after the other parameters were
+	// assigned, so the "--" separator is not required before the command;
+	// for example, "prog run echo hi" works like "prog run -- echo hi".
+	// Options are still parsed anywhere on the command line before "--", so
+	// unknown options remain errors (see OptionsFirst to stop at the first
+	// positional argument).
+	OptionalSeparator bool
+
+	// When set to true, arguments of the form "@path" are replaced by the list
+	// of arguments read from the file at path before the command line gets
+	// parsed. Arguments in the file are separated by white spaces or new lines,
+	// and may be quoted like they would be in a shell. This is useful when the
+	// command lines exceed the limits of the operating system. Programs may
+	// also expand the files of their whole command line, including the names
+	// of sub-commands, with WithArgsFiles.
+	//
+	// Fields loading their values from files (see the "file" struct tag) must
+	// use the --flag=@path form when ArgsFiles is enabled.
+	ArgsFiles bool
+
+	// An optional function called with the arguments of the command before
+	// they get parsed, returning the arguments to parse instead. It may be
+	// used to support legacy syntaxes, expand aliases, or normalize values.
+	TransformArgs func([]string) []string
+
+	// A set of flags registered with the standard library flag package (for
+	// example by packages like glog), which the command accepts in addition to
+	// the flags of its configuration struct. Multi-character flags of the set
+	// are accepted with both one and two leading dashes, and their values are
+	// assigned to the set by calling its Set method.
+	FlagSet *flag.FlagSet
+
+	// When set to true, long flags may also be written with a single dash,
+	// like in -flag=value, following the conventions of the standard library
+	// flag package. Short flags keep precedence when they exist.
+	SingleDashFlags bool
+
+	// When set to true, long flags may be abbreviated to any prefix which is
+	// not shared with another long flag of the command, similarly to GNU
+	// getopt_long; for example, --verb resolves to --verbose.
+	AbbrevFlags bool
+
+	// The prefix of environment variables that the command loads options from
+	// when it is the program called by Exec or Call. When empty, the prefix is
+	// derived from the program name, and the special value "-" indicates that
+	// environment variables should not be prefixed.
+	//
+	// Setting a prefix explicitly ensures that renaming the program binary does
+	// not change the environment variables it is configured with.
+	EnvPrefix string
+
 	// Set of options to not set from the environment
 	// this is a more user-friendly-syntax than IgnoreEnvOptionMap
 	// However, this is strictly for user input and should not be used in the cli code
@@ -129,9 +398,13 @@ type CommandFunc struct {
 	function reflect.Value
 	parser   parser
 	options  structDecoder
+	config   string // name of the option carrying the configuration file
 	values   []decodeFunc
 	variadic bool
 	context  bool
+	output   bool // whether the values returned by the function are printed
+	forward  bool // whether all arguments are passed to the variadic parameter
+	internal bool // whether the command parses options on behalf of the package
 	help     string
 }
 
@@ -166,9 +439,14 @@ func (cmd *CommandFunc) configure() {
 		}
 
 		if x < n {
-			if f := t.In(x); f.Kind() == reflect.Struct {
+			if f := t.In(x); f.Kind() == reflect.Struct && !isInjectedType(f) {
 				cmd.parser, cmd.options, cmd.help = makeStructDecoder(f)
 				x++
+			} else if cmd.variadic && x == n-1 {
+				// The function only accepts the variadic string parameter,
+				// which receives all the arguments.
+				cmd.parser, cmd.options, cmd.help = makeStructDecoder(emptyType)
+				cmd.forward = true
 			} else {
 				panic("cli.Command: expected a struct as first argument but got " + f.String())
 			}
@@ -181,7 +459,16 @@ func (cmd *CommandFunc) configure() {
 		for i := x; i < n; i++ {
 			p := t.In(i)
 
-			if p.Kind() == reflect.Slice {
+			if isInjectedType(p) {
+				cmd.values = append(cmd.values, nil)
+				continue
+			}
+
+			if len(cmd.parser.args) != 0 {
+				panic("cli.Command: positional arguments cannot be declared in both the configuration struct and the function parameters")
+			}
+
+			if isSliceType(p) {
 				cmd.values = append(cmd.values, makeSliceDecoder(p))
 				break
 			}
@@ -199,11 +486,14 @@ func (cmd *CommandFunc) configure() {
 			)
 		}
 	case 2:
-		if r0, r1 := t.Out(0), t.Out(1); r0 != intType || r1 != errorType {
+		if r1 := t.Out(1); r1 != errorType {
 			panic(
-				"cli.Command: expected a function returing (int, error) but got (" + r0.String() + ", " + r1.String() + ")",
+				"cli.Command: expected a function returing (int, error) or (T, error) but got (" + t.Out(0).String() + ", " + r1.String() + ")",
 			)
 		}
+		if t.Out(0) != intType {
+			cmd.addOutputFlag()
+		}
 	default:
 		panic("cli.Command: the function returns too many values")
 	}
@@ -211,6 +501,111 @@ func (cmd *CommandFunc) configure() {
 	if cmd.help == "" {
 		cmd.help = cmd.Help
 	}
+
+	if cmd.FlagSet != nil {
+		cmd.addFlagSet(cmd.FlagSet)
+	}
+
+	cmd.parser.optionsFirst = cmd.OptionsFirst
+	cmd.parser.abbrev = cmd.AbbrevFlags
+	cmd.parser.singleDash = cmd.SingleDashFlags
+
+	for name, field := range cmd.options {
+		if field.config {
+			if cmd.config != "" {
+				panic("cli.Command: the configuration struct has more than one field with a config tag")
+			}
+			cmd.config = name
+		}
+	}
+}
+
+// addOutputFlag registers the -o and --output options which select the format
+// that the values returned by the function are printed in.
+func (cmd *CommandFunc) addOutputFlag() {
+	if cmd.options == nil {
+		cmd.parser, cmd.options, _ = makeStructDecoder(emptyType)
+	}
+
+	if _, exists := cmd.options["--output"]; exists {
+		panic("cli.Command: functions returning values cannot declare a --output flag")
+	}
+
+	flags := []string{"--output"}
+	if !cmd.parser.has("-o") {
+		flags = []string{"-o", "--output"}
+		cmd.parser.aliases["-o"] = "--output"
+	}
+
+	cmd.output = true
+	cmd.parser.options["--output"] = option{}
+	cmd.options["--output"] = structFieldDecoder{
+		flags:   flags,
+		envvars: []string{envNameOf("--output")},
+		help:    "Format of the output (text, json, json=<path>, jsonl, yaml, csv, html)",
+		argtyp:  "format",
+		defval:  "text",
+	}
+}
+
+// addFlagSet registers the flags of fs as options of the command.
+func (cmd *CommandFunc) addFlagSet(fs *flag.FlagSet) {
+	if cmd.options == nil {
+		cmd.parser, cmd.options, _ = makeStructDecoder(emptyType)
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		name := "-" + f.Name
+		if len(f.Name) > 1 {
+			name = "--" + f.Name
+			cmd.parser.aliases["-"+f.Name] = name
+		}
+
+		if _, exists := cmd.options[name]; exists {
+			panic("cli.Command: flag of the flag set is already declared by the configuration struct: " + name)
+		}
+
+		boolean := false
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+			boolean = b.IsBoolFlag()
+		}
+
+		var envvars []string
+		if isLongFlag(name) {
+			envvars = []string{envNameOf(name)}
+		}
+
+		argtyp, help := flag.UnquoteUsage(f)
+		if boolean {
+			argtyp = ""
+		}
+
+		// Like the flag package, don't show zero values as defaults.
+		defval := f.DefValue
+		switch defval {
+		case "false", "0", "[]":
+			defval = ""
+		}
+
+		cmd.parser.options[name] = option{boolean: boolean}
+		cmd.options[name] = structFieldDecoder{
+			flags:   []string{name},
+			envvars: envvars,
+			help:    help,
+			argtyp:  argtyp,
+			defval:  defval,
+			boolean: boolean,
+			extern:  true,
+			decode: func(_ reflect.Value, a []string) error {
+				for _, v := range a {
+					if err := fs.Set(f.Name, v); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+	})
 }
 
 // Call satisfies the Function interface.
@@ -219,15 +614,64 @@ func (cmd *CommandFunc) configure() {
 func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, error) {
 	cmd.configure()
 
+	if cmd.ArgsFiles {
+		a, err := expandArgsFiles(args)
+		if err != nil {
+			return 1, &Usage{Cmd: cmd, Err: err}
+		}
+		args = a
+	}
+
+	if cmd.TransformArgs != nil {
+		args = cmd.TransformArgs(args)
+	}
+
+	if cmd.forward {
+		args = append([]string{"--"}, args...)
+	}
+
+	// Problems found on the command line are collected so they can all be
+	// reported at once instead of only the first one.
+	var errs []error
+
 	options, values, command, err := cmd.parser.parseCommandLine(args)
 	if err != nil {
-		return 1, err
+		errs = appendErrors(errs, err.(*Usage).Err)
+	} else if wantHelp(options) {
+		return 0, &Help{Cmd: cmd}
+	} else if boolOption(options, "--help-all") {
+		return 0, &Help{Cmd: &showAll{wrappedCommand{cmd}}}
 	}
+	// The verbose help is returned once the values of the options have been
+	// resolved, so it can show them.
+	verbose := err == nil && boolOption(options, "--help-verbose")
 
-	if wantHelp(options) {
-		return 0, &Help{Cmd: cmd}
+	info := FlagInfo{
+		sources: make(map[string]FlagSource),
+		aliases: cmd.parser.aliases,
+	}
+
+	// Positional arguments declared in the configuration struct are assigned
+	// to their fields in order, the last one consuming all remaining values
+	// if it is a slice.
+	for _, name := range cmd.parser.args {
+		if len(values) == 0 {
+			break
+		}
+		switch field := cmd.options[name]; {
+		case field.slice:
+			options[name], values = values, nil
+		case field.array > len(values):
+			options[name], values = values, nil
+		case field.array > 0:
+			options[name], values = values[:field.array], values[field.array:]
+		default:
+			options[name], values = values[:1], values[1:]
+		}
 	}
 
+	info.setSources(options, FromCommandLine)
+
 	// If user chooses to pass in IgnoreEnvOptionsMap instead of IgnoreEnvOptions
 	// we do not reset it
 	if cmd.IgnoreEnvOptionsMap == nil {
@@ -244,36 +688,74 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 			continue
 		}
 
-		if _, ok := options[name]; !ok && len(field.envvars) != 0 {
-			for _, e := range field.envvars {
-				if v, ok := lookupEnv(e, env); ok {
-					options[name] = []string{v}
-					break
-				}
+		given, ok := options[name]
+		if ok && !field.append {
+			continue
+		}
+
+		for _, e := range field.envvars {
+			if v, ok := lookupEnvContext(ctx, e, env); ok {
+				options[name] = append(field.splitEnv(v), given...)
+				break
 			}
 		}
 	}
 
+	info.setSources(options, FromEnv)
+
+	if err := cmd.loadConfig(ctx, options); err != nil {
+		return 1, &Usage{Cmd: cmd, Err: err}
+	}
+
+	info.setSources(options, FromConfig)
+
 	for name, field := range cmd.options {
-		if _, ok := options[name]; !ok && field.defval != "" && field.defval != "-" {
-			options[name] = []string{field.defval}
+		if _, ok := options[name]; !ok && field.defval != "" && field.defval != "-" && !field.extern {
+			options[name] = field.defaults()
 		}
 	}
 
-	for name, field := range cmd.options {
-		if _, ok := options[name]; !ok && field.defval == "" && !field.boolean && !field.slice {
-			return 1, &Usage{Cmd: cmd, Err: fmt.Errorf("missing required flag: %q", name)}
+	if verbose {
+		return 0, &Help{Cmd: &verboseHelp{cmd, cmd.valueAnnotations(ctx, options, info, env)}}
+	}
+
+	if printConfigOf(ctx) {
+		cmd.printConfig(ctx, streamsOf(ctx).Stdout, options, info, env)
+		return 0, nil
+	}
+
+	for _, name := range sortedKeys(cmd.options) {
+		field := cmd.options[name]
+		if _, ok := options[name]; !ok && field.required() {
+			errs = append(errs, &ErrMissingRequired{Flag: name, Positional: field.arg})
+			continue
+		}
+		if field.slice {
+			if err := field.checkCount(name, options[name]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// Flags of the external flag set are decoded directly into the set since
+	// they do not have a field in the configuration struct.
+	for _, name := range sortedKeys(cmd.options) {
+		if values, ok := options[name]; ok && cmd.options[name].extern {
+			if err := cmd.options[name].decode(reflect.Value{}, values); err != nil {
+				errs = append(errs, errorf("decoding %q: %w", name, err))
+			}
 		}
 	}
 
 	var params []reflect.Value
+	var provided []int // indexes of the parameters set by providers
 
 	x := 0
 
 	if cmd.context {
 		params = append(params, reflect.ValueOf(ctx))
 		x++
-	} else if ctx != nil && ctx != context.TODO() {
+	} else if ctx := execOptionsOf(ctx).origin; ctx != nil && ctx != context.TODO() {
 		panic("to use context, all commands must accept a context.Context as their first argument")
 	}
 
@@ -283,17 +765,11 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 		// the remaining values.
 		n := t.NumIn()
 
-		if x < n {
+		if x < n && !cmd.forward {
 			// Configuration options are decoded into the first function parameter.
 			v := reflect.New(t.In(x)).Elem()
-			if err := cmd.options.decode(v, options); err != nil {
-				if uerr, ok := err.(*Usage); ok {
-					uerr.Cmd = cmd
-				}
-				if herr, ok := err.(*Help); ok {
-					herr.Cmd = cmd
-				}
-				return 1, err
+			if err := cmd.options.decode(v, options, streamsOf(ctx).Stdin); err != nil {
+				errs = appendErrors(errs, err.(*Usage).Err)
 			}
 			params = append(params, v)
 			x++
@@ -307,9 +783,25 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 			p := t.In(i)
 			v := reflect.New(p).Elem()
 
-			if p.Kind() == reflect.Slice {
+			if p == flagInfoType {
+				params = append(params, reflect.ValueOf(info))
+				continue
+			}
+
+			if p == streamsType {
+				params = append(params, reflect.ValueOf(streamsOf(ctx)))
+				continue
+			}
+
+			if isInjectedType(p) { // provided after parsing succeeded
+				provided = append(provided, len(params))
+				params = append(params, v)
+				continue
+			}
+
+			if isSliceType(p) {
 				if err := cmd.values[i-x](v, values); err != nil {
-					return 1, err
+					errs = append(errs, err)
 				}
 				params = append(params, v)
 				values = nil
@@ -317,38 +809,74 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 			}
 
 			var value []string
-			if len(values) == 0 {
+			switch {
+			case isArrayType(p) && p.Len() < len(values):
+				value, values = values[:p.Len()], values[p.Len():]
+			case isArrayType(p):
+				value, values = values, nil
+			case len(values) == 0:
 				value = []string{""}
-			} else {
+			default:
 				value, values = values[:1], values[1:]
 			}
 
 			if err := cmd.values[i-x](v, value); err != nil {
-				return 1, err
+				errs = append(errs, err)
 			}
 			params = append(params, v)
 		}
 	}
 
+	if cmd.variadic && cmd.OptionalSeparator && len(command) == 0 {
+		command, values = values, nil
+	}
+
 	if len(values) != 0 {
-		return 1, &Usage{
-			Cmd: cmd,
-			Err: fmt.Errorf("too many positional arguments: %q", values),
+		errs = append(errs, &ErrTooManyArgs{Args: values})
+	}
+
+	if cmd.variadic && len(command) == 0 && !cmd.forward {
+		errs = append(errs, errorf("missing command after \"--\" separator"))
+	}
+
+	if !cmd.variadic && len(command) != 0 {
+		errs = append(errs, errorf("unsupported command after \"--\" separator"))
+	}
+
+	var format string
+	if cmd.output {
+		values := options["--output"]
+		format = values[len(values)-1]
+		// The format is validated by the printers so the option accepts
+		// all the formats that they support.
+		if _, err := Format(format, io.Discard); err != nil {
+			if u, ok := err.(*Usage); ok {
+				err = u.Err
+			}
+			errs = append(errs, err)
 		}
 	}
 
-	if cmd.variadic && len(command) == 0 {
-		return 1, &Usage{
-			Cmd: cmd,
-			Err: fmt.Errorf("missing command after \"--\" separator"),
+	if len(errs) != 0 {
+		return 1, &Usage{Cmd: cmd, Err: joinErrors(errs)}
+	}
+
+	if !cmd.internal {
+		if err := runPreRunHooks(ctx); err != nil {
+			return 1, err
 		}
 	}
 
-	if !cmd.variadic && len(command) != 0 {
-		return 1, &Usage{
-			Cmd: cmd,
-			Err: fmt.Errorf("unsupported command after \"--\" separator"),
+	if cmd.Deprecated != "" {
+		warnDeprecated(ctx, cmd.Deprecated)
+	}
+
+	for _, i := range provided {
+		v, err := provide(ctx, params[i].Type())
+		if err != nil {
+			return 1, err
 		}
+		params[i] = v
 	}
 
 	var r []reflect.Value
@@ -358,6 +886,14 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 		r = cmd.function.Call(params)
 	}
 
+	return cmd.results(ctx, r, format)
+}
+
+// results converts the values returned by the function of cmd to the code and
+// error returned by its Call method, printing the output value in format if
+// the function had one.
+func (cmd *CommandFunc) results(ctx context.Context, r []reflect.Value, format string) (int, error) {
+	var err error
 	var ret int
 	switch len(r) {
 	case 0:
@@ -366,11 +902,18 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 			ret = 1
 		}
 	default:
-		ret, _ = r[0].Interface().(int)
 		err, _ = r[1].Interface().(error)
+		switch {
+		case !cmd.output:
+			ret, _ = r[0].Interface().(int)
+		case err != nil:
+			ret = 1
+		default:
+			printResult(streamsOf(ctx).Stdout, format, r[0])
+		}
 	}
 
-	switch e := err.(type) {
+	switch e := callError(err).(type) {
 	case *Help:
 		e.Cmd = cmd
 	case *Usage:
@@ -380,6 +923,80 @@ func (cmd *CommandFunc) Call(ctx context.Context, args, env []string) (int, erro
 	return ret, err
 }
 
+// printResult prints the value v returned by a command function in the given
+// format. Slices are printed as lists, and nil pointers are not printed.
+func printResult(w io.Writer, format string, v reflect.Value) {
+	var p PrintFlusher
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+	}
+
+	if v.Kind() == reflect.Slice && !isBytesType(v.Type()) {
+		p, _ = FormatList(format, w)
+		for i := 0; i < v.Len(); i++ {
+			p.Print(v.Index(i).Interface())
+		}
+	} else {
+		p, _ = Format(format, w)
+		p.Print(reflect.Indirect(v).Interface())
+	}
+
+	p.Flush()
+}
+
+// loadConfig sets the options which were not given on the command line or via
+// environment variables from the configuration file of the command, if any.
+func (cmd *CommandFunc) loadConfig(ctx context.Context, options map[string][]string) error {
+	var path string
+	var explicit bool
+
+	if cmd.config != "" {
+		if values := options[cmd.config]; len(values) != 0 {
+			path, explicit = values[len(values)-1], true
+		} else if defval := cmd.options[cmd.config].defval; defval != "-" {
+			path = defval
+		}
+	}
+
+	if path == "" {
+		if o := execOptionsOf(ctx); o.configDiscovery {
+			path = o.discoverConfigFile()
+		}
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	values, err := loadConfigFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for name, value := range values {
+		if alias, ok := cmd.parser.aliases[name]; ok {
+			name = alias
+		}
+		// The configuration file may be shared by multiple commands of a
+		// program, so keys which do not match any options are ignored.
+		if field, ok := cmd.options[name]; !ok || field.config {
+			continue
+		}
+		if _, ok := options[name]; !ok {
+			options[name] = value
+		}
+	}
+
+	return nil
+}
+
 // Format satisfies the fmt.Formatter interface. It recognizes the following
 // verbs:
 //
@@ -394,31 +1011,38 @@ func (cmd *CommandFunc) Format(w fmt.State, v rune) {
 			return
 		}
 
-		io.WriteString(w, "[options]")
-
-		t := cmd.function.Type()
-		n := t.NumIn()
-		if cmd.variadic {
-			n--
-		}
-
-		i := 1
-		if cmd.context {
-			i = 2
+		if cmd.forward {
+			io.WriteString(w, "[args...]")
+			return
 		}
 
-		for i < n {
-			p := t.In(i)
-			fmt.Fprintf(w, " [%s]", typeNameOf(p))
+		io.WriteString(w, "[options]")
 
-			if p.Kind() == reflect.Slice {
-				break
+		for _, name := range cmd.parser.args {
+			field := cmd.options[name]
+			if len(field.choices) != 0 {
+				name = strings.Join(field.choices, "|")
 			}
+			switch {
+			case field.slice && field.min > 0:
+				fmt.Fprintf(w, " <%s>...", name)
+			case field.slice:
+				fmt.Fprintf(w, " [%s...]", name)
+			case field.required():
+				fmt.Fprintf(w, " <%s>", name)
+			default:
+				fmt.Fprintf(w, " [%s]", name)
+			}
+		}
 
-			i++
+		for i, p := range cmd.positionalParams() {
+			fmt.Fprintf(w, " [%s]", cmd.argName(i, p))
 		}
 
-		if cmd.variadic {
+		switch {
+		case cmd.variadic && cmd.OptionalSeparator:
+			io.WriteString(w, " [--] [command]")
+		case cmd.variadic:
 			io.WriteString(w, " -- [command]")
 		}
 
@@ -431,110 +1055,321 @@ func (cmd *CommandFunc) Format(w fmt.State, v rune) {
 			return
 		}
 
-		if cmd.Desc != "" {
-			for _, line := range strings.Split(cmd.Desc, "\n") {
-				fmt.Fprintf(w, "  %s\n", line)
-			}
-			io.WriteString(w, "\n")
+		cmd.formatHelp(w, colorsOf(w), helpWidthOf(w), nil, showHidden(w))
+
+	case 'x': // help
+		if cmd.help != "" {
+			io.WriteString(w, cmd.help)
+		} else if cmd.Help != "" {
+			// if we're asking for help text, we may not have called configure()
+			// on this CommandFunc yet
+			io.WriteString(w, cmd.Help)
 		}
+	}
+}
 
-		io.WriteString(w, "Options:\n")
+// formatHelp writes the body of the help message of cmd to w, wrapping text to
+// width columns. The annotations replace the default values of the options
+// they are set for, see writeOptions, and the hidden options are listed when
+// all is true.
+func (cmd *CommandFunc) formatHelp(w io.Writer, c colors, width int, annotations map[string]string, all bool) {
+	data := CommandHelp{Cmd: cmd, colors: c}
+	b := new(strings.Builder)
+
+	if cmd.Desc != "" {
+		for _, line := range wrapText(cmd.Desc, width-2) {
+			fmt.Fprintf(b, "  %s\n", line)
+		}
+		data.Desc = b.String()
+		b.Reset()
+	}
 
-		tw := newTabWriter(w)
-		defer tw.Flush()
+	writeArguments(b, cmd, data.colors)
+	data.Arguments = b.String()
+	b.Reset()
 
-		// Compute the length of all short flags in order to align the positions
-		// of short and long flags on different columns.
-		shortLen := 0
+	writeOptions(b, cmd.options, annotations, all, data.colors, width)
+	data.Options = b.String()
+	b.Reset()
 
-		for _, field := range cmd.options {
-			if field.hidden {
-				continue
-			}
-			n := 0
-			for _, f := range field.flags {
-				if isShortFlag(f) {
-					n += utf8.RuneCountInString(f) + 2
-				}
-			}
-			if n > shortLen {
-				shortLen = n
-			}
+	data.Examples = indentLines(cmd.Examples)
+	data.Footer = indentLines(cmd.Footer)
+
+	tmpl := cmd.Template
+	if tmpl == nil {
+		tmpl = CommandTemplate
+	}
+	executeTemplate(w, tmpl, data)
+}
+
+// indentLines indents the non-empty lines of s by two spaces, and terminates
+// the last line with a new line. It returns an empty string if s is empty.
+func indentLines(s string) string {
+	if s == "" {
+		return ""
+	}
+	b := new(strings.Builder)
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		if line != "" {
+			b.WriteString("  ")
 		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
 
-		b := &bytes.Buffer{}
-		b.Grow(128)
+// Arg carries the name and help message of a positional argument of a command,
+// see CommandFunc.Args.
+type Arg struct {
+	Name string
+	Help string
+}
 
-		for _, fieldName := range sortedMapKeys(reflect.ValueOf(cmd.options)) {
-			field := cmd.options[fieldName.String()]
-			if field.hidden {
-				continue
-			}
+// positionalParams returns the types of the parameters of the function of cmd
+// which receive positional arguments, the last one receiving all remaining
+// arguments if it is a slice.
+func (cmd *CommandFunc) positionalParams() []reflect.Type {
+	t := cmd.function.Type()
+	n := t.NumIn()
+	if cmd.variadic {
+		n--
+	}
 
-			b.Reset()
-			b.WriteString("  ") // indent
+	i := 1
+	if cmd.context {
+		i = 2
+	}
 
-			// This counter is used to track how many short and long flags have
-			// been written.
-			//
-			// Short flags are printed first, then long flags. Empty columns are
-			// written between short and long flags to align fields.
-			n := 0
+	var params []reflect.Type
+	for ; i < n; i++ {
+		p := t.In(i)
+		if isInjectedType(p) {
+			continue
+		}
+		params = append(params, p)
+		if isSliceType(p) {
+			break
+		}
+	}
+	return params
+}
 
-			for i, f := range field.flags {
-				if isShortFlag(f) {
-					n += writeFlag(b, f, i, len(field.flags))
-				}
-			}
+// argName returns the name of the positional parameter i of type p, which is
+// set in the Args field or defaults to the name of the type.
+func (cmd *CommandFunc) argName(i int, p reflect.Type) string {
+	if i < len(cmd.Args) && cmd.Args[i].Name != "" {
+		if isSliceType(p) {
+			return cmd.Args[i].Name + "..."
+		}
+		return cmd.Args[i].Name
+	}
+	return typeNameOf(p)
+}
 
-			for n < shortLen {
-				b.WriteByte(' ')
-				n++
-			}
+// writeArguments writes the list of positional arguments of cmd to w, in the
+// format used in help messages. Nothing is written unless the command has
+// arguments with names, since the list would only repeat the usage line.
+func writeArguments(w io.Writer, cmd *CommandFunc, c colors) {
+	var rows [][3]string
+	named := false
+
+	for _, name := range cmd.parser.args {
+		field := cmd.options[name]
+		help := tr(field.help)
+		if field.defval != "" && field.defval != "-" && !field.nodef {
+			help = strings.TrimSpace(help + " " + fmt.Sprintf(tr("(default: %s)"), field.defval))
+		}
+		rows = append(rows, [3]string{name, field.argtyp, help})
+		named = true
+	}
 
-			for i, f := range field.flags {
-				if isLongFlag(f) {
-					writeFlag(b, f, i, len(field.flags))
-				}
+	if !cmd.forward {
+		for i, p := range cmd.positionalParams() {
+			row := [3]string{"", typeNameOf(p), ""}
+			if i < len(cmd.Args) {
+				row[0], row[2] = cmd.Args[i].Name, tr(cmd.Args[i].Help)
+				named = named || row[0] != ""
 			}
+			rows = append(rows, row)
+		}
+	}
 
-			if field.argtyp != "" {
-				b.WriteString(" ")
-				b.WriteString(field.argtyp)
-			}
+	if !named {
+		return
+	}
 
-			b.WriteString("\t")
+	tw := newColumnWriter(w)
+	defer tw.Flush()
 
-			if field.help != "" {
-				b.WriteString("  ")
-				b.WriteString(field.help)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "  %s\t  %s\t", c.flag(row[0]), row[1])
+		if row[2] != "" {
+			fmt.Fprintf(tw, "  %s", row[2])
+		}
+		io.WriteString(tw, "\n")
+	}
+}
+
+// writeOptions writes the list of options to w, one per line, in the format
+// used in help messages. Descriptions which do not fit in wrap columns are
+// wrapped and aligned on their column.
+//
+// The annotations map the names of options to the notes written after their
+// descriptions in place of their default values, when they are not nil.
+//
+// Hidden options are only listed when all is true, and are marked as hidden.
+//
+// Options declaring a group with the "group" tag are listed in subsections
+// named after their group, which follow the options without a group in the
+// order that the groups were first declared in.
+func writeOptions(w io.Writer, options structDecoder, annotations map[string]string, all bool, c colors, wrap int) {
+	var groups []string
+	var first = map[string][]int{} // index of the first field of each group
+	var rows = map[string][]optionRow{}
+	var width [2]int
+
+	for _, fieldName := range sortedMapKeys(reflect.ValueOf(options)) {
+		field := options[fieldName.String()]
+		if (field.hidden && !all) || field.arg || (HideHelpFlag && fieldName.String() == "--help") {
+			continue
+		}
+		row := optionRowOf(field)
+		if annotation, ok := annotations[fieldName.String()]; ok {
+			row.annotation = annotation
+		}
+		cells := row.cells(false)
+		for i := range width {
+			if n := displayWidth(cells[i]); n > width[i] {
+				width[i] = n
 			}
+		}
+		if _, ok := rows[field.group]; !ok && field.group != "" {
+			groups = append(groups, field.group)
+		}
+		if index, ok := first[field.group]; !ok || lessIndex(field.index, index) {
+			first[field.group] = field.index
+		}
+		rows[field.group] = append(rows[field.group], row)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return lessIndex(first[groups[i]], first[groups[j]])
+	})
+
+	tw := newColumnWriter(w)
+	defer tw.Flush()
 
-			if field.defval != "" && field.defval != "-" {
-				fmt.Fprintf(b, " (default: %s)", field.defval)
+	for _, group := range append([]string{""}, groups...) {
+		if group != "" {
+			fmt.Fprintf(tw, "\n  %s\n", c.header(group+":"))
+		}
+
+		for _, row := range rows[group] {
+			cells := row.cells(c)
+			lines := wrapText(cells[2], wrap-(width[0]+width[1]+2))
+			if len(lines) == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t\n", cells[0], cells[1])
+				continue
 			}
+			fmt.Fprintf(tw, "%s\t%s\t  %s\n", cells[0], cells[1], lines[0])
+			for _, line := range lines[1:] {
+				fmt.Fprintf(tw, "%s\t%s\t  %s\n", c.flag(""), c.flag(""), line)
+			}
+		}
+	}
+}
 
-			b.WriteString("\n")
-			tw.Write(b.Bytes())
+// lessIndex compares the indexes of two struct fields, returning true if the
+// field at a is declared before the one at b.
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
 		}
+	}
+	return len(a) < len(b)
+}
 
-	case 'x': // help
-		if cmd.help != "" {
-			io.WriteString(w, cmd.help)
-		} else if cmd.Help != "" {
-			// if we're asking for help text, we may not have called configure()
-			// on this CommandFunc yet
-			io.WriteString(w, cmd.Help)
+// optionRow is the model of a line in the Options section of help messages.
+// Each column is written as a cell of a columnWriter, so rows line up
+// regardless of which columns are empty.
+type optionRow struct {
+	short []string // e.g. "-v"
+	long  []string // e.g. "--verbose"
+	typ   string   // type of the value, empty for boolean flags
+	help  string
+	def   string // default value, empty when not shown
+	req   bool   // whether the option must be set
+	hide  bool   // whether the option is hidden
+	// annotation replaces the note on the default value or requirement of
+	// the option when it is not empty.
+	annotation string
+}
+
+func optionRowOf(field structFieldDecoder) optionRow {
+	row := optionRow{typ: field.argtyp, help: tr(field.help), req: field.required(), hide: field.hidden}
+
+	for _, f := range field.flags {
+		if isShortFlag(f) {
+			row.short = append(row.short, f)
+		} else {
+			row.long = append(row.long, f)
 		}
 	}
+
+	if field.defval != "" && field.defval != "-" && !field.nodef {
+		row.def = field.defval
+	}
+
+	return row
 }
 
-func writeFlag(b *bytes.Buffer, f string, i, n int) int {
-	b.WriteString(f)
-	if (i + 1) < n {
-		b.WriteString(", ")
+// cells returns the columns of the row: the indented short flags, followed by
+// a separator when there are also long flags, then the long flags and the
+// type, and the description with the default value or a note that the option
+// is required. The flags are painted
+// with the palette c.
+func (row optionRow) cells(c colors) (cells [3]string) {
+	short := strings.Join(row.short, ", ")
+	if len(row.short) != 0 && len(row.long) != 0 {
+		short += ", "
+	}
+	cells[0] = "  " + c.flag(short)
+
+	// The type follows the long flags, or takes their place in the column
+	// when there are only short flags.
+	cells[1] = c.flag(strings.Join(row.long, ", "))
+	if row.typ != "" {
+		if len(row.long) != 0 {
+			cells[1] += " "
+		}
+		cells[1] += row.typ
 	}
-	return utf8.RuneCountInString(f) + 2
+
+	annotation := ""
+	switch {
+	case row.annotation != "":
+		annotation = row.annotation
+	case row.req:
+		annotation = tr("(required)")
+	case row.def != "":
+		annotation = fmt.Sprintf(tr("(default: %s)"), row.def)
+	}
+	if row.hide {
+		annotation = strings.TrimSpace(tr("(hidden)") + " " + annotation)
+	}
+
+	switch {
+	case row.help != "" && annotation != "":
+		cells[2] = row.help + " " + annotation
+	case row.help != "":
+		cells[2] = row.help
+	default:
+		cells[2] = annotation
+	}
+
+	return cells
 }
 
 func isShortFlag(s string) bool { return !isLongFlag(s) }
@@ -564,6 +1399,13 @@ func isLongFlag(s string) bool  { return strings.HasPrefix(s, "--") }
 //	$ program top sub-1
 //
 //	$ program top sub-2
+//
+// The help message of a command is printed with "program top sub-1 --help",
+// or "program help top sub-1" unless the set has its own "help" command.
+//
+// Use Group to give a help message and a description to a command set. The
+// command registered under the special key "_" may also carry them, for
+// example a CommandFunc with Help and Desc fields.
 type CommandSet map[string]Function
 
 // Call dispatches the given arguments and environment variables to the
@@ -593,6 +1435,9 @@ func (cmds CommandSet) Call(ctx context.Context, args, env []string) (int, error
 	if wantHelp, args = parseHelp(args); wantHelp {
 		return 0, &Help{Cmd: cmds}
 	}
+	if len(args) != 0 && args[0] == "--help-all" {
+		return 0, &Help{Cmd: &showAll{wrappedCommand{cmds}}}
+	}
 
 	var a string // command name
 	var c Function
@@ -613,29 +1458,121 @@ func (cmds CommandSet) Call(ctx context.Context, args, env []string) (int, error
 	}
 
 	if a == "" {
-		return 1, &Usage{Cmd: cmds, Err: fmt.Errorf("missing command")}
+		return 1, &Usage{Cmd: cmds, Err: errorf("missing command")}
 	}
 
-	if c = cmds[a]; c == nil {
+	// "prog help sub" is the same as "prog sub --help", unless the set has a
+	// command of its own named "help".
+	if a == "help" && cmds["help"] == nil {
+		if len(args) == 0 {
+			return 0, &Help{Cmd: cmds}
+		}
+		return cmds.Call(ctx, append(args, "--help"), env)
+	}
+
+	if c = cmds[a]; c == nil && execOptionsOf(ctx).commandPrefixes {
+		var matches []string
+		for cmd := range cmds {
+			if cmd != "_" && strings.HasPrefix(cmd, a) {
+				matches = append(matches, cmd)
+			}
+		}
+		sort.Strings(matches)
+
+		switch len(matches) {
+		case 0:
+		case 1:
+			a, c = matches[0], cmds[matches[0]]
+		default:
+			return 1, &Usage{Cmd: cmds, Err: errorf("ambiguous command: %q could be %s", a, strings.Join(matches, ", "))}
+		}
+	}
+
+	if c == nil {
 		minLevenshtein := 1000
 		closestCommand := ""
 		for cmd := range cmds {
+			if hiddenOf(cmds[cmd]) {
+				continue
+			}
 			score := levenshtein(a, cmd)
 			if score < minLevenshtein {
 				closestCommand = cmd
 				minLevenshtein = score
 			}
 		}
-		errMessage := fmt.Sprintf("unknown command: %q", a)
 		if similarEnough(a, closestCommand, minLevenshtein) {
-			errMessage += fmt.Sprintf(". Did you mean %q? Use --help to see all commands",
-				closestCommand)
-			return 1, errors.New(errMessage)
+			return 1, errorf("unknown command: %q. Did you mean %q? Use --help to see all commands", a, closestCommand)
+		}
+		return 1, &Usage{Cmd: cmds, Err: errorf("unknown command: %q", a)}
+	}
+
+	if o := execOptionsOf(ctx); o.nestedEnv {
+		env = scopeEnv(env, a, o.caseInsensitiveEnv())
+		ctx = withEnvScope(ctx, a)
+	}
+
+	code, err := NamedCommand(a, c).Call(ctx, args, env)
+	if e, ok := callError(err).(*Usage); ok {
+		cmds.hintFlags(commandPathOf(ctx), a, e.Err)
+	}
+	return code, err
+}
+
+// hintFlags completes the unknown flags found in err, which was returned by the
+// command called, with the other commands of the set which have these flags.
+// The commands of nested sets are searched as well, so users get guided to
+// the right command in large trees of commands.
+func (cmds CommandSet) hintFlags(path []string, called string, err error) {
+	errs, ok := err.(errorList)
+	if !ok {
+		errs = errorList{err}
+	}
+	for _, err := range errs {
+		e, ok := err.(*ErrUnknownFlag)
+		if !ok || len(e.Commands) != 0 {
+			continue
+		}
+		for _, name := range subcommandNames(cmds) {
+			if name != called {
+				e.Commands = findFlag(e.Commands, cmds[name], e.Flag, append(path[:len(path):len(path)], name))
+			}
+		}
+	}
+}
+
+// findFlag appends to list the paths of cmd, and of its sub-commands, which
+// accept the flag.
+func findFlag(list []string, cmd Function, flag string, path []string) []string {
+	if fn := commandFuncOf(cmd); fn != nil {
+		fn.configure()
+		if field, ok := fn.option(flag); ok && !field.hidden {
+			list = append(list, strings.Join(path, " "))
+		}
+	}
+	if cmds := commandsOf(cmd); cmds != nil {
+		for _, name := range subcommandNames(cmds) {
+			list = findFlag(list, cmds[name], flag, append(path[:len(path):len(path)], name))
 		}
-		return 1, &Usage{Cmd: cmds, Err: errors.New(errMessage)}
 	}
+	return list
+}
 
-	return NamedCommand(a, c).Call(ctx, args, env)
+// scopeEnv returns the environment of the command name, where the variables
+// prefixed with the name are placed first and stripped of their prefix, so
+// they take precedence over the other variables. The case of the prefix is
+// ignored if fold is true.
+func scopeEnv(env []string, name string, fold bool) []string {
+	prefix := strings.ToUpper(snakecase(name)) + "_"
+	scoped := make([]string, 0, len(env))
+
+	for _, e := range env {
+		if e, ok := cutEnvPrefix(e, prefix, fold); ok {
+			scoped = append(scoped, e)
+		}
+	}
+
+	return append(scoped, env...)
 }
 
 // similarEnough determines if input and want are similar enough. If input and
@@ -686,31 +1623,88 @@ func (cmds CommandSet) Format(w fmt.State, v rune) {
 			return
 		}
 
-		io.WriteString(w, "Commands:\n")
-		tw := newTabWriter(w)
+		c := colorsOf(w)
+
+		if desc := descOf(cmds["_"]); desc != "" {
+			for _, line := range wrapText(desc, helpWidthOf(w)-2) {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+			io.WriteString(w, "\n")
+		}
 
+		// Commands are listed in sections named after their category, the
+		// ones without a category come first in the "Commands" section.
+		sections := map[string][]string{}
 		for _, cmd := range sortedMapKeys(reflect.ValueOf(cmds)) {
 			cmdKey := cmd.String()
 			if cmdKey == "_" {
 				// Short flag for help text, not a runnable command.
 				continue
 			}
-			fmt.Fprintf(tw, "  %s", cmdKey)
-			// Avoid printing the whitespace if there's no value - makes it
-			// easier to write tests against with text editors that
-			// strip extraneous whitespace from the ends of lines.
-			val := fmt.Sprintf("%x", cmds[cmdKey])
-			if val != "" {
-				io.WriteString(tw, "\t  "+val)
+			if hiddenOf(cmds[cmdKey]) && !showHidden(w) {
+				continue
+			}
+			category := categoryOf(cmds[cmdKey])
+			sections[category] = append(sections[category], cmdKey)
+		}
+
+		categories := make([]string, 0, len(sections))
+		for category := range sections {
+			if category != "" {
+				categories = append(categories, category)
+			}
+		}
+		sort.Strings(categories)
+
+		if _, ok := sections[""]; ok || len(categories) == 0 {
+			categories = append([]string{""}, categories...)
+		}
+
+		for i, category := range categories {
+			if i != 0 {
+				io.WriteString(w, "\n")
+			}
+			title := c.header(category + ":")
+			if category == "" {
+				title = c.bold(tr("Commands") + ":")
+			}
+			io.WriteString(w, title+"\n")
+			tw := newColumnWriter(w)
+
+			nameLen := 0
+			for _, cmdKey := range sections[category] {
+				if n := displayWidth(cmdKey); n > nameLen {
+					nameLen = n
+				}
 			}
-			tw.Write([]byte{'\n'})
+
+			for _, cmdKey := range sections[category] {
+				fmt.Fprintf(tw, "  %s", c.flag(cmdKey))
+				// Avoid printing the whitespace if there's no value - makes it
+				// easier to write tests against with text editors that
+				// strip extraneous whitespace from the ends of lines.
+				val := fmt.Sprintf("%x", cmds[cmdKey])
+				if deprecatedOf(cmds[cmdKey]) != "" {
+					val = strings.TrimSpace(val + " " + tr("(deprecated)"))
+				}
+				if hiddenOf(cmds[cmdKey]) {
+					val = strings.TrimSpace(val + " " + tr("(hidden)"))
+				}
+				for i, line := range wrapText(val, helpWidthOf(w)-(nameLen+4)) {
+					if i != 0 {
+						io.WriteString(tw, "\n"+c.flag(""))
+					}
+					io.WriteString(tw, "\t  "+line)
+				}
+				tw.Write([]byte{'\n'})
+			}
+
+			tw.Flush()
 		}
 
-		tw.Flush()
-		io.WriteString(w, `
-Options:
-  -h, --help  Show this help message
-`)
+		if !HideHelpFlag {
+			fmt.Fprintf(w, "\n%s\n  %s  %s\n", c.header("Options:"), c.flag("-h, --help"), tr("Show this help message"))
+		}
 	case 'x':
 		if cmd, ok := cmds["_"]; ok {
 			fmt.Fprintf(w, "%x", cmd)
@@ -718,6 +1712,19 @@ Options:
 	}
 }
 
+// The description of a command set is the one of the command registered
+// under the special key "_".
+func (cmds CommandSet) desc() string { return descOf(cmds["_"]) }
+
+// The environment prefix of a command set may be configured by the command
+// registered under the special key "_".
+func (cmds CommandSet) envPrefix() (string, bool) {
+	if cmd, ok := cmds["_"]; ok {
+		return envPrefixOf(cmd)
+	}
+	return "", false
+}
+
 // NamedCommand constructs a command which carries the name passed as argument
 // and delegate execution to cmd.
 func NamedCommand(name string, cmd Function) Function {
@@ -736,8 +1743,28 @@ type namedCommand struct {
 //
 // Call satisfies the Function interface.
 func (c *namedCommand) Call(ctx context.Context, args, env []string) (int, error) {
-	code, err := c.cmd.Call(ctx, args, env)
-	switch e := err.(type) {
+	ctx = withCommandPath(ctx, c.name)
+	execOptionsOf(ctx).path.Store(commandPathOf(ctx))
+
+	var code int
+	var err error
+	if commandsOf(c.cmd) == nil {
+		// The program dispatched to this command, which is recorded in a
+		// single span, and runs the pre-run hooks if it does not parse its
+		// arguments.
+		code, err = trace(ctx, func(ctx context.Context) (int, error) {
+			if isLeafFunction(c.cmd) {
+				if err := runPreRunHooks(ctx); err != nil {
+					return 1, err
+				}
+			}
+			return c.cmd.Call(ctx, args, env)
+		})
+	} else {
+		code, err = c.cmd.Call(ctx, args, env)
+	}
+
+	switch e := callError(err).(type) {
 	case *Help:
 		if e.Cmd == nil {
 			e.Cmd = c
@@ -774,12 +1801,50 @@ func (c *namedCommand) Name() string {
 	return c.name
 }
 
+func (c *namedCommand) envPrefix() (string, bool) {
+	return envPrefixOf(c.cmd)
+}
+
+func (c *namedCommand) desc() string { return descOf(c.cmd) }
+
 func (c *namedCommand) configure() {
 	if x, ok := c.cmd.(interface{ configure() }); ok {
 		x.configure()
 	}
 }
 
+func (cmd *CommandFunc) deprecated() string { return cmd.Deprecated }
+
+// envPrefix returns the prefix of environment variables set on cmd, and a
+// boolean indicating whether a prefix was set.
+func (cmd *CommandFunc) envPrefix() (string, bool) {
+	switch cmd.EnvPrefix {
+	case "":
+		return "", false
+	case "-":
+		return "", true
+	default:
+		return cmd.EnvPrefix, true
+	}
+}
+
+func envPrefixOf(cmd Function) (string, bool) {
+	if x, ok := cmd.(interface{ envPrefix() (string, bool) }); ok {
+		return x.envPrefix()
+	}
+	return "", false
+}
+
+// descOf returns the description of cmd, which is empty if it has none.
+func descOf(cmd Function) string {
+	if x, ok := cmd.(interface{ desc() string }); ok {
+		return x.desc()
+	}
+	return ""
+}
+
+func (cmd *CommandFunc) desc() string { return cmd.Desc }
+
 func nameOf(cmd Function) string {
 	if x, ok := cmd.(interface{ Name() string }); ok {
 		return x.Name()
@@ -787,17 +1852,18 @@ func nameOf(cmd Function) string {
 	return ""
 }
 
-func newTabWriter(w io.Writer) *tabwriter.Writer {
-	return tabwriter.NewWriter(w, 0, 0, 0, ' ', 0)
+func wantHelp(options map[string][]string) bool {
+	return boolOption(options, "--help")
 }
 
-func wantHelp(options map[string][]string) bool {
-	if values, ok := options["--help"]; ok {
+// boolOption returns true if the boolean option name is set in options.
+func boolOption(options map[string][]string, name string) bool {
+	if values, ok := options[name]; ok {
 		if len(values) == 0 {
 			return true
 		}
 		for _, v := range values {
-			if v == "true" {
+			if b, _ := parseBool(v); b {
 				return true
 			}
 		}
@@ -820,7 +1886,7 @@ func parseHelp(args []string) (wantHelp bool, next []string) {
 	}
 
 	if hasValue {
-		wantHelp = value == "true"
+		wantHelp, _ = parseBool(value)
 	} else {
 		wantHelp = true
 	}
@@ -828,6 +1894,15 @@ func parseHelp(args []string) (wantHelp bool, next []string) {
 	return
 }
 
+func sortedKeys(m structDecoder) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func sortedMapKeys(m reflect.Value) []reflect.Value {
 	keys := m.MapKeys()
 	sort.Slice(keys, func(i, j int) bool {
@@ -2,9 +2,16 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/segmentio/cli/human"
 )
@@ -54,7 +61,7 @@ Usage:
   [options]
 
 Options:
-      --duration duration
+      --duration duration  (required)
   -h, --help               Show this help message
 
 Error:
@@ -66,3 +73,414 @@ Error:
 		t.Errorf("Struct error: got %q, want %q", b.String(), want)
 	}
 }
+
+func TestCallEnvPrefix(t *testing.T) {
+	type config struct {
+		Flag string `flag:"--flag" default:"-"`
+	}
+
+	t.Setenv("PROG_FLAG", "prog")
+	t.Setenv("OTHER_FLAG", "other")
+	t.Setenv("FLAG", "none")
+
+	var flag string
+	cmd := NamedCommand("prog", &CommandFunc{
+		Func: func(config config) { flag = config.Flag },
+	})
+
+	tests := []struct {
+		options []ExecOption
+		flag    string
+	}{
+		{nil, "prog"},
+		{[]ExecOption{WithEnvPrefix("OTHER")}, "other"},
+		{[]ExecOption{WithEnvPrefix("OTHER_")}, "other"},
+		{[]ExecOption{WithEnvPrefix("")}, "none"},
+	}
+
+	for _, test := range tests {
+		flag = ""
+		call(context.TODO(), cmd, nil, makeExecOptions(test.options))
+		if flag != test.flag {
+			t.Errorf("wrong flag value: got %q, want %q", flag, test.flag)
+		}
+	}
+}
+
+func TestCallCommandPrefixes(t *testing.T) {
+	var called string
+	cmd := CommandSet{
+		"status": Command(func() { called = "status" }),
+		"stash":  Command(func() { called = "stash" }),
+		"start":  Command(func() { called = "start" }),
+	}
+	ctx := context.WithValue(context.TODO(), execOptionsKey{}, makeExecOptions([]ExecOption{WithCommandPrefixes()}))
+
+	if _, err := cmd.Call(ctx, []string{"stat"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if called != "status" {
+		t.Errorf("wrong command called: %q", called)
+	}
+
+	_, err := cmd.Call(ctx, []string{"sta"}, nil)
+	if err == nil {
+		t.Fatal("expected an error for an ambiguous prefix")
+	}
+	if msg := err.(*Usage).Err.Error(); msg != `ambiguous command: "sta" could be start, stash, status` {
+		t.Error("wrong error message:", msg)
+	}
+
+	if _, err := cmd.Call(context.TODO(), []string{"stat"}, nil); err == nil {
+		t.Error("expected an error when prefixes are not enabled")
+	}
+}
+
+func TestCallNestedEnvPrefixes(t *testing.T) {
+	type config struct {
+		Flag string `flag:"--flag" default:"-"`
+	}
+
+	t.Setenv("PROG_FLAG", "prog")
+	t.Setenv("PROG_SUB_FLAG", "sub")
+
+	var flag string
+	cmd := NamedCommand("prog", CommandSet{
+		"sub":   Command(func(config config) { flag = config.Flag }),
+		"other": Command(func(config config) { flag = config.Flag }),
+	})
+
+	tests := []struct {
+		options []ExecOption
+		args    []string
+		flag    string
+	}{
+		{nil, []string{"sub"}, "prog"},
+		{[]ExecOption{WithNestedEnvPrefixes()}, []string{"sub"}, "sub"},
+		{[]ExecOption{WithNestedEnvPrefixes()}, []string{"other"}, "prog"},
+	}
+
+	for _, test := range tests {
+		flag = ""
+		call(context.TODO(), cmd, test.args, makeExecOptions(test.options))
+		if flag != test.flag {
+			t.Errorf("wrong flag value: got %q, want %q", flag, test.flag)
+		}
+	}
+}
+
+func TestCallEnvLookup(t *testing.T) {
+	type config struct {
+		Flag string `flag:"--flag" default:"-"`
+	}
+
+	// The variables of the process are ignored when a lookup function is set.
+	t.Setenv("PROG_FLAG", "process")
+
+	vars := map[string]string{
+		"PROG_FLAG":     "prog",
+		"PROG_SUB_FLAG": "sub",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	var flag string
+	cmd := NamedCommand("prog", CommandSet{
+		"sub":   Command(func(config config) { flag = config.Flag }),
+		"other": Command(func(config config) { flag = config.Flag }),
+	})
+
+	tests := []struct {
+		options []ExecOption
+		args    []string
+		flag    string
+	}{
+		{[]ExecOption{WithEnvLookup(lookup)}, []string{"sub"}, "prog"},
+		{[]ExecOption{WithEnvLookup(lookup), WithNestedEnvPrefixes()}, []string{"sub"}, "sub"},
+		{[]ExecOption{WithEnvLookup(lookup), WithNestedEnvPrefixes()}, []string{"other"}, "prog"},
+		{[]ExecOption{WithEnvLookup(lookup), WithEnv("PROG_FLAG=list")}, []string{"sub"}, "list"},
+		{[]ExecOption{WithEnvLookup(lookup)}, []string{"sub", "--flag=args"}, "args"},
+	}
+
+	for _, test := range tests {
+		flag = ""
+		call(context.TODO(), cmd, test.args, makeExecOptions(test.options))
+		if flag != test.flag {
+			t.Errorf("%q: wrong flag value: got %q, want %q", test.args, flag, test.flag)
+		}
+	}
+}
+
+func TestCallCaseInsensitiveEnv(t *testing.T) {
+	type config struct {
+		Flag string `flag:"--flag" default:"-"`
+	}
+
+	var flag string
+	cmd := NamedCommand("prog", CommandSet{
+		"sub": Command(func(config config) { flag = config.Flag }),
+	})
+
+	tests := []struct {
+		options []ExecOption
+		flag    string
+	}{
+		{[]ExecOption{WithEnv("PROG_flag=prog")}, ""},
+		{[]ExecOption{WithEnv("PROG_flag=prog"), WithCaseInsensitiveEnv()}, "prog"},
+		{[]ExecOption{WithEnv("prog_Flag=prog"), WithCaseInsensitiveEnv()}, "prog"},
+		{[]ExecOption{WithEnv("Prog_Sub_Flag=sub", "PROG_FLAG=prog"), WithCaseInsensitiveEnv(), WithNestedEnvPrefixes()}, "sub"},
+	}
+
+	if runtime.GOOS == "windows" {
+		tests[0].flag = "prog"
+	}
+
+	for _, test := range tests {
+		flag = ""
+		call(context.TODO(), cmd, []string{"sub"}, makeExecOptions(test.options))
+		if flag != test.flag {
+			t.Errorf("wrong flag value: got %q, want %q", flag, test.flag)
+		}
+	}
+}
+
+func TestCallTimeoutFlag(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	cmd := NamedCommand("prog", Command(func(ctx context.Context) {
+		deadline, ok = ctx.Deadline()
+	}))
+	options := []ExecOption{WithTimeoutFlag()}
+
+	call(context.TODO(), cmd, nil, makeExecOptions(options))
+	if ok {
+		t.Error("the context has a deadline when no timeout was set:", deadline)
+	}
+
+	start := time.Now()
+	call(context.TODO(), cmd, []string{"--timeout", "1m"}, makeExecOptions(options))
+	if !ok {
+		t.Fatal("the context has no deadline when a timeout was set")
+	}
+	if d := deadline.Sub(start); d < time.Minute || d > 2*time.Minute {
+		t.Error("wrong deadline:", d)
+	}
+}
+
+func TestCallReporter(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	Err = io.Discard
+
+	cmd := NamedCommand("prog", CommandSet{
+		"ok":   Command(func() {}),
+		"exit": Command(func() error { return Exit(3, errors.New("failed")) }),
+	})
+
+	var reports []Report
+	options := []ExecOption{WithReporter(func(r Report) { reports = append(reports, r) })}
+
+	call(context.TODO(), cmd, []string{"ok"}, makeExecOptions(options))
+	call(context.TODO(), cmd, []string{"exit"}, makeExecOptions(options))
+	call(context.TODO(), cmd, []string{"unknown"}, makeExecOptions(options))
+
+	tests := []struct {
+		path     string
+		code     int
+		category string
+	}{
+		{"prog ok", 0, ""},
+		{"prog exit", 3, "error"},
+		{"prog", 1, "usage"},
+	}
+
+	if len(reports) != len(tests) {
+		t.Fatalf("wrong number of reports: %d", len(reports))
+	}
+
+	for i, test := range tests {
+		r := reports[i]
+		if path := strings.Join(r.Path, " "); path != test.path {
+			t.Errorf("wrong path: got %q, want %q", path, test.path)
+		}
+		if r.Code != test.code {
+			t.Errorf("%s: wrong code: got %d, want %d", test.path, r.Code, test.code)
+		}
+		if r.Category != test.category {
+			t.Errorf("%s: wrong category: got %q, want %q", test.path, r.Category, test.category)
+		}
+	}
+}
+
+func TestCommandSetDesc(t *testing.T) {
+	cmd := CommandSet{
+		"_":    &CommandFunc{Help: "Manage files", Desc: "Files are stored in the current directory."},
+		"copy": &CommandFunc{Help: "Copy files", Func: func() {}},
+	}
+
+	_, err := cmd.Call(context.TODO(), []string{"help"}, nil)
+	if _, ok := err.(*Help); !ok {
+		t.Fatalf("expected a help error, got %v", err)
+	}
+
+	want := `
+Usage:
+  [command] [-h] [--help] ...
+
+  Files are stored in the current directory.
+
+Commands:
+  copy  Copy files
+
+Options:
+  -h, --help  Show this help message
+`
+	if got := fmt.Sprint(err); got != want {
+		t.Errorf("wrong help message:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestQuietDeprecationWarning(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	stderr := new(strings.Builder)
+	Err = stderr
+
+	cmd := NamedCommand("prog", CommandSet{
+		"legacy": Deprecated("", Command(func(struct{}) {})),
+	})
+	options := makeExecOptions([]ExecOption{WithVerbosityFlags()})
+
+	call(context.TODO(), cmd, []string{"legacy"}, options)
+	if !strings.Contains(stderr.String(), "deprecated") {
+		t.Errorf("the deprecation warning was not printed: %q", stderr)
+	}
+
+	stderr.Reset()
+	options = makeExecOptions([]ExecOption{WithVerbosityFlags()})
+	call(context.TODO(), cmd, []string{"-q", "legacy"}, options)
+	if stderr.Len() != 0 {
+		t.Errorf("the deprecation warning was printed in quiet mode: %q", stderr)
+	}
+}
+
+func TestCommandOutputFormats(t *testing.T) {
+	defer func(out io.Writer) { Out = out }(Out)
+	Out = io.Discard
+
+	cmd := Command(func(struct{}) ([]int, error) { return nil, nil })
+
+	tests := []struct {
+		format string
+		err    string
+	}{
+		{format: "json=.[0]"},
+		{format: "jsonl"},
+		{format: "ndjson"},
+		{format: "xml", err: `unsupported output format: "xml"`},
+		{format: "json=[0]", err: `invalid path: "[0]" must start with '.'`},
+	}
+
+	for _, test := range tests {
+		_, err := cmd.Call(context.TODO(), []string{"-o", test.format}, nil)
+		var usage *Usage
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: %v", test.format, err)
+		case test.err != "" && !errors.As(err, &usage):
+			t.Errorf("%q: expected a usage error but got %v", test.format, err)
+		case test.err != "" && usage.Err.Error() != test.err:
+			t.Errorf("%q: wrong error: %q, want %q", test.format, usage.Err, test.err)
+		}
+	}
+}
+
+func TestChainSplit(t *testing.T) {
+	type buildConfig struct {
+		Tag     string `flag:"-t,--tag"`
+		NoCache bool   `flag:"--no-cache"`
+	}
+
+	type pushConfig struct {
+		Tag      string `flag:"-t,--tag"`
+		Registry string `flag:"--registry" default:"-"`
+	}
+
+	build := Command(func(buildConfig, []string) {})
+	push := Command(func(pushConfig, []string) {})
+	list := CommandSet{"ls": Command(func(struct{}) {})}
+
+	tests := []struct {
+		cmds []Function
+		args []string
+		argv [][]string
+	}{
+		{
+			cmds: []Function{build, push},
+			args: []string{"-t", "v1", "--no-cache", "--registry=ghcr.io", "img"},
+			argv: [][]string{
+				{"-t", "v1", "--no-cache", "img"},
+				{"-t", "v1", "--registry=ghcr.io", "img"},
+			},
+		},
+		{
+			cmds: []Function{build, push},
+			args: []string{"--nope", "--registry", "ghcr.io", "--", "extra"},
+			argv: [][]string{
+				{"--nope", "--", "extra"},
+				{"--registry", "ghcr.io", "--", "extra"},
+			},
+		},
+		{
+			cmds: []Function{build, list},
+			args: []string{"ls", "--no-cache"},
+			argv: [][]string{
+				{"ls", "--no-cache"},
+				{"ls", "--no-cache"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		argv := Chain(test.cmds...).(*chainCommand).split(test.args)
+		if !reflect.DeepEqual(argv, test.argv) {
+			t.Errorf("%q: got %q, want %q", test.args, argv, test.argv)
+		}
+	}
+}
+
+func TestLazyUnknownCommand(t *testing.T) {
+	constructed := 0
+	lazy := func() Function {
+		return Lazy(func() Function {
+			constructed++
+			return Command(func(struct{}) {})
+		})
+	}
+
+	cmd := CommandSet{
+		"deploy":  lazy(),
+		"debug":   Hidden(lazy()),
+		"destroy": Deprecated("use delete", lazy()),
+	}
+
+	// The errors are not formatted with %v, which would construct the
+	// commands to list them in the help message.
+	_, err := cmd.Call(context.TODO(), []string{"deplo"}, nil)
+	if err == nil || !strings.Contains(err.Error(), `Did you mean "deploy"?`) {
+		t.Errorf("wrong error: %s", err.Error())
+	}
+
+	_, err = cmd.Call(context.TODO(), []string{"debu"}, nil)
+	if err == nil || strings.Contains(err.Error(), "Did you mean") {
+		t.Errorf("hidden command was suggested: %s", err.Error())
+	}
+
+	if !hiddenOf(cmd["debug"]) || deprecatedOf(cmd["destroy"]) != "use delete" {
+		t.Error("the metadata of the wrappers of lazy commands was lost")
+	}
+
+	if constructed != 0 {
+		t.Errorf("%d lazy commands were constructed", constructed)
+	}
+}
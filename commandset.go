@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Add registers cmd under name in the set, returning an error if the name is
+// invalid or already used by another command. The command is configured
+// immediately, so errors in its declaration are detected when the set is
+// assembled rather than when the command is called.
+//
+// Add, Remove, and Merge are intended to assemble command sets at startup, for
+// example from plugins or feature flags; they must not be used concurrently
+// with calls to the set.
+func (cmds CommandSet) Add(name string, cmd Function) error {
+	if err := validCommandName(name); err != nil {
+		return err
+	}
+	if _, exists := cmds[name]; exists {
+		return fmt.Errorf("command %q already exists", name)
+	}
+	configure(cmd)
+	cmds[name] = cmd
+	return nil
+}
+
+// Remove removes the command registered under name from the set, returning
+// true if it existed.
+func (cmds CommandSet) Remove(name string) bool {
+	_, exists := cmds[name]
+	delete(cmds, name)
+	return exists
+}
+
+// Merge adds the commands of other to the set. Command sets registered under
+// the same name in both sets are merged recursively, other name collisions are
+// errors. When an error is returned, the set is left unmodified.
+func (cmds CommandSet) Merge(other CommandSet) error {
+	var conflicts []string
+	if err := cmds.conflicts(other, "", &conflicts); err != nil {
+		return err
+	}
+
+	switch len(conflicts) {
+	case 0:
+	case 1:
+		return fmt.Errorf("command %q already exists", conflicts[0])
+	default:
+		sort.Strings(conflicts)
+		return fmt.Errorf("commands already exist: %q", conflicts)
+	}
+
+	cmds.merge(other)
+	return nil
+}
+
+// conflicts appends the paths of the commands of other which collide with the
+// ones of the set to list.
+func (cmds CommandSet) conflicts(other CommandSet, prefix string, list *[]string) error {
+	for name, cmd := range other {
+		if err := validCommandName(name); err != nil {
+			return err
+		}
+		existing, exists := cmds[name]
+		if !exists {
+			continue
+		}
+		a, ok1 := existing.(CommandSet)
+		b, ok2 := cmd.(CommandSet)
+		if !ok1 || !ok2 {
+			*list = append(*list, prefix+name)
+		} else if err := a.conflicts(b, prefix+name+" ", list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cmds CommandSet) merge(other CommandSet) {
+	for name, cmd := range other {
+		if existing, ok := cmds[name].(CommandSet); ok {
+			existing.merge(cmd.(CommandSet))
+			continue
+		}
+		configure(cmd)
+		cmds[name] = cmd
+	}
+}
+
+// validCommandName returns an error if name cannot be used as a command name
+// on a command line.
+func validCommandName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("invalid empty command name")
+	case name == "_":
+		return nil
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("invalid command name starting with a dash: %q", name)
+	case strings.IndexFunc(name, unicode.IsSpace) >= 0:
+		return fmt.Errorf("invalid command name containing spaces: %q", name)
+	}
+	return nil
+}
+
+// withCommand returns a copy of cmd with the command sub added under name, if
+// cmd is a command set, or a named command set, which does not have a command
+// of this name already. Otherwise, cmd is returned unchanged.
+func withCommand(cmd Function, name string, sub Function) Function {
+	named, _ := cmd.(*namedCommand)
+	set, ok := cmd.(CommandSet)
+	if named != nil {
+		set, ok = named.cmd.(CommandSet)
+	}
+	if !ok {
+		return cmd
+	}
+	if _, exists := set[name]; exists {
+		return cmd
+	}
+
+	tmp := make(CommandSet, len(set)+1)
+	for name, cmd := range set {
+		tmp[name] = cmd
+	}
+	tmp[name] = sub
+
+	if named != nil {
+		return NamedCommand(named.name, tmp)
+	}
+	return tmp
+}
+
+// configure configures cmd if it supports it, recursively for command sets.
+func configure(cmd Function) {
+	switch c := cmd.(type) {
+	case CommandSet:
+		for name, cmd := range c {
+			if name != "_" {
+				configure(cmd)
+			}
+		}
+	case interface{ configure() }:
+		c.configure()
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCommandSetAdd(t *testing.T) {
+	cmds := CommandSet{"a": Command(func() {})}
+
+	if err := cmds.Add("b", Command(func() {})); err != nil {
+		t.Error(err)
+	}
+	if err := cmds.Add("a", Command(func() {})); err == nil || err.Error() != `command "a" already exists` {
+		t.Error("wrong error for an existing command:", err)
+	}
+	for _, name := range []string{"", "-x", "two words"} {
+		if err := cmds.Add(name, Command(func() {})); err == nil {
+			t.Errorf("no errors for the invalid name %q", name)
+		}
+	}
+
+	if !cmds.Remove("a") || cmds.Remove("a") {
+		t.Error("wrong results removing a command")
+	}
+	if names := commandNames(cmds); !reflect.DeepEqual(names, []string{"b"}) {
+		t.Error("wrong commands:", names)
+	}
+}
+
+func TestCommandSetAddConfigures(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("adding an invalid command did not panic")
+		}
+	}()
+	CommandSet{}.Add("bad", Command(func(int) {}))
+}
+
+func TestCommandSetMerge(t *testing.T) {
+	cmds := CommandSet{
+		"a":   Command(func() {}),
+		"top": CommandSet{"x": Command(func() {})},
+	}
+
+	if err := cmds.Merge(CommandSet{
+		"b":   Command(func() {}),
+		"top": CommandSet{"y": Command(func() {})},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := commandNames(cmds); !reflect.DeepEqual(names, []string{"a", "b", "top"}) {
+		t.Error("wrong commands:", names)
+	}
+	if names := commandNames(cmds["top"].(CommandSet)); !reflect.DeepEqual(names, []string{"x", "y"}) {
+		t.Error("wrong nested commands:", names)
+	}
+
+	err := cmds.Merge(CommandSet{
+		"a":   Command(func() {}),
+		"c":   Command(func() {}),
+		"top": CommandSet{"x": Command(func() {})},
+	})
+	if err == nil || err.Error() != `commands already exist: ["a" "top x"]` {
+		t.Error("wrong error for conflicting commands:", err)
+	}
+	if _, exists := cmds["c"]; exists {
+		t.Error("the set was modified by a failed merge")
+	}
+}
+
+func commandNames(cmds CommandSet) []string {
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
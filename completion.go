@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// CompletionFunc is the type of functions proposing values to complete the
+// word being typed on a command line. The function receives the part of the
+// word which was already typed, and returns the candidate values; values which
+// do not start with prefix are discarded.
+type CompletionFunc func(ctx context.Context, prefix string) []string
+
+// RegisterCompletion registers fn under name, making it available to complete
+// the values of configuration fields with a "complete" struct tag set to name:
+//
+//	func init() {
+//		cli.RegisterCompletion("buckets", func(ctx context.Context, prefix string) []string {
+//			return listBuckets(ctx, prefix)
+//		})
+//	}
+//
+//	type config struct {
+//		Bucket string `flag:"--bucket" complete:"buckets"`
+//	}
+//
+// Registering a function under a name which already had one replaces it.
+func RegisterCompletion(name string, fn CompletionFunc) {
+	completions.Store(name, fn)
+}
+
+var completions sync.Map // string => CompletionFunc
+
+func completionOf(name string) CompletionFunc {
+	if fn, ok := completions.Load(name); ok {
+		return fn.(CompletionFunc)
+	}
+	return nil
+}
+
+// completer is implemented by commands which can propose completions for the
+// last word of args, which is the one being typed.
+type completer interface {
+	complete(ctx context.Context, args []string) []string
+}
+
+// completeOf returns the completions proposed by cmd for the last word of args.
+func completeOf(ctx context.Context, cmd Function, args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	if c, ok := cmd.(completer); ok {
+		return c.complete(ctx, args)
+	}
+	return nil
+}
+
+func (cmd *CommandFunc) complete(ctx context.Context, args []string) []string {
+	cmd.configure()
+
+	word, prev := args[len(args)-1], args[:len(args)-1]
+
+	if strings.HasPrefix(word, "-") {
+		if name, value, ok := splitNameValue(word); ok {
+			field, _ := cmd.option(name)
+			return prefixAll(name+"=", cmd.suggest(ctx, field, value))
+		}
+		return filterPrefix(cmd.flags(), word)
+	}
+
+	// Values of options are either in the same word, or in the next one.
+	if n := len(prev); n != 0 && isOption(prev[n-1]) && !strings.Contains(prev[n-1], "=") {
+		if field, ok := cmd.option(prev[n-1]); ok && !field.boolean {
+			return cmd.suggest(ctx, field, word)
+		}
+	}
+
+	pos := 0
+	for i := 0; i < len(prev); i++ {
+		switch arg := prev[i]; {
+		case isCommandSeparator(arg):
+			return nil
+		case isOption(arg):
+			if field, ok := cmd.option(arg); ok && !field.boolean && !strings.Contains(arg, "=") {
+				i++
+			}
+		default:
+			pos++
+		}
+	}
+
+	// Positional arguments declared in the configuration struct, the last one
+	// receiving all remaining values when it is a slice.
+	if n := len(cmd.parser.args); pos < n {
+		return cmd.suggest(ctx, cmd.options[cmd.parser.args[pos]], word)
+	} else if n != 0 && cmd.options[cmd.parser.args[n-1]].slice {
+		return cmd.suggest(ctx, cmd.options[cmd.parser.args[n-1]], word)
+	}
+
+	if cmd.CompleteArgs != nil {
+		return filterPrefix(cmd.CompleteArgs(ctx, word), word)
+	}
+	return nil
+}
+
+// option returns the decoder of the option or alias name.
+func (cmd *CommandFunc) option(name string) (structFieldDecoder, bool) {
+	if alias, ok := cmd.parser.aliases[name]; ok {
+		name = alias
+	}
+	field, ok := cmd.options[name]
+	return field, ok && !field.arg
+}
+
+// flags returns the sorted list of flags accepted by the command, except for
+// the hidden ones.
+func (cmd *CommandFunc) flags() []string {
+	var flags []string
+	for _, field := range cmd.options {
+		if !field.hidden && !field.arg {
+			flags = append(flags, field.flags...)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// suggest returns the values proposed for the word being typed as value of
+// field, by the completion function registered under the name of its
+// "complete" tag, or from the list of its choices.
+func (cmd *CommandFunc) suggest(ctx context.Context, field structFieldDecoder, word string) []string {
+	if fn := completionOf(field.suggest); fn != nil {
+		return filterPrefix(fn(ctx, word), word)
+	}
+	return filterPrefix(field.choices, word)
+}
+
+func (cmds CommandSet) complete(ctx context.Context, args []string) []string {
+	word, prev := args[len(args)-1], args[:len(args)-1]
+
+	for i, arg := range prev {
+		if isCommandSeparator(arg) {
+			return nil
+		}
+		if isOption(arg) {
+			continue
+		}
+		if cmd, ok := cmds[arg]; ok && arg != "_" {
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return completeOf(ctx, cmd, rest)
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(word, "-") {
+		return filterPrefix([]string{"--help", "-h"}, word)
+	}
+
+	return filterPrefix(subcommandNames(cmds), word)
+}
+
+func (g Group) complete(ctx context.Context, args []string) []string {
+	return g.Commands.complete(ctx, args)
+}
+
+func (c *namedCommand) complete(ctx context.Context, args []string) []string {
+	return completeOf(ctx, c.cmd, args)
+}
+
+func (w wrappedCommand) complete(ctx context.Context, args []string) []string {
+	return completeOf(ctx, w.cmd, args)
+}
+
+func (c *lazyCommand) complete(ctx context.Context, args []string) []string {
+	return c.get().complete(ctx, args)
+}
+
+// filterPrefix returns the values of list which start with prefix.
+func filterPrefix(list []string, prefix string) []string {
+	var values []string
+	for _, s := range list {
+		if strings.HasPrefix(s, prefix) {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func prefixAll(prefix string, list []string) []string {
+	for i, s := range list {
+		list[i] = prefix + s
+	}
+	return list
+}
+
+// WithCompletion enables the shell completion of the program's command lines.
+// When the program is a command set without a "completion" command, the
+// option adds a "completion" command to it, which prints the script loading
+// the completions in bash, zsh, fish, or PowerShell, for example:
+//
+//	$ source <(prog completion bash)
+//
+// The scripts call the program with the hidden "__complete" argument followed
+// by the words of the command line, the last one being the word to complete,
+// and the program prints the completions one per line. The completions include
+// the commands, the flags, the choices of fields with a "choices" tag, and the
+// values of fields with a "complete" tag (see RegisterCompletion).
+//
+// Programs which are not command sets may print the scripts with
+// WriteCompletion.
+func WithCompletion() ExecOption {
+	return func(o *execOptions) { o.completion = true }
+}
+
+// withCompletionCommand returns a version of cmd which has a "completion"
+// command printing the completion scripts of program, if cmd is a command set.
+func withCompletionCommand(cmd Function, program string) Function {
+	type config struct {
+		Shell string `arg:"shell" choices:"bash,zsh,fish,powershell" help:"Shell to generate the script for"`
+	}
+	return withCommand(cmd, "completion", &CommandFunc{
+		Help: "Generate shell completion scripts",
+		Desc: "Prints the script loading the completions of the program in the shell, " +
+			"which may be added to its configuration, like with \"source <(" + program + " completion bash)\".",
+		Func: func(config config, s Streams) error {
+			return WriteCompletion(s.Stdout, config.Shell, program)
+		},
+	})
+}
+
+// withCompletion returns a version of cmd which answers the requests for
+// completions made by the completion scripts.
+func withCompletion(cmd Function) Function {
+	return &completionCommand{wrappedCommand{cmd}}
+}
+
+type completionCommand struct{ wrappedCommand }
+
+func (c *completionCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	if len(args) != 0 && args[0] == "__complete" {
+		w := streamsOf(ctx).Stdout
+		for _, s := range completeOf(ctx, c.cmd, args[1:]) {
+			fmt.Fprintln(w, s)
+		}
+		return 0, nil
+	}
+	return c.cmd.Call(ctx, args, env)
+}
+
+// WriteCompletion writes to w the script loading the completions of program in
+// shell, which is one of "bash", "zsh", "fish", or "powershell". The program
+// must be executed with the WithCompletion option for the completions to work.
+func WriteCompletion(w io.Writer, shell, program string) error {
+	tmpl, ok := completionScripts[shell]
+	if !ok {
+		return errorf("unsupported shell: %q", shell)
+	}
+	if program = strings.TrimSpace(program); program == "" {
+		program = filepath.Base(os.Args[0])
+	}
+	return tmpl.Execute(w, struct{ Program, Func string }{
+		Program: program,
+		Func:    "_" + strings.Map(shellIdent, program) + "_completion",
+	})
+}
+
+// shellIdent replaces the characters of program names which are not valid in
+// the names of shell functions.
+func shellIdent(r rune) rune {
+	if r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+		return r
+	}
+	return '_'
+}
+
+var completionScripts = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Parse(`# bash completion for {{.Program}}
+{{.Func}}() {
+    local cur words cword
+    if declare -F _init_completion >/dev/null 2>&1; then
+        _init_completion -n =: || return
+    else
+        cur=${COMP_WORDS[COMP_CWORD]}
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($("${words[0]}" __complete "${words[@]:1:cword}" 2>/dev/null))
+    # Bash only replaces the part of the word which follows the last "=".
+    if [[ $cur == *=* && $COMP_WORDBREAKS == *=* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#*=}")
+    fi
+}
+complete -o default -F {{.Func}} {{.Program}}
+`)),
+
+	"zsh": template.Must(template.New("zsh").Parse(`#compdef {{.Program}}
+{{.Func}}() {
+    local -a completions
+    completions=(${(f)"$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    if (( ${#completions} )); then
+        compadd -- "${completions[@]}"
+    else
+        _files
+    fi
+}
+if [ "$funcstack[1]" = "{{.Func}}" ]; then
+    {{.Func}} "$@"
+else
+    compdef {{.Func}} {{.Program}}
+fi
+`)),
+
+	"fish": template.Must(template.New("fish").Parse(`# fish completion for {{.Program}}
+function {{.Func}}
+    set -l words (commandline -opc)
+    $words[1] __complete $words[2..-1] (commandline -ct) 2>/dev/null
+end
+complete -c {{.Program}} -a '({{.Func}})'
+`)),
+
+	"powershell": template.Must(template.New("powershell").Parse(`# PowerShell completion for {{.Program}}
+Register-ArgumentCompleter -Native -CommandName '{{.Program}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $elements = @($commandAst.CommandElements | Where-Object { $_.Extent.StartOffset -lt $cursorPosition })
+    $words = @($elements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        $words += ''
+    }
+    & $elements[0].ToString() __complete @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)),
+}
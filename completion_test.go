@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	RegisterCompletion("test-buckets", func(ctx context.Context, prefix string) []string {
+		return []string{"archive", "assets", "backups"}
+	})
+
+	type config struct {
+		Bucket  string   `flag:"-b,--bucket" complete:"test-buckets"`
+		Verbose bool     `flag:"-v,--verbose"`
+		Hidden  bool     `flag:"--hidden" hidden:"true"`
+		Format  string   `flag:"--format" choices:"text,json" default:"text"`
+		Source  string   `arg:"source" complete:"test-buckets"`
+		Targets []string `arg:"targets"`
+	}
+
+	cmd := CommandSet{
+		"copy": &CommandFunc{
+			Func: func(config) {},
+		},
+		"debug": Hidden(Command(func(struct{}) {})),
+		"list": &CommandFunc{
+			Func: func(struct{}, []string) {},
+			CompleteArgs: func(ctx context.Context, prefix string) []string {
+				return []string{"one", "two"}
+			},
+		},
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{""}, []string{"copy", "list"}},
+		{[]string{"co"}, []string{"copy"}},
+		{[]string{"-"}, []string{"--help", "-h"}},
+		{[]string{"copy", "--"}, []string{"--bucket", "--format", "--help", "--verbose"}},
+		{[]string{"copy", "--format", "j"}, []string{"json"}},
+		{[]string{"copy", "-b", "a"}, []string{"archive", "assets"}},
+		{[]string{"copy", "--bucket=b"}, []string{"--bucket=backups"}},
+		{[]string{"copy", "-v", ""}, []string{"archive", "assets", "backups"}},
+		{[]string{"copy", "-b", "x", "a"}, []string{"archive", "assets"}},
+		{[]string{"copy", "src", ""}, nil},
+		{[]string{"copy", "--", ""}, nil},
+		{[]string{"list", "t"}, []string{"two"}},
+		{[]string{"unknown", ""}, nil},
+	}
+
+	for _, test := range tests {
+		got := completeOf(context.TODO(), NamedCommand("prog", cmd), test.args)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: wrong completions: got %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestCompletionProtocol(t *testing.T) {
+	type config struct {
+		Format string `flag:"--format" choices:"text,json" default:"text"`
+	}
+
+	cmd := NamedCommand("prog", CommandSet{
+		"get": Command(func(config) {}),
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"__complete", ""}, "completion\nget\nversion\n"},
+		{[]string{"__complete", "get", "--format", "j"}, "json\n"},
+		{[]string{"__complete", "completion", ""}, "bash\nzsh\nfish\npowershell\n"},
+	}
+
+	for _, test := range tests {
+		out := new(strings.Builder)
+		code := CallWith(cmd, test.args,
+			WithCompletion(),
+			WithVersion("v1.0.0"),
+			WithStreams(Streams{Stdout: out}),
+		)
+		if code != 0 {
+			t.Errorf("%q: wrong exit code: %d", test.args, code)
+		}
+		if s := out.String(); s != test.want {
+			t.Errorf("%q: wrong completions: got %q, want %q", test.args, s, test.want)
+		}
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	cmd := NamedCommand("my-prog", CommandSet{
+		"get": Command(func(struct{}) {}),
+	})
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			out := new(strings.Builder)
+			code := CallWith(cmd, []string{"completion", shell},
+				WithCompletion(),
+				WithStreams(Streams{Stdout: out}),
+			)
+			if code != 0 {
+				t.Fatalf("wrong exit code: %d", code)
+			}
+			if s := out.String(); !strings.Contains(s, "__complete") || !strings.Contains(s, "my-prog") {
+				t.Errorf("wrong completion script:\n%s", s)
+			}
+		})
+	}
+
+	if err := WriteCompletion(io.Discard, "tcsh", "my-prog"); err == nil {
+		t.Error("expected an error for an unsupported shell")
+	}
+}
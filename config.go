@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// loadConfigFile reads the configuration file at path, returning the values
+// that it contains indexed by long flag names.
+//
+// The file is expected to be a YAML (or JSON) object where each key is the name
+// of a long flag without its leading "--", and each value is either a scalar
+// or a list of scalars. Keys may also be written in snake or camel case, they
+// are converted to the kebab case convention of flag names.
+func loadConfigFile(path string) (map[string][]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string][]string)
+	if len(doc.Content) == 0 { // empty file
+		return values, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected an object at the top level", path)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		name := "--" + kebabcase(key.Value)
+
+		switch value.Kind {
+		case yaml.ScalarNode:
+			values[name] = []string{value.Value}
+		case yaml.SequenceNode:
+			list := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("%s:%d: unsupported value for %q", path, item.Line, key.Value)
+				}
+				list = append(list, item.Value)
+			}
+			values[name] = list
+		default:
+			return nil, fmt.Errorf("%s:%d: unsupported value for %q", path, value.Line, key.Value)
+		}
+	}
+
+	return values, nil
+}
+
+// discoverConfigFile searches the standard locations for the configuration
+// file of the program, returning an empty string if none were found. The
+// locations depend on the XDG_CONFIG_HOME and HOME environment variables of
+// the program.
+func (o *execOptions) discoverConfigFile() string {
+	program := o.program
+	if program == "" {
+		return ""
+	}
+
+	var dirs []string
+	if dir := o.getenv("XDG_CONFIG_HOME"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	home := o.getenv("HOME")
+	if home == "" && o.env == nil {
+		home, _ = os.UserHomeDir()
+	}
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+
+	for _, dir := range dirs {
+		for _, file := range []string{"config.yaml", "config.yml", "config.json"} {
+			path := filepath.Join(dir, program, file)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+
+	return ""
+}
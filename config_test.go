@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("name: Luke\nlast_name: Skywalker\nsiblings:\n  - Leia\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"--name":      {"Luke"},
+		"--last-name": {"Skywalker"},
+		"--siblings":  {"Leia"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("wrong configuration values: got %q, want %q", values, want)
+	}
+}
+
+func TestLoadConfigFileError(t *testing.T) {
+	for _, content := range []string{
+		"- a\n- b\n",
+		"name:\n  first: Luke\n",
+		"names:\n  - [Luke]\n",
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadConfigFile(path); err == nil {
+			t.Errorf("expected an error loading %q", content)
+		}
+	}
+}
+
+func TestCallConfig(t *testing.T) {
+	type config struct {
+		Config string `flag:"--config" config:"true" default:"-"`
+		Name   string `flag:"--name" default:"Anakin"`
+		Other  string `flag:"--other" default:"-"`
+	}
+
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	if err := os.Mkdir(filepath.Join(home, "prog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "prog", "config.yaml"), []byte("name: Luke\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	explicit := filepath.Join(t.TempDir(), "other.yaml")
+	if err := os.WriteFile(explicit, []byte("name: Leia\nother: 42\nunknown: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	cmd := NamedCommand("prog", &CommandFunc{
+		Func: func(config config) { name = config.Name },
+	})
+
+	tests := []struct {
+		args    []string
+		options []ExecOption
+		name    string
+	}{
+		{nil, nil, "Anakin"},
+		{nil, []ExecOption{WithConfigDiscovery()}, "Luke"},
+		{[]string{"--name", "Han"}, []ExecOption{WithConfigDiscovery()}, "Han"},
+		{[]string{"--config", explicit}, nil, "Leia"},
+		{[]string{"--config", explicit}, []ExecOption{WithConfigDiscovery()}, "Leia"},
+	}
+
+	for _, test := range tests {
+		name = ""
+		call(context.TODO(), cmd, test.args, makeExecOptions(test.options))
+		if name != test.name {
+			t.Errorf("%q: wrong name: got %q, want %q", test.args, name, test.name)
+		}
+	}
+
+	if code := call(context.TODO(), cmd, []string{"--config", filepath.Join(home, "missing.yaml")}, nil); code == 0 {
+		t.Error("expected an error when the configuration file does not exist")
+	}
+}
+
+func TestConfigDiscoveryEnv(t *testing.T) {
+	type config struct {
+		Name string `flag:"--name" default:"Anakin"`
+	}
+
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".config", "prog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".config", "prog", "config.yaml"), []byte("name: Luke\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The directories are looked up in the environment of the program
+	// rather than in the one of the process.
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var name string
+	cmd := NamedCommand("prog", &CommandFunc{
+		Func: func(config config) { name = config.Name },
+	})
+
+	tests := []struct {
+		env  []string
+		name string
+	}{
+		{[]string{"HOME=" + home}, "Luke"},
+		{[]string{"XDG_CONFIG_HOME=" + filepath.Join(home, ".config")}, "Luke"},
+		{[]string{"HOME=" + t.TempDir()}, "Anakin"},
+	}
+
+	for _, test := range tests {
+		name = ""
+		call(context.TODO(), cmd, nil, makeExecOptions([]ExecOption{WithConfigDiscovery(), WithEnv(test.env...)}))
+		if name != test.name {
+			t.Errorf("%q: wrong name: got %q, want %q", test.env, name, test.name)
+		}
+	}
+}
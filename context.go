@@ -0,0 +1,144 @@
+package cli
+
+import "context"
+
+// withValue is like context.WithValue, but preserves the context that the
+// program was called with, which is used to verify that commands which do not
+// accept a context.Context were not given one.
+func withValue(ctx context.Context, key, value interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	if ctx.Value(execOptionsKey{}) == nil {
+		ctx = context.WithValue(ctx, execOptionsKey{}, execOptionsOf(ctx))
+	}
+	return context.WithValue(ctx, key, value)
+}
+
+type commandPathKey struct{}
+
+// withCommandPath returns a context where name is appended to the path of
+// commands which led to the command being called.
+func withCommandPath(ctx context.Context, name string) context.Context {
+	path := commandPathOf(ctx)
+	return withValue(ctx, commandPathKey{}, append(path[:len(path):len(path)], name))
+}
+
+// commandPathOf returns the path of named commands which led to the command
+// being called with ctx, starting with the program name.
+func commandPathOf(ctx context.Context) []string {
+	if ctx != nil {
+		path, _ := ctx.Value(commandPathKey{}).([]string)
+		return path
+	}
+	return nil
+}
+
+type envScopeKey struct{}
+
+// withEnvScope returns a context where name is appended to the scopes of the
+// environment variables of the command being called, see WithNestedEnvPrefixes.
+func withEnvScope(ctx context.Context, name string) context.Context {
+	scopes := envScopesOf(ctx)
+	return withValue(ctx, envScopeKey{}, append(scopes[:len(scopes):len(scopes)], name))
+}
+
+// envScopesOf returns the names of the command sets' commands which scoped the
+// environment variables of the command being called with ctx.
+func envScopesOf(ctx context.Context) []string {
+	if ctx != nil {
+		scopes, _ := ctx.Value(envScopeKey{}).([]string)
+		return scopes
+	}
+	return nil
+}
+
+type preRunKey struct{}
+
+// PreRun returns a Function which runs hook before any command of cmd is
+// executed, after their arguments were successfully parsed. When cmd is a
+// command set, this includes the commands of nested command sets. The hook
+// receives the context of the call, and the path of names of the commands
+// which were dispatched to, starting with the program name:
+//
+//	cmd := cli.PreRun(func(ctx context.Context, path []string) error {
+//		return loadCredentials(ctx)
+//	}, cli.CommandSet{
+//		...
+//	})
+//
+// The command is not executed if the hook returns an error, which is returned
+// instead. When multiple hooks are declared in a tree of commands, the outer
+// hooks run first.
+func PreRun(hook func(ctx context.Context, path []string) error, cmd Function) Function {
+	return &preRunCommand{wrappedCommand{cmd}, hook}
+}
+
+type preRunCommand struct {
+	wrappedCommand
+	hook func(context.Context, []string) error
+}
+
+func (c *preRunCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	if invocationOf(ctx) == nil {
+		ctx = withValue(ctx, invocationKey{}, &invocation{preRun: make(map[*preRunCommand]bool)})
+	}
+	hooks := preRunHooksOf(ctx)
+	ctx = withValue(ctx, preRunKey{}, append(hooks[:len(hooks):len(hooks)], c))
+	if isLeafFunction(c.cmd) {
+		if err := runPreRunHooks(ctx); err != nil {
+			return 1, err
+		}
+	}
+	return c.cmd.Call(ctx, args, env)
+}
+
+func preRunHooksOf(ctx context.Context) []*preRunCommand {
+	if ctx != nil {
+		hooks, _ := ctx.Value(preRunKey{}).([]*preRunCommand)
+		return hooks
+	}
+	return nil
+}
+
+// runPreRunHooks runs the hooks declared with PreRun in the commands which led
+// to the command being called with ctx. Each hook runs once per invocation of
+// the program, even if multiple commands are called, like with Chain.
+func runPreRunHooks(ctx context.Context) error {
+	inv := invocationOf(ctx)
+	for _, c := range preRunHooksOf(ctx) {
+		if inv.preRun[c] {
+			continue
+		}
+		inv.preRun[c] = true
+		if err := c.hook(ctx, commandPathOf(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type invocationKey struct{}
+
+// invocation carries the state shared by the commands called during one
+// invocation of a program. It is created by the outermost PreRun command.
+type invocation struct {
+	// The PreRun commands whose hooks already ran.
+	preRun map[*preRunCommand]bool
+}
+
+func invocationOf(ctx context.Context) *invocation {
+	if ctx != nil {
+		inv, _ := ctx.Value(invocationKey{}).(*invocation)
+		return inv
+	}
+	return nil
+}
+
+// isLeafFunction returns true if cmd is a command which is neither a command
+// set nor backed by a command function, like CallFunc values or the adapters
+// of other libraries. Since these commands do not parse their arguments, the
+// pre-run hooks run when they are dispatched to.
+func isLeafFunction(cmd Function) bool {
+	return commandsOf(cmd) == nil && commandFuncOf(cmd) == nil
+}
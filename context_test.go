@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreRun(t *testing.T) {
+	type globals struct {
+		Region string `flag:"--region" default:"us-west-2"`
+	}
+
+	var g globals
+	var calls []string
+	hook := func(name string) func(context.Context, []string) error {
+		return func(ctx context.Context, path []string) error {
+			calls = append(calls, name+": "+strings.Join(path, " "))
+			return nil
+		}
+	}
+
+	tests := []struct {
+		scenario string
+		cmd      Function
+		args     []string
+		calls    []string
+	}{
+		{
+			scenario: "persistent",
+			cmd: PreRun(hook("hook"), Persistent(&g, CommandSet{
+				"ls": Command(func(struct{}) {}),
+			})),
+			args:  []string{"ls", "--region", "eu-west-1"},
+			calls: []string{"hook: prog ls"},
+		},
+		{
+			scenario: "chain",
+			cmd: PreRun(hook("hook"), CommandSet{
+				"build-and-push": Chain(Command(func(struct{}) {}), Command(func(struct{}) {})),
+			}),
+			args:  []string{"build-and-push"},
+			calls: []string{"hook: prog build-and-push"},
+		},
+		{
+			scenario: "chain of commands with their own hooks",
+			cmd: Chain(
+				PreRun(hook("build"), Command(func(struct{}) {})),
+				PreRun(hook("push"), Command(func(struct{}) {})),
+			),
+			calls: []string{"build: prog", "push: prog"},
+		},
+		{
+			scenario: "call function",
+			cmd: PreRun(hook("outer"), CommandSet{
+				"run": PreRun(hook("inner"), CallFunc(func(context.Context, []string, []string) (int, error) {
+					calls = append(calls, "run")
+					return 0, nil
+				})),
+			}),
+			args:  []string{"run"},
+			calls: []string{"outer: prog run", "inner: prog run", "run"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			calls = nil
+			if _, err := CallErr(context.TODO(), NamedCommand("prog", test.cmd), test.args...); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(calls, test.calls) {
+				t.Errorf("wrong calls: got %q, want %q", calls, test.calls)
+			}
+		})
+	}
+}
@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"bytes"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/segmentio/cli/human"
 )
 
 const uintSize = 32 << (^uint(0) >> 32 & 1)
@@ -19,20 +29,43 @@ type decodeFunc func(reflect.Value, []string) error
 // corresponding value is the decoder for that field.
 type structDecoder map[string]structFieldDecoder
 
-func (s structDecoder) decode(value reflect.Value, options map[string][]string) error {
-	for option, values := range options {
+// decode assigns the values of options to the fields of value. The values of
+// the fields with a file tag may be read from stdin.
+func (s structDecoder) decode(value reflect.Value, options map[string][]string, stdin io.Reader) error {
+	names := make([]string, 0, len(options))
+	for option := range options {
+		names = append(names, option)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, option := range names {
 		f := s[option]
+		if f.index == nil { // --help, or flags of an external flag set
+			continue
+		}
 		v := value.FieldByIndex(f.index)
 
-		switch err := f.decode(v, values).(type) {
+		values, err := options[option], error(nil)
+		if f.file {
+			values, err = readValueFiles(values, stdin)
+		}
+		if err == nil {
+			err = f.decode(v, values)
+		}
+
+		switch err := err.(type) {
 		case nil:
 		case *Usage:
-			err.Err = fmt.Errorf("decoding %q: %w", option, err.Err)
-			return err
+			errs = append(errs, errorf("decoding %q: %w", option, err.Err))
 		default:
-			return &Usage{Err: fmt.Errorf("decoding %q: %w", option, err)}
+			errs = append(errs, errorf("decoding %q: %w", option, err))
 		}
 	}
+
+	if len(errs) != 0 {
+		return &Usage{Err: joinErrors(errs)}
+	}
 	return nil
 }
 
@@ -46,11 +79,76 @@ type structFieldDecoder struct {
 	argtyp  string
 	defval  string
 	hidden  bool
+	secret  bool
+	nodef   bool
 	boolean bool
 	slice   bool
+	array   int
+	arg     bool
+	config  bool
+	file    bool // values of the form "@path" are read from files
+	sep     string
+	append  bool
+	extern  bool // flag of a flag.FlagSet, not stored in the struct
+	min     int
+	max     int
+	suggest string   // name of the completion function of the field
+	choices []string // values accepted by the field, any when empty
+	group   string   // section of the help message listing the field
 	decode  decodeFunc
 }
 
+// required returns true if a value must be provided for the field.
+func (f structFieldDecoder) required() bool {
+	return f.defval == "" && !f.boolean && !f.slice && !f.extern
+}
+
+// splitEnv returns the list of values held by the environment variable value
+// v, which is split on the field separator if it has one.
+func (f structFieldDecoder) splitEnv(v string) []string {
+	if f.sep == "" {
+		return []string{v}
+	}
+	values := strings.Split(v, f.sep)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// defaults returns the list of default values of the field. The default value
+// of slices is split on their separator, or on commas if they have none.
+func (f structFieldDecoder) defaults() []string {
+	if !f.slice {
+		return []string{f.defval}
+	}
+	sep := f.sep
+	if sep == "" {
+		sep = ","
+	}
+	values := strings.Split(f.defval, sep)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// checkCount validates that the number of values given to a slice field is
+// within the bounds set by its "min" and "max" tags.
+func (f structFieldDecoder) checkCount(name string, values []string) error {
+	kind := "flag"
+	if f.arg {
+		kind = "argument"
+	}
+	switch n := len(values); {
+	case n < f.min:
+		return errorf("not enough values for %s %q, expected at least %d but got %d", kind, name, f.min, n)
+	case f.max > 0 && n > f.max:
+		return errorf("too many values for %s %q, expected at most %d but got %d", kind, name, f.max, n)
+	}
+	return nil
+}
+
 // makeStructDecoder creates a parser and struct decoder based on the given
 // struct type, which is expected to represent the options for a command. The
 // decoder automatically includes an additional "--help" Boolean decoder.
@@ -58,6 +156,9 @@ type structFieldDecoder struct {
 // The returned parser is programmed with flag alternatives (aliases) and
 // additional metadata so that a command line can be parsed correctly.
 //
+// Fields declared with an "arg" tag are registered as positional arguments of
+// the parser, and added to the struct decoder under their argument name.
+//
 // The final argument is the value of the "help" tag for the struct field named
 // "_", if it exists.
 func makeStructDecoder(t reflect.Type) (parser, structDecoder, string) {
@@ -70,12 +171,39 @@ func makeStructDecoder(t reflect.Type) (parser, structDecoder, string) {
 			boolean: true,
 			decode:  decodeBool,
 		},
+		"--help-all": structFieldDecoder{
+			index:   nil,
+			flags:   []string{"--help-all"},
+			help:    "Show this help message with the hidden options",
+			hidden:  true,
+			boolean: true,
+			decode:  decodeBool,
+		},
+		"--help-verbose": structFieldDecoder{
+			index:   nil,
+			flags:   []string{"--help-verbose"},
+			help:    "Show this help message with the values of the options and their sources",
+			hidden:  true,
+			boolean: true,
+			decode:  decodeBool,
+		},
 	}
 
+	var args []structField
+
 	forEachStructField(t, nil, func(field structField) {
 		boolean := field.isBoolean()
 		decoder := makeStructFieldDecoder(field)
 
+		if field.arg != "" {
+			if _, exists := s[field.arg]; exists {
+				panic("repeated argument in configuration struct: " + field.arg)
+			}
+			s[field.arg] = decoder
+			args = append(args, field)
+			return
+		}
+
 		for i, flag := range field.flags {
 			flag = strings.TrimSpace(flag)
 			if _, exists := p.aliases[flag]; exists {
@@ -89,12 +217,26 @@ func makeStructDecoder(t reflect.Type) (parser, structDecoder, string) {
 			if n := len(field.flags) - 1; i < n {
 				p.aliases[flag] = strings.TrimSpace(field.flags[n])
 			} else {
-				p.options[flag] = option{boolean: boolean}
+				p.options[flag] = option{boolean: boolean, repeated: field.isCounter(), hidden: field.hidden}
 				s[flag] = decoder
 			}
 		}
 	})
 
+	sort.SliceStable(args, func(i, j int) bool {
+		return args[i].argpos < args[j].argpos
+	})
+
+	for i, arg := range args {
+		if i != 0 && arg.argpos == args[i-1].argpos {
+			panic("repeated argument position in configuration struct: " + arg.arg)
+		}
+		if arg.isSlice() && i != len(args)-1 {
+			panic("only the last argument of a configuration struct may be a slice: " + arg.arg)
+		}
+		p.args = append(p.args, arg.arg)
+	}
+
 	if helpField, ok := t.FieldByName("_"); ok {
 		return p, s, helpField.Tag.Get("help")
 	}
@@ -105,16 +247,47 @@ func makeStructDecoder(t reflect.Type) (parser, structDecoder, string) {
 // makeStructFieldDecoder creates a decoder for a struct field, containing a
 // decode function appropriate for the field type.
 func makeStructFieldDecoder(f structField) structFieldDecoder {
+	makeDecoder := makeValueDecoder
+	switch {
+	case f.strict:
+		makeDecoder = makeStrictDecoder
+	case f.human:
+		makeDecoder = makeHumanDecoder
+	case f.enc == "hex":
+		makeDecoder = makeHexDecoder
+	}
+
+	// The choices apply to each element of lists, after the values were
+	// split on their separator.
+	choose := func(decode decodeFunc) decodeFunc {
+		if decode == nil || len(f.choices) == 0 {
+			return decode
+		}
+		return makeChoicesDecoder(decode, f.choices)
+	}
+
 	var decode decodeFunc
-	switch f.typ.Kind() {
-	case reflect.Slice:
-		decode = makeSliceDecoder(f.typ)
+	switch {
+	case f.isSlice():
+		decode = makeElemSliceDecoder(f.typ.Elem(), choose(makeDecoder(f.typ.Elem())))
+	case f.isArray():
+		decode = makeArrayDecoder(f.typ, choose(makeDecoder(f.typ.Elem())), f.sep)
 	default:
-		decode = makeValueDecoder(f.typ)
+		decode = choose(makeDecoder(f.typ))
 	}
 	if decode == nil {
 		panic("makeFieldDecoder called with unsupported type: " + f.typ.String())
 	}
+	argtyp := typeNameOf(f.typ)
+	if f.enc != "" {
+		argtyp = f.enc + strings.TrimPrefix(argtyp, "base64")
+	}
+	if len(f.choices) != 0 {
+		argtyp = "(" + strings.Join(f.choices, "|") + ")"
+	}
+	if f.placeholder != "" {
+		argtyp = f.placeholder
+	}
 	return structFieldDecoder{
 		index:   f.index,
 		flags:   f.flags,
@@ -122,10 +295,23 @@ func makeStructFieldDecoder(f structField) structFieldDecoder {
 		help:    f.help,
 		defval:  f.defval,
 		hidden:  f.hidden,
+		secret:  f.secret,
+		nodef:   f.nodef,
 		boolean: f.isBoolean(),
 		slice:   f.isSlice(),
+		array:   f.arrayLen(),
+		arg:     f.arg != "",
+		config:  f.config,
+		file:    f.file,
+		sep:     f.sep,
+		append:  f.merge == "append",
+		min:     f.min,
+		max:     f.max,
+		suggest: f.suggest,
+		choices: f.choices,
+		group:   f.group,
 		decode:  decode,
-		argtyp:  typeNameOf(f.typ),
+		argtyp:  argtyp,
 	}
 }
 
@@ -142,8 +328,15 @@ func makeStructFieldDecoder(f structField) structFieldDecoder {
 // * If the tag is empty, `envvars` is a list of all long options, converted to
 //   environment variable name equivalents.
 // * If the tag is `-`, `envvars` is `nil`.
-// * Otherwise, `envvars` is only the single tag value.
+// * Otherwise, `envvars` is the comma-separated list of names in the tag.
+//
+// Fields with an `arg` tag are positional arguments and have no flags. The tag
+// value is the name of the argument, its position being the order in which the
+// arguments are declared. A numeric tag value sets the position explicitly and
+// names the argument after the field.
 func forEachStructField(t reflect.Type, index []int, do func(structField)) {
+	argpos := 0
+
 	for i, n := 0, t.NumField(); i < n; i++ {
 		f := t.Field(i)
 
@@ -168,11 +361,31 @@ func forEachStructField(t reflect.Type, index []int, do func(structField)) {
 			panic("configuration struct contains unsupported field type: " + f.Name + " " + f.Type.String())
 		}
 
-		var splitFlags = strings.Split(f.Tag.Get("flag"), ",")
-		flags := make([]string, len(splitFlags))
-		for i := range splitFlags {
-			flags[i] = strings.TrimSpace(splitFlags[i])
+		var flags []string
+		var arg string
+		var pos int
+
+		if name, ok := f.Tag.Lookup("arg"); ok {
+			if _, hasFlag := f.Tag.Lookup("flag"); hasFlag {
+				panic("configuration struct field has both a flag and an arg tag: " + f.Name)
+			}
+			if n, err := strconv.Atoi(name); err == nil {
+				arg, pos = kebabcase(f.Name), n
+			} else {
+				arg, pos = name, argpos
+			}
+			if arg == "" {
+				arg = kebabcase(f.Name)
+			}
+			argpos++
+		} else {
+			splitFlags := strings.Split(f.Tag.Get("flag"), ",")
+			flags = make([]string, len(splitFlags))
+			for i := range splitFlags {
+				flags[i] = strings.TrimSpace(splitFlags[i])
+			}
 		}
+
 		var envvars []string
 
 		switch env := f.Tag.Get("env"); env {
@@ -185,7 +398,11 @@ func forEachStructField(t reflect.Type, index []int, do func(structField)) {
 		case "-":
 			envvars = nil
 		default:
-			envvars = append(envvars, env)
+			for _, e := range strings.Split(env, ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					envvars = append(envvars, e)
+				}
+			}
 		}
 
 		hidden, err := strconv.ParseBool(f.Tag.Get("hidden"))
@@ -193,18 +410,185 @@ func forEachStructField(t reflect.Type, index []int, do func(structField)) {
 			hidden = false
 		}
 
+		secret, err := strconv.ParseBool(f.Tag.Get("secret"))
+		if err != nil {
+			secret = false
+		}
+
+		hidedefault, err := strconv.ParseBool(f.Tag.Get("hidedefault"))
+		if err != nil {
+			hidedefault = false
+		}
+
+		file, err := strconv.ParseBool(f.Tag.Get("file"))
+		if err != nil {
+			file = false
+		}
+
+		config, err := strconv.ParseBool(f.Tag.Get("config"))
+		if err != nil {
+			config = false
+		}
+		if config && f.Type.Kind() != reflect.String {
+			panic("configuration struct field has a config tag but is not a string: " + f.Name)
+		}
+
+		human, err := strconv.ParseBool(f.Tag.Get("human"))
+		if err != nil {
+			human = false
+		}
+		_, hasHuman := f.Tag.Lookup("human")
+		if hasHuman && !isHumanType(f.Type) && !(isListType(f.Type) && isHumanType(f.Type.Elem())) {
+			panic("configuration struct field has a human tag but is not an integer or a duration: " + f.Name)
+		}
+
+		encoding := f.Tag.Get("encoding")
+		switch encoding {
+		case "", "base64", "hex":
+			if encoding != "" && !isBytesType(f.Type) && !(isListType(f.Type) && isBytesType(f.Type.Elem())) {
+				panic("configuration struct field has an encoding tag but is not a []byte: " + f.Name)
+			}
+		default:
+			panic("configuration struct field has an invalid encoding tag: " + f.Name + " " + encoding)
+		}
+
+		sep := f.Tag.Get("sep")
+		if sep != "" && !isListType(f.Type) {
+			panic("configuration struct field has a sep tag but is not a slice or an array: " + f.Name)
+		}
+
+		merge := f.Tag.Get("merge")
+		switch merge {
+		case "", "replace":
+		case "append":
+			if !isSliceType(f.Type) {
+				panic("configuration struct field has a merge tag but is not a slice: " + f.Name)
+			}
+		default:
+			panic("configuration struct field has an invalid merge tag: " + f.Name + " " + merge)
+		}
+
+		min, max := parseCountTag(f, "min"), parseCountTag(f, "max")
+		if (min != 0 || max != 0) && !isSliceType(f.Type) {
+			panic("configuration struct field has min or max tags but is not a slice: " + f.Name)
+		}
+		if max != 0 && min > max {
+			panic("configuration struct field has a min tag greater than its max tag: " + f.Name)
+		}
+
+		placeholder := f.Tag.Get("placeholder")
+		if placeholder != "" && f.Type.Kind() == reflect.Bool {
+			panic("configuration struct field has a placeholder tag but is a boolean: " + f.Name)
+		}
+
+		var choices []string
+		if tag := f.Tag.Get("choices"); tag != "" {
+			if f.Type.Kind() == reflect.Bool {
+				panic("configuration struct field has a choices tag but is a boolean: " + f.Name)
+			}
+			choices = strings.Split(tag, ",")
+			for i := range choices {
+				choices[i] = strings.TrimSpace(choices[i])
+			}
+		}
+
 		do(structField{
 			typ:     f.Type,
 			index:   fieldIndex,
 			envvars: envvars,
 			flags:   flags,
+			arg:     arg,
+			argpos:  pos,
 			help:    f.Tag.Get("help"),
 			defval:  f.Tag.Get("default"),
 			hidden:  hidden,
+			secret:  secret,
+			nodef:   hidedefault || secret,
+			file:    file,
+			config:  config,
+			human:   human,
+			strict:  hasHuman && !human,
+			enc:     encoding,
+			sep:     sep,
+			merge:   merge,
+			min:     min,
+			max:     max,
+			suggest: f.Tag.Get("complete"),
+			choices: choices,
+			group:   f.Tag.Get("group"),
+
+			placeholder: placeholder,
 		})
 	}
 }
 
+// makeChoicesDecoder wraps decode to reject the values which are not in the
+// list of choices.
+func makeChoicesDecoder(decode decodeFunc, choices []string) decodeFunc {
+	return func(v reflect.Value, a []string) error {
+		for _, s := range a {
+			if !contains(choices, s) {
+				return errorf("invalid value %q, expected one of: %s", s, strings.Join(choices, ", "))
+			}
+		}
+		return decode(v, a)
+	}
+}
+
+// readValueFiles replaces the values of the form "@path" with the content of
+// the file at path, or the content of stdin if path is "-".
+func readValueFiles(a []string, stdin io.Reader) ([]string, error) {
+	values := make([]string, len(a))
+
+	for i, s := range a {
+		if !strings.HasPrefix(s, "@") {
+			values[i] = s
+			continue
+		}
+		b, err := readValueFile(s[1:], stdin)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(b)
+	}
+
+	return values, nil
+}
+
+func readValueFile(path string, stdin io.Reader) ([]byte, error) {
+	var b []byte
+	var err error
+
+	if path == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Text editors commonly add a new line at the end of files, which is
+	// rarely intended to be part of the value.
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	return b, nil
+}
+
+// parseCountTag returns the value of a tag expected to carry a non-negative
+// count, or zero if the tag is not set.
+func parseCountTag(f reflect.StructField, tag string) int {
+	s, ok := f.Tag.Lookup(tag)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		panic("configuration struct field has a malformed " + tag + " tag: " + f.Name + ": " + strconv.Quote(s))
+	}
+	return n
+}
+
 // envNameOf gets a environment variable name that is equivalent to the given
 // flag.
 func envNameOf(s string) string {
@@ -269,19 +653,27 @@ func makeValueDecoder(t reflect.Type) decodeFunc {
 		return decodeFloat64
 	case reflect.String:
 		return decodeString
+	case reflect.Slice:
+		if isBytesType(t) {
+			return decodeBase64
+		}
+	case reflect.Array:
+		if f := makeValueDecoder(t.Elem()); f != nil {
+			return makeArrayDecoder(t, f, "")
+		}
 	}
 	return nil
 }
 
+// makeSliceDecoder returns a decode function for slices of type t, where each
+// value is decoded into a new element using the decoder of the element type.
 func makeSliceDecoder(t reflect.Type) decodeFunc {
-	if isTextUnmarshaler(t) {
-		return decodeTextUnmarshaler
-	}
-	if isBinaryUnmarshaler(t) {
-		return decodeBinaryUnmarshaler
-	}
-	e := t.Elem()
-	f := makeValueDecoder(e)
+	return makeElemSliceDecoder(t.Elem(), makeValueDecoder(t.Elem()))
+}
+
+// makeElemSliceDecoder returns a decode function which appends an element of
+// type e to the slice for each value, decoding it with f.
+func makeElemSliceDecoder(e reflect.Type, f decodeFunc) decodeFunc {
 	z := reflect.Zero(e)
 	return func(v reflect.Value, a []string) error {
 		for i := 0; i < len(a); i++ {
@@ -294,12 +686,40 @@ func makeSliceDecoder(t reflect.Type) decodeFunc {
 	}
 }
 
+// makeArrayDecoder returns a decode function for arrays of type t, decoding
+// each element with f. Exactly as many values as the array length must be
+// given; a single value is split on sep (which defaults to ",") to support
+// passing all the elements at once, like in "--point 1,2".
+func makeArrayDecoder(t reflect.Type, f decodeFunc, sep string) decodeFunc {
+	n := t.Len()
+	if sep == "" {
+		sep = ","
+	}
+	return func(v reflect.Value, a []string) error {
+		if len(a) == 1 && n != 1 {
+			a = strings.Split(a[0], sep)
+			for i := range a {
+				a[i] = strings.TrimSpace(a[i])
+			}
+		}
+		if len(a) != n {
+			return &Usage{Err: errorf("expected %d values but got %d", n, len(a))}
+		}
+		for i := range a {
+			if err := f(v.Index(i), a[i:i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 func assertArgumentCount(a []string, n int) error {
 	switch {
 	case len(a) < n:
-		return &Usage{Err: fmt.Errorf("not enough arguments, expected %d but got %d", n, len(a))}
+		return &Usage{Err: errorf("not enough arguments, expected %d but got %d", n, len(a))}
 	case len(a) > n:
-		return &Usage{Err: fmt.Errorf("too many arguments, expected %d but got %d", n, len(a))}
+		return &Usage{Err: errorf("too many arguments, expected %d but got %d", n, len(a))}
 	}
 	return nil
 }
@@ -308,7 +728,7 @@ func decodeBool(v reflect.Value, a []string) error {
 	if err := assertArgumentCount(a, 1); err != nil {
 		return err
 	}
-	x, err := strconv.ParseBool(a[0])
+	x, err := parseBool(a[0])
 	if err != nil {
 		return err
 	}
@@ -316,6 +736,19 @@ func decodeBool(v reflect.Value, a []string) error {
 	return nil
 }
 
+// parseBool extends strconv.ParseBool to also accept the "yes", "no", "on",
+// "off", "enabled", and "disabled" forms (case insensitive), which are often
+// found in environment variables and configuration files.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "on", "enabled":
+		return true, nil
+	case "no", "off", "disabled":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
 func decodeInt(v reflect.Value, a []string) error {
 	return decodeIntSize(v, a, uintSize)
 }
@@ -384,6 +817,100 @@ func decodeUintSize(v reflect.Value, a []string, bits int) error {
 	return nil
 }
 
+// makeHumanDecoder returns a decode function for values of type t which accepts
+// human-friendly representations.
+func makeHumanDecoder(t reflect.Type) decodeFunc {
+	if t == durationType {
+		return decodeDuration
+	}
+	return makeHumanIntDecoder(t)
+}
+
+// makeStrictDecoder returns a decode function for values of type t which only
+// accepts the representations supported by the standard library.
+func makeStrictDecoder(t reflect.Type) decodeFunc {
+	if t == durationType {
+		return decodeStrictDuration
+	}
+	return makeValueDecoder(t)
+}
+
+// makeHexDecoder is like makeValueDecoder but decodes []byte values from their
+// hexadecimal representation.
+func makeHexDecoder(t reflect.Type) decodeFunc {
+	if isBytesType(t) {
+		return decodeHex
+	}
+	return makeValueDecoder(t)
+}
+
+// makeHumanIntDecoder returns a decode function for integers of type t which
+// accepts the human-friendly representations of counts and sizes, like "10K"
+// or "2Mi".
+func makeHumanIntDecoder(t reflect.Type) decodeFunc {
+	bits := t.Bits()
+	signed := t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64
+
+	return func(v reflect.Value, a []string) error {
+		if err := assertArgumentCount(a, 1); err != nil {
+			return err
+		}
+
+		// Plain integers are parsed exactly, since float64 values cannot
+		// represent all the integers of 64 bits.
+		var err error
+		if signed {
+			var x int64
+			if x, err = strconv.ParseInt(a[0], 0, bits); err == nil {
+				v.SetInt(x)
+				return nil
+			}
+		} else {
+			var x uint64
+			if x, err = strconv.ParseUint(a[0], 0, bits); err == nil {
+				v.SetUint(x)
+				return nil
+			}
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return errorf("integer value out of range: %q", a[0])
+		}
+
+		x, err := parseHumanNumber(a[0])
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case x != math.Trunc(x):
+			return errorf("not an integer value: %q", a[0])
+		case signed && (x < -math.Ldexp(1, bits-1) || x >= math.Ldexp(1, bits-1)):
+			return errorf("integer value out of range: %q", a[0])
+		case !signed && (x < 0 || x >= math.Ldexp(1, bits)):
+			return errorf("integer value out of range: %q", a[0])
+		}
+
+		if signed {
+			v.SetInt(int64(x))
+		} else {
+			v.SetUint(uint64(x))
+		}
+		return nil
+	}
+}
+
+// parseHumanNumber parses s as a human.Count, or as a human.Bytes if it has a
+// unit of bytes (e.g. "Ki" or "MB").
+func parseHumanNumber(s string) (float64, error) {
+	if c, err := human.ParseCount(s); err == nil {
+		return float64(c), nil
+	}
+	if b, err := human.ParseBytesFloat64(s); err == nil {
+		return b, nil
+	}
+	return 0, errorf("malformed integer value: %q", s)
+}
+
 func decodeFloat32(v reflect.Value, a []string) error {
 	return decodeFloat(v, a, 32)
 }
@@ -404,7 +931,25 @@ func decodeFloat(v reflect.Value, a []string, bits int) error {
 	return nil
 }
 
+// decodeDuration accepts the representations of time.ParseDuration, and falls
+// back to human.ParseDuration to support values like "2 days" or "1w".
 func decodeDuration(v reflect.Value, a []string) error {
+	if err := assertArgumentCount(a, 1); err != nil {
+		return err
+	}
+	x, err := time.ParseDuration(a[0])
+	if err != nil {
+		d, err := human.ParseDuration(a[0])
+		if err != nil {
+			return err
+		}
+		x = time.Duration(d)
+	}
+	v.SetInt(int64(x))
+	return nil
+}
+
+func decodeStrictDuration(v reflect.Value, a []string) error {
 	if err := assertArgumentCount(a, 1); err != nil {
 		return err
 	}
@@ -445,7 +990,7 @@ func decodeTime(v reflect.Value, a []string) error {
 		}
 	}
 
-	return fmt.Errorf("malformed time value: %q", a[0])
+	return errorf("malformed time value: %q", a[0])
 }
 
 func decodeString(v reflect.Value, a []string) error {
@@ -456,6 +1001,35 @@ func decodeString(v reflect.Value, a []string) error {
 	return nil
 }
 
+// decodeBase64 accepts both the standard and URL base64 encodings, with or
+// without padding.
+func decodeBase64(v reflect.Value, a []string) error {
+	if err := assertArgumentCount(a, 1); err != nil {
+		return err
+	}
+	s := strings.TrimRight(a[0], "=")
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		if b, err = base64.RawURLEncoding.DecodeString(s); err != nil {
+			return errorf("malformed base64 value: %q", a[0])
+		}
+	}
+	v.SetBytes(b)
+	return nil
+}
+
+func decodeHex(v reflect.Value, a []string) error {
+	if err := assertArgumentCount(a, 1); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(a[0])
+	if err != nil {
+		return errorf("malformed hexadecimal value: %q", a[0])
+	}
+	v.SetBytes(b)
+	return nil
+}
+
 func decodeTextUnmarshaler(v reflect.Value, a []string) error {
 	if err := assertArgumentCount(a, 1); err != nil {
 		return err
@@ -484,6 +1058,12 @@ type structField struct {
 	index   []int
 	// flags is the list of values for the field's `flag` tag.
 	flags   []string
+	// arg is the name of the positional argument declared by the field's
+	// `arg` tag, empty if the field is not a positional argument.
+	arg     string
+	// argpos is the position of the argument among the positional arguments
+	// of the struct.
+	argpos  int
 	// envvars is the list of environment variable names calculated from either
 	// the field's `flag` tag or its `env` tag.
 	envvars []string
@@ -493,10 +1073,56 @@ type structField struct {
 	defval  string
 	// hidden is the value of the field's `hidden` tag.
 	hidden  bool
+	// secret is the value of the field's `secret` tag.
+	secret  bool
+	// nodef is true if the default value must not be shown in the help, set
+	// by the `hidedefault` and `secret` tags.
+	nodef   bool
+	// file is the value of the field's `file` tag.
+	file    bool
+	// config is the value of the field's `config` tag.
+	config  bool
+	// human is the value of the field's `human` tag.
+	human   bool
+	// strict is true when the field's `human` tag is explicitly false.
+	strict  bool
+	// enc is the value of the field's `encoding` tag.
+	enc     string
+	// sep is the value of the field's `sep` tag, used to split the values
+	// of slice fields read from environment variables.
+	sep     string
+	// merge is the value of the field's `merge` tag.
+	merge   string
+	// min and max are the values of the field's `min` and `max` tags, which
+	// bound the number of values of slice fields.
+	min     int
+	max     int
+	// suggest is the value of the field's `complete` tag, the name of the
+	// completion function proposing values for the field.
+	suggest string
+	// choices is the list of values of the field's `choices` tag.
+	choices []string
+	// group is the value of the field's `group` tag.
+	group   string
+	// placeholder is the value of the field's `placeholder` tag, the name of
+	// the value shown in help messages instead of its type.
+	placeholder string
 }
 
-func (f structField) isBoolean() bool { return f.typ.Kind() == reflect.Bool }
-func (f structField) isSlice() bool   { return f.typ.Kind() == reflect.Slice }
+func (f structField) isBoolean() bool { return isBoolType(f.typ) || f.isCounter() }
+func (f structField) isSlice() bool   { return isSliceType(f.typ) }
+func (f structField) isArray() bool   { return isArrayType(f.typ) }
+
+// isCounter returns true if the field is a slice of booleans, which is set by
+// a boolean flag that may be repeated, like -v -v or -vv.
+func (f structField) isCounter() bool { return f.isSlice() && isBoolType(f.typ.Elem()) }
+
+func (f structField) arrayLen() int {
+	if f.isArray() {
+		return f.typ.Len()
+	}
+	return 0
+}
 
 var (
 	intType               = reflect.TypeOf(0)
@@ -534,8 +1160,57 @@ func isSupportedFieldType(t reflect.Type) bool {
 		reflect.Float64,
 		reflect.String:
 		return true
-	case reflect.Slice:
-		return t.Elem().Kind() != reflect.Slice && isSupportedFieldType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return !isListType(t.Elem()) && isSupportedFieldType(t.Elem())
+	}
+	return false
+}
+
+// isSliceType returns true if t is a slice type which accepts repeated values.
+// Slice types implementing encoding.TextUnmarshaler or
+// encoding.BinaryUnmarshaler (e.g. net.IP) are decoded from a single value, so
+// they are treated as scalars.
+func isSliceType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !isTextUnmarshaler(t) && !isBinaryUnmarshaler(t) && !isBytesType(t)
+}
+
+// isBytesType returns true if t is a []byte, which is decoded from a single
+// base64 or hexadecimal value instead of a list of integers.
+func isBytesType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 &&
+		!isTextUnmarshaler(t) && !isBinaryUnmarshaler(t)
+}
+
+// isArrayType is like isSliceType but for fixed-size arrays.
+func isArrayType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && !isTextUnmarshaler(t) && !isBinaryUnmarshaler(t)
+}
+
+func isListType(t reflect.Type) bool {
+	return isSliceType(t) || isArrayType(t)
+}
+
+func isHumanType(t reflect.Type) bool {
+	return t == durationType || isInteger(t)
+}
+
+func isInteger(t reflect.Type) bool {
+	if t == durationType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.Uintptr:
+		return !isTextUnmarshaler(t) && !isBinaryUnmarshaler(t)
 	}
 	return false
 }
@@ -548,16 +1223,22 @@ func isBinaryUnmarshaler(t reflect.Type) bool {
 	return reflect.PtrTo(t).Implements(binaryUnmarshalerType)
 }
 
+func isBoolType(t reflect.Type) bool { return t.Kind() == reflect.Bool }
+
 func typeNameOf(t reflect.Type) string {
-	switch t.Kind() {
-	case reflect.Bool:
+	switch {
+	case isBoolType(t), isSliceType(t) && isBoolType(t.Elem()):
 		return ""
-	case reflect.Slice:
+	case isSliceType(t):
 		return typeNameOf(t.Elem()) + "..."
+	case isArrayType(t):
+		return fmt.Sprintf("%s[%d]", typeNameOf(t.Elem()), t.Len())
+	case isBytesType(t):
+		return "base64"
 	}
 	s := t.String()
 	if i := strings.LastIndexByte(s, '.'); i >= 0 {
 		s = s[i+1:]
 	}
-	return strings.ReplaceAll(strings.ToLower(snakecase(s)), "_", "-")
+	return kebabcase(s)
 }
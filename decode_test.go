@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"context"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -100,3 +102,188 @@ func TestForEachStructField(t *testing.T) {
 		t.Error("Failed to locate Sibling field")
 	}
 }
+
+func TestMakeStructDecoderArgs(t *testing.T) {
+	type config struct {
+		Verbose bool     `flag:"-v,--verbose"`
+		Last    []string `arg:"2"`
+		First   string   `arg:"0"`
+		Second  int      `arg:"1" default:"-"`
+	}
+
+	p, s, _ := makeStructDecoder(reflect.TypeOf(config{}))
+
+	if !reflect.DeepEqual(p.args, []string{"first", "second", "last"}) {
+		t.Errorf("Incorrect positional arguments: %v", p.args)
+	}
+
+	for _, name := range p.args {
+		if f, ok := s[name]; !ok || !f.arg || len(f.flags) != 0 {
+			t.Errorf("Incorrect decoder for argument %q: %+v", name, f)
+		}
+	}
+
+	if _, ok := p.options["--verbose"]; !ok {
+		t.Error("Missing --verbose option")
+	}
+}
+
+func TestForEachStructFieldEnvList(t *testing.T) {
+	type config struct {
+		Token string `flag:"--token" env:"API_TOKEN, LEGACY_TOKEN,"`
+	}
+
+	forEachStructField(reflect.TypeOf(config{}), nil, func(sf structField) {
+		if !reflect.DeepEqual(sf.envvars, []string{"API_TOKEN", "LEGACY_TOKEN"}) {
+			t.Errorf("Incorrect envvars for Token field: %v", sf.envvars)
+		}
+	})
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"true", true},
+		{"1", true},
+		{"yes", true},
+		{"On", true},
+		{"ENABLED", true},
+		{"false", false},
+		{"0", false},
+		{"no", false},
+		{"off", false},
+		{"Disabled", false},
+	}
+
+	for _, test := range tests {
+		got, err := parseBool(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+		} else if got != test.want {
+			t.Errorf("%q: got %t, want %t", test.in, got, test.want)
+		}
+	}
+
+	if _, err := parseBool("maybe"); err == nil {
+		t.Error("expected an error parsing an invalid boolean value")
+	}
+}
+
+func TestMakeHumanIntDecoder(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		fail bool
+	}{
+		{in: "42", want: 42},
+		{in: "-1K", want: -1000},
+		{in: "1.5K", want: 1500},
+		{in: "2Mi", want: 2 << 20},
+		{in: "1GB", want: 1e9},
+		{in: "1.5", fail: true},
+		{in: "1P", fail: true}, // overflows int32
+		{in: "lots", fail: true},
+	}
+
+	decode := makeHumanIntDecoder(reflect.TypeOf(int32(0)))
+
+	for _, test := range tests {
+		var x int32
+		err := decode(reflect.ValueOf(&x).Elem(), []string{test.in})
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("%q: expected an error but got %d", test.in, x)
+		case !test.fail && err != nil:
+			t.Errorf("%q: %v", test.in, err)
+		case !test.fail && int64(x) != test.want:
+			t.Errorf("%q: got %d, want %d", test.in, x, test.want)
+		}
+	}
+}
+
+func TestMakeHumanIntDecoderBounds(t *testing.T) {
+	decodeInt := makeHumanIntDecoder(reflect.TypeOf(int64(0)))
+
+	for _, test := range []struct {
+		in   string
+		want int64
+		fail bool
+	}{
+		{in: "9223372036854775807", want: math.MaxInt64},
+		{in: "-9223372036854775808", want: math.MinInt64},
+		{in: "9007199254740993", want: 1<<53 + 1},
+		{in: "9223372036854775808", fail: true},
+		{in: "-9223372036854775809", fail: true},
+	} {
+		var x int64
+		err := decodeInt(reflect.ValueOf(&x).Elem(), []string{test.in})
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("%q: expected an error but got %d", test.in, x)
+		case !test.fail && err != nil:
+			t.Errorf("%q: %v", test.in, err)
+		case !test.fail && x != test.want:
+			t.Errorf("%q: got %d, want %d", test.in, x, test.want)
+		}
+	}
+
+	decodeUint := makeHumanIntDecoder(reflect.TypeOf(uint64(0)))
+
+	for _, test := range []struct {
+		in   string
+		want uint64
+		fail bool
+	}{
+		{in: "18446744073709551615", want: math.MaxUint64},
+		{in: "18446744073709551614", want: math.MaxUint64 - 1},
+		{in: "18446744073709551616", fail: true},
+		{in: "-1", fail: true},
+	} {
+		var x uint64
+		err := decodeUint(reflect.ValueOf(&x).Elem(), []string{test.in})
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("%q: expected an error but got %d", test.in, x)
+		case !test.fail && err != nil:
+			t.Errorf("%q: %v", test.in, err)
+		case !test.fail && x != test.want:
+			t.Errorf("%q: got %d, want %d", test.in, x, test.want)
+		}
+	}
+}
+
+func TestChoicesSeparator(t *testing.T) {
+	type config struct {
+		Pair   [2]string `flag:"--pair" sep:":" choices:"a,b" default:"a:b"`
+		Levels []string  `flag:"--level" env:"LEVELS" sep:":" choices:"debug,info" default:"info:debug"`
+	}
+
+	var got config
+	cmd := Command(func(c config) { got = c })
+
+	tests := []struct {
+		args []string
+		env  []string
+		fail bool
+	}{
+		{args: []string{"--level", "debug", "--level", "info"}},
+		{args: []string{"--level", "warn"}, fail: true},
+		{args: []string{"--pair", "b:a"}},
+		{args: []string{"--pair", "a:c"}, fail: true},
+		{},
+		{env: []string{"LEVELS=debug:info"}},
+		{env: []string{"LEVELS=debug:warn"}, fail: true},
+	}
+
+	for _, test := range tests {
+		_, err := cmd.Call(context.TODO(), test.args, test.env)
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("%q %q: expected an error but got %+v", test.args, test.env, got)
+		case !test.fail && err != nil:
+			t.Errorf("%q %q: %v", test.args, test.env, err)
+		}
+	}
+}
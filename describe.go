@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Description is the machine-readable definition of a command, returned by
+// Describe. It can be encoded to JSON for external tools, like documentation
+// sites, shell completion specs, or audits of the options of programs:
+//
+//	e := json.NewEncoder(os.Stdout)
+//	e.SetEscapeHTML(false) // usage lines contain "<" and ">"
+//	e.Encode(cli.Describe(cmd))
+type Description struct {
+	// The name of the command, which is its key in the command set it belongs
+	// to, or the name of the program for the root of the tree.
+	Name string `json:"name"`
+
+	// The usage line of the command, starting with the names of its parents.
+	Usage string `json:"usage"`
+
+	// The short help message of the command.
+	Help string `json:"help,omitempty"`
+
+	// The full description of the command.
+	Desc string `json:"desc,omitempty"`
+
+	// The category of the command in the help of its command set.
+	Category string `json:"category,omitempty"`
+
+	// The deprecation message of the command, empty if it is not deprecated.
+	Deprecated string `json:"deprecated,omitempty"`
+
+	// The examples of invocations of the command.
+	Examples string `json:"examples,omitempty"`
+
+	// The positional arguments of the command.
+	Arguments []ArgDescription `json:"arguments,omitempty"`
+
+	// The options of the command, excluding the hidden ones.
+	Options []OptionDescription `json:"options,omitempty"`
+
+	// The sub-commands of the command set, sorted by name.
+	Commands []Description `json:"commands,omitempty"`
+}
+
+// ArgDescription is the machine-readable definition of a positional argument
+// of a command.
+type ArgDescription struct {
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type"`
+	Help     string   `json:"help,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	Variadic bool     `json:"variadic,omitempty"`
+	Choices  []string `json:"choices,omitempty"`
+}
+
+// OptionDescription is the machine-readable definition of an option of a
+// command.
+type OptionDescription struct {
+	Flags    []string `json:"flags"`
+	Type     string   `json:"type,omitempty"`
+	Help     string   `json:"help,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	Required bool     `json:"required,omitempty"`
+	Env      []string `json:"env,omitempty"`
+	Choices  []string `json:"choices,omitempty"`
+	Group    string   `json:"group,omitempty"`
+	Repeated bool     `json:"repeated,omitempty"`
+}
+
+// Describe returns the definition of cmd and of all its sub-commands. The
+// environment variables of options are prefixed like they would be when
+// calling the program with Exec, so cmd should be named after the program
+// with NamedCommand.
+func Describe(cmd Function) Description {
+	return describe(cmd, nil, programEnvPrefix(cmd))
+}
+
+func describe(cmd Function, path []string, envPrefix string) Description {
+	configure(cmd)
+
+	name := nameOf(cmd)
+	node := cmd
+	// The usage line of sub-commands starts with the names of their parents,
+	// like in the help messages printed by Exec.
+	for i := len(path) - 1; i >= 0; i-- {
+		node = NamedCommand(path[i], node)
+	}
+
+	d := Description{
+		Name:       name,
+		Usage:      strings.TrimSpace(fmt.Sprintf("%s", node)),
+		Help:       fmt.Sprintf("%x", cmd),
+		Desc:       descOf(cmd),
+		Category:   categoryOf(cmd),
+		Deprecated: deprecatedOf(cmd),
+	}
+
+	if fn := commandFuncOf(cmd); fn != nil {
+		d.Examples = fn.Examples
+		d.Arguments = describeArguments(fn)
+		d.Options = describeOptions(fn.options, envPrefix)
+	}
+
+	if cmds := commandsOf(cmd); len(cmds) != 0 {
+		if name != "" {
+			path = append(path[:len(path):len(path)], name)
+		}
+		for _, name := range subcommandNames(cmds) {
+			d.Commands = append(d.Commands, describe(NamedCommand(name, cmds[name]), path, envPrefix))
+		}
+	}
+
+	return d
+}
+
+func describeArguments(cmd *CommandFunc) []ArgDescription {
+	var args []ArgDescription
+
+	for _, name := range cmd.parser.args {
+		field := cmd.options[name]
+		arg := ArgDescription{
+			Name:     name,
+			Type:     field.argtyp,
+			Help:     field.help,
+			Variadic: field.slice,
+			Choices:  field.choices,
+		}
+		if field.defval != "-" && !field.nodef {
+			arg.Default = field.defval
+		}
+		args = append(args, arg)
+	}
+
+	if !cmd.forward {
+		for i, p := range cmd.positionalParams() {
+			arg := ArgDescription{Type: typeNameOf(p), Variadic: isSliceType(p)}
+			if i < len(cmd.Args) {
+				arg.Name, arg.Help = cmd.Args[i].Name, cmd.Args[i].Help
+			}
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// describeOptions returns the definitions of options, in the same order as in
+// help messages.
+func describeOptions(options structDecoder, envPrefix string) []OptionDescription {
+	var list []OptionDescription
+
+	for _, fieldName := range sortedMapKeys(reflect.ValueOf(options)) {
+		field := options[fieldName.String()]
+		if field.hidden || field.arg {
+			continue
+		}
+
+		row := optionRowOf(field)
+		opt := OptionDescription{
+			Flags:    field.flags,
+			Type:     row.typ,
+			Help:     field.help,
+			Default:  row.def,
+			Required: row.req,
+			Choices:  field.choices,
+			Group:    field.group,
+			Repeated: field.slice,
+		}
+		for _, env := range field.envvars {
+			opt.Env = append(opt.Env, envPrefix+env)
+		}
+		list = append(list, opt)
+	}
+
+	return list
+}
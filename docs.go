@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GenerateDocs writes the documentation of cmd and of all its sub-commands to
+// the directory dir, as one Markdown page per command. The pages show the
+// usage, description, options, environment variables, and examples of the
+// commands, and link to each other, so the directory can be published as is,
+// for example with a static site generator.
+//
+// The pages are named after the path of their commands joined by underscores,
+// like "prog.md" and "prog_get.md", where the name of the program is the name
+// of cmd if it was created by NamedCommand, or the name of the running
+// executable otherwise. Environment variables are prefixed like they would be
+// when calling the program with Exec.
+//
+// The directory is created if it does not exist.
+func GenerateDocs(cmd Function, dir string) error {
+	name := nameOf(cmd)
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+		cmd = NamedCommand(name, cmd)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	g := docsGenerator{dir: dir, envPrefix: programEnvPrefix(cmd)}
+	return g.generate(cmd, []string{name}, nil)
+}
+
+// programEnvPrefix returns the prefix of the environment variables of the
+// program cmd, which is set on cmd or defaults to its uppercased name.
+func programEnvPrefix(cmd Function) string {
+	prefix, ok := envPrefixOf(cmd)
+	if !ok {
+		prefix = strings.ToUpper(snakecase(nameOf(cmd)))
+	}
+	if prefix = strings.TrimSuffix(prefix, "_"); prefix != "" {
+		prefix = prefix + "_"
+	}
+	return prefix
+}
+
+type docsGenerator struct {
+	dir       string
+	envPrefix string
+}
+
+// generate writes the page of cmd, which is at path in the command tree, then
+// the pages of its sub-commands. The parent is nil for the program itself.
+func (g *docsGenerator) generate(cmd Function, path []string, parent []string) error {
+	configure(cmd)
+
+	f, err := os.Create(filepath.Join(g.dir, docsFileName(path)))
+	if err != nil {
+		return err
+	}
+	writeDocs(f, cmd, path, parent, g.envPrefix)
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	cmds := commandsOf(cmd)
+	for _, name := range subcommandNames(cmds) {
+		sub := NamedCommand(name, cmds[name])
+		// The usage line of sub-commands starts with the names of their
+		// parents, like in the help messages printed by Exec.
+		for i := len(path) - 1; i >= 0; i-- {
+			sub = NamedCommand(path[i], sub)
+		}
+		if err := g.generate(sub, append(path[:len(path):len(path)], name), path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDocs writes the Markdown page of cmd to w.
+func writeDocs(w io.Writer, cmd Function, path, parent []string, envPrefix string) {
+	fmt.Fprintf(w, "# %s\n", strings.Join(path, " "))
+
+	if help := fmt.Sprintf("%x", cmd); help != "" {
+		fmt.Fprintf(w, "\n%s\n", help)
+	}
+
+	fmt.Fprintf(w, "\n## %s\n\n```\n%s\n```\n", tr("Usage"), strings.TrimSpace(fmt.Sprintf("%s", cmd)))
+
+	if desc := descOf(cmd); desc != "" {
+		fmt.Fprintf(w, "\n%s\n", desc)
+	}
+
+	if fn := commandFuncOf(cmd); fn != nil {
+		writeDocsOptions(w, fn.options, envPrefix)
+
+		if fn.Examples != "" {
+			fmt.Fprintf(w, "\n## %s\n\n```\n%s\n```\n", tr("Examples"), strings.TrimRight(fn.Examples, "\n"))
+		}
+	}
+
+	if cmds := commandsOf(cmd); len(cmds) != 0 {
+		fmt.Fprintf(w, "\n## %s\n\n| %s | %s |\n| --- | --- |\n", tr("Commands"), tr("Command"), tr("Description"))
+		for _, name := range subcommandNames(cmds) {
+			fmt.Fprintf(w, "| [%s](%s) | %s |\n",
+				name,
+				docsFileName(append(path[:len(path):len(path)], name)),
+				markdownCell(fmt.Sprintf("%x", cmds[name])),
+			)
+		}
+	}
+
+	if parent != nil {
+		fmt.Fprintf(w, "\n## %s\n\n* [%s](%s)\n", tr("See Also"), strings.Join(parent, " "), docsFileName(parent))
+	}
+}
+
+// writeDocsOptions writes the table of options of a command, in the same order
+// as in its help message.
+func writeDocsOptions(w io.Writer, options structDecoder, envPrefix string) {
+	header := false
+
+	for _, fieldName := range sortedMapKeys(reflect.ValueOf(options)) {
+		field := options[fieldName.String()]
+		if field.hidden || field.arg || (HideHelpFlag && fieldName.String() == "--help") {
+			continue
+		}
+
+		if !header {
+			fmt.Fprintf(w, "\n## %s\n\n| %s | %s | %s | %s |\n| --- | --- | --- | --- |\n",
+				tr("Options"), tr("Option"), tr("Type"), tr("Environment"), tr("Description"))
+			header = true
+		}
+
+		row := optionRowOf(field)
+		desc := row.cells(false)[2]
+
+		envs := make([]string, len(field.envvars))
+		for i, env := range field.envvars {
+			envs[i] = envPrefix + env
+		}
+
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+			markdownCode(append(row.short, row.long...)),
+			markdownCell(row.typ),
+			markdownCode(envs),
+			markdownCell(desc),
+		)
+	}
+}
+
+// commandsOf returns the sub-commands of cmd, or nil if it has none.
+func commandsOf(cmd Function) CommandSet {
+	if x, ok := cmd.(interface{ commands() CommandSet }); ok {
+		return x.commands()
+	}
+	return nil
+}
+
+func (cmds CommandSet) commands() CommandSet { return cmds }
+
+func (g Group) commands() CommandSet { return g.Commands }
+
+func (c *namedCommand) commands() CommandSet { return commandsOf(c.cmd) }
+
+func (w wrappedCommand) commands() CommandSet { return commandsOf(w.cmd) }
+
+func (c *lazyCommand) commands() CommandSet { return c.get().commands() }
+
+// subcommandNames returns the sorted names of the commands of cmds, except the
+// special "_" entry and the hidden commands.
+func subcommandNames(cmds CommandSet) []string {
+	names := make([]string, 0, len(cmds))
+	for name, cmd := range cmds {
+		if name != "_" && !hiddenOf(cmd) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func docsFileName(path []string) string {
+	return strings.Join(path, "_") + ".md"
+}
+
+// markdownCode formats values as inline code, separated by commas.
+func markdownCode(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return "`" + strings.Join(values, "`, `") + "`"
+}
+
+// markdownCell escapes s to be written in a cell of a Markdown table.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\n", " ")
+	return s
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	type getConfig struct {
+		Format string `flag:"-f,--format" help:"Output format" choices:"text,json" default:"text"`
+		Name   string `arg:"name" help:"Name of the resource"`
+	}
+
+	cmd := NamedCommand("prog", Group{
+		Help: "Manage resources",
+		Desc: "Resources are stored remotely.",
+		Commands: CommandSet{
+			"get": &CommandFunc{
+				Help:     "Get a resource",
+				Examples: "$ prog get foo",
+				Func:     func(getConfig) {},
+			},
+		},
+	})
+
+	dir := t.TempDir()
+	if err := GenerateDocs(cmd, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		docs string
+	}{
+		{
+			file: "prog.md",
+			docs: "# prog\n" +
+				"\n" +
+				"Manage resources\n" +
+				"\n" +
+				"## Usage\n" +
+				"\n" +
+				"```\n" +
+				"prog [command] [-h] [--help] ...\n" +
+				"```\n" +
+				"\n" +
+				"Resources are stored remotely.\n" +
+				"\n" +
+				"## Commands\n" +
+				"\n" +
+				"| Command | Description |\n" +
+				"| --- | --- |\n" +
+				"| [get](prog_get.md) | Get a resource |\n",
+		},
+		{
+			file: "prog_get.md",
+			docs: "# prog get\n" +
+				"\n" +
+				"Get a resource\n" +
+				"\n" +
+				"## Usage\n" +
+				"\n" +
+				"```\n" +
+				"prog get [options] <name>\n" +
+				"```\n" +
+				"\n" +
+				"## Options\n" +
+				"\n" +
+				"| Option | Type | Environment | Description |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| `-f`, `--format` | (text\\|json) | `PROG_FORMAT` | Output format (default: text) |\n" +
+				"| `-h`, `--help` |  |  | Show this help message |\n" +
+				"\n" +
+				"## Examples\n" +
+				"\n" +
+				"```\n" +
+				"$ prog get foo\n" +
+				"```\n" +
+				"\n" +
+				"## See Also\n" +
+				"\n" +
+				"* [prog](prog.md)\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(dir, test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := string(b); s != test.docs {
+				t.Errorf("wrong documentation:\n%s\nwant:\n%s", s, test.docs)
+			}
+		})
+	}
+}
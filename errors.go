@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownFlag is the error reported when the command line contains a flag
+// which is not recognized by the command.
+//
+// Like the other parse errors, it is wrapped in the *Usage error returned by
+// the command, and can be retrieved with errors.As.
+type ErrUnknownFlag struct {
+	Flag string
+
+	// The flags of the command which are the closest to Flag, which are
+	// suggested in the error message. It is empty when no flags are similar
+	// enough.
+	Suggestions []string
+
+	// The paths of the other commands of the program which accept Flag, like
+	// "prog deploy", which are mentioned in the error message.
+	Commands []string
+}
+
+// Error satisfies the error interface.
+func (e *ErrUnknownFlag) Error() string {
+	msg := fmt.Sprintf(tr("unrecognized option: %q"), e.Flag)
+	if len(e.Suggestions) != 0 {
+		quoted := make([]string, len(e.Suggestions))
+		for i, s := range e.Suggestions {
+			quoted[i] = strconv.Quote(s)
+		}
+		msg += " " + fmt.Sprintf(tr("(did you mean %s?)"), strings.Join(quoted, tr(" or ")))
+	}
+	if len(e.Commands) != 0 {
+		quoted := make([]string, len(e.Commands))
+		for i, s := range e.Commands {
+			quoted[i] = strconv.Quote(s)
+		}
+		msg += " " + fmt.Sprintf(tr("(%s is a flag of %s)"), e.Flag, strings.Join(quoted, ", "))
+	}
+	return msg
+}
+
+// ErrMissingValue is the error reported when a flag which expects a value is
+// the last argument of the command line, or is followed by another flag.
+type ErrMissingValue struct {
+	Flag string
+}
+
+// Error satisfies the error interface.
+func (e *ErrMissingValue) Error() string {
+	return fmt.Sprintf(tr("missing option value: %q"), e.Flag)
+}
+
+// ErrMissingRequired is the error reported when a required flag or positional
+// argument was not given a value. When Positional is true, Flag is the name of
+// the missing argument.
+type ErrMissingRequired struct {
+	Flag       string
+	Positional bool
+}
+
+// Error satisfies the error interface.
+func (e *ErrMissingRequired) Error() string {
+	if e.Positional {
+		return fmt.Sprintf(tr("missing required argument: %q"), e.Flag)
+	}
+	return fmt.Sprintf(tr("missing required flag: %q"), e.Flag)
+}
+
+// ErrTooManyArgs is the error reported when the command line contains more
+// positional arguments than the command accepts. Args holds the extra ones.
+type ErrTooManyArgs struct {
+	Args []string
+}
+
+// Error satisfies the error interface.
+func (e *ErrTooManyArgs) Error() string {
+	return fmt.Sprintf(tr("too many positional arguments: %q"), e.Args)
+}
+
+// ExitCoder is implemented by errors which carry the exit code that the
+// program should terminate with. When a command returns an error satisfying
+// this interface (possibly wrapped), Exec and Call use its exit code instead
+// of the default value of 1.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// Exit returns an error which wraps err and carries the exit code, for example:
+//
+//	if !found {
+//		return cli.Exit(2, fmt.Errorf("%s: not found", name))
+//	}
+//
+// When err is nil, the program exits with the code without printing an error.
+func Exit(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// Exitf is like Exit, but formats the error message according to a format
+// specifier, like fmt.Errorf, so the %w verb may be used to wrap errors:
+//
+//	if !found {
+//		return cli.Exitf(2, "%s: not found", name)
+//	}
+//
+// When format is empty, the program exits with the code without printing an
+// error.
+func Exitf(code int, format string, args ...interface{}) error {
+	if format == "" {
+		return Exit(code, nil)
+	}
+	return Exit(code, fmt.Errorf(format, args...))
+}
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error { return e.err }
+
+func (e *exitError) ExitCode() int { return e.code }
+
+// ErrorPrinter is the type of functions printing the errors returned by the
+// commands of a program, other than *Help and *Usage, see WithErrorPrinter.
+// The program is the name of the program, which is empty when the command was
+// not named.
+type ErrorPrinter func(w io.Writer, program string, err error)
+
+// WithErrorPrinter sets the function printing the errors returned by the
+// commands of the program, in place of PrintError. The function writes to Err,
+// and is not called for errors with an empty message, like the ones created
+// by Exit with a nil error.
+func WithErrorPrinter(p ErrorPrinter) ExecOption {
+	return func(o *execOptions) { o.errorPrinter = p }
+}
+
+// PrintError is the default ErrorPrinter, which prints errors on a single line
+// prefixed with the name of the program:
+//
+//	prog: error: open config.yaml: no such file or directory
+func PrintError(w io.Writer, program string, err error) {
+	if program != "" {
+		fmt.Fprintf(w, "%s: ", program)
+	}
+	fmt.Fprintf(w, "%s: %s\n", tr("error"), err)
+}
+
+// errorList is an error carrying a list of errors, which is used to report all
+// the problems found on a command line at once.
+type errorList []error
+
+// Error satisfies the error interface, each error is on its own line.
+func (errs errorList) Error() string {
+	s := make([]string, len(errs))
+	for i, err := range errs {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Is supports errors.Is, returning true if any of the errors in the list
+// matches target.
+func (errs errorList) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As supports errors.As, assigning the first error of the list which matches
+// target.
+func (errs errorList) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the list of errors.
+func (errs errorList) Unwrap() []error { return errs }
+
+// appendErrors appends err to errs, flattening it if it is a list of errors.
+func appendErrors(errs []error, err error) []error {
+	if list, ok := err.(errorList); ok {
+		return append(errs, list...)
+	}
+	return append(errs, err)
+}
+
+// joinErrors returns nil if errs is empty, the only error if it contains one,
+// or an errorList combining them.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errorList(errs)
+	}
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestErrorList(t *testing.T) {
+	errA := errors.New("A")
+	errB := &os.PathError{Op: "open", Path: "B", Err: os.ErrNotExist}
+	err := joinErrors(appendErrors([]error{errA}, errorList{errB}))
+
+	if !errors.Is(err, errA) {
+		t.Error("errors.Is did not find the first error of the list")
+	}
+
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) || pathErr != errB {
+		t.Error("errors.As did not find the second error of the list")
+	}
+
+	if msg := err.Error(); msg != "A\nopen B: file does not exist" {
+		t.Errorf("wrong error message: %q", msg)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	Err = io.Discard
+
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("failed"), 1},
+		{Exit(3, errors.New("failed")), 3},
+		{fmt.Errorf("wrapped: %w", Exit(4, errors.New("failed"))), 4},
+		{Exit(5, nil), 5},
+		{Exitf(6, "%s: not found", "file"), 6},
+		{Exitf(7, ""), 7},
+	}
+
+	for _, test := range tests {
+		cmd := Command(func() error { return test.err })
+		if code := call(context.TODO(), cmd, nil, nil); code != test.code {
+			t.Errorf("%v: wrong exit code: got %d, want %d", test.err, code, test.code)
+		}
+	}
+}
+
+func TestWrappedCallErrors(t *testing.T) {
+	defer func(out, err io.Writer) { Out, Err = out, err }(Out, Err)
+	stdout, stderr := new(strings.Builder), new(strings.Builder)
+	Out, Err = stdout, stderr
+
+	tests := []struct {
+		err    error
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			err:    fmt.Errorf("loading: %w", &Usage{Err: errors.New("invalid region")}),
+			code:   1,
+			stderr: "\nUsage:\n  prog [options]\n\nOptions:\n  -h, --help  Show this help message\n\nError:\n  invalid region\n\n\n",
+		},
+		{
+			err:    fmt.Errorf("loading: %w", &Help{}),
+			code:   0,
+			stdout: "\nUsage:\n  prog [options]\n\nOptions:\n  -h, --help  Show this help message\n\n",
+		},
+	}
+
+	for i, test := range tests {
+		stdout.Reset()
+		stderr.Reset()
+
+		cmd := NamedCommand("prog", Command(func(struct{}) error { return test.err }))
+		if code := Call(cmd); code != test.code {
+			t.Errorf("test %d: wrong exit code: got %d, want %d", i, code, test.code)
+		}
+		if s := stdout.String(); s != test.stdout {
+			t.Errorf("test %d: wrong output:\n%q\nwant:\n%q", i, s, test.stdout)
+		}
+		if s := stderr.String(); s != test.stderr {
+			t.Errorf("test %d: wrong error output:\n%q\nwant:\n%q", i, s, test.stderr)
+		}
+		if c := errorCategoryOf(test.err); c == "error" {
+			t.Errorf("test %d: wrong error category: %q", i, c)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"reflect"
+	"sort"
+)
+
+// FlagSource represents where the value of an option came from.
+type FlagSource int
+
+const (
+	// FromDefault is the source of options which were not set, and received
+	// their default value (or the zero-value).
+	FromDefault FlagSource = iota
+	// FromCommandLine is the source of options passed on the command line.
+	FromCommandLine
+	// FromEnv is the source of options loaded from environment variables.
+	FromEnv
+	// FromConfig is the source of options loaded from a configuration file.
+	FromConfig
+)
+
+// String satisfies the fmt.Stringer interface.
+func (s FlagSource) String() string {
+	switch s {
+	case FromCommandLine:
+		return "command line"
+	case FromEnv:
+		return "environment"
+	case FromConfig:
+		return "config"
+	default:
+		return "default"
+	}
+}
+
+// FlagInfo records where the values of the options of a command came from.
+//
+// Commands receive a FlagInfo when they declare a parameter of this type after
+// their configuration struct, for example:
+//
+//	cmd := cli.Command(func(config config, info cli.FlagInfo) {
+//		if info.Provided("--name") {
+//			...
+//		}
+//	})
+//
+// This makes it possible to implement semantics like only updating the fields
+// that the user set explicitly.
+type FlagInfo struct {
+	sources map[string]FlagSource
+	aliases map[string]string
+}
+
+// Source returns the source of the value of flag, which may be any of the
+// names of the option, or the name of a positional argument.
+func (info FlagInfo) Source(flag string) FlagSource {
+	if alias, ok := info.aliases[flag]; ok {
+		flag = alias
+	}
+	return info.sources[flag]
+}
+
+// Provided returns true if flag was passed on the command line.
+func (info FlagInfo) Provided(flag string) bool {
+	return info.Source(flag) == FromCommandLine
+}
+
+// Flags returns the sorted list of options which were passed on the command
+// line.
+func (info FlagInfo) Flags() []string {
+	var flags []string
+	for flag, source := range info.sources {
+		if source == FromCommandLine {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// setSources assigns source to all options which don't have a source yet.
+func (info FlagInfo) setSources(options map[string][]string, source FlagSource) {
+	for name := range options {
+		if _, ok := info.sources[name]; !ok {
+			info.sources[name] = source
+		}
+	}
+}
+
+var flagInfoType = reflect.TypeOf(FlagInfo{})
+
+// isInjectedType returns true if parameters of type t are not decoded from
+// positional arguments but injected by the command.
+func isInjectedType(t reflect.Type) bool {
+	return t == flagInfoType || t == streamsType || providerOf(t) != nil
+}
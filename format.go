@@ -3,8 +3,10 @@ package cli
 import (
 	"bytes"
 	"encoding"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"reflect"
 	"strings"
@@ -34,7 +36,7 @@ type PrintFlusher interface {
 //
 // Typical usage looks like this:
 //
-//	p, err := cli.Format(config.Format, os.Stdout)
+//	p, err := cli.Format(config.Format, nil)
 //	if err != nil {
 //		return err
 //	}
@@ -44,24 +46,54 @@ type PrintFlusher interface {
 //	p.Print(v2)
 //	p.Print(v3)
 //
-// The package supports three formats: text, json, and yaml. All formats
-// einterpret the `json` struct tag to configure the names of the fields
-// and the behavior of the formatting operation.
+// The package supports six formats: text, json, jsonl, yaml, csv, and html.
+// All formats einterpret the `json` struct tag to configure the names of the
+// fields and the behavior of the formatting operation.
 //
 // The text format also interprets `fmt` tags as carrying the formatting
 // string passed in calls to functions of the `fmt` package.
 //
+// The csv format writes a header row with the names of the fields of structs,
+// or the keys of maps, followed by one record per value, quoted as described
+// in RFC 4180. The html format writes the same rows as an escaped <table>,
+// for example to publish reports as the artifacts of CI jobs.
+//
+// The jsonl format, also named ndjson, writes each value as a compact JSON
+// document on its own line, as expected by log pipelines. Unlike the json
+// format of FormatList, it does not buffer values until the printer is
+// flushed, so it streams well.
+//
+// The json format may be followed by a path selecting fields in the output,
+// in a subset of the jq syntax, so scripts can extract values without piping
+// the output to jq: "json=.items[0].id" prints the "id" field of the first
+// element of "items", and "json=.items[].id" the ones of all its elements.
+// The selected strings are printed without quotes, other values as JSON.
+//
+// When output is nil, the values are printed to Stdout().
+//
 // If the format name is not supported, the function returns a usage error.
 func Format(format string, output io.Writer) (PrintFlusher, error) {
+	if output == nil {
+		output = Stdout()
+	}
+	if name, path, ok := strings.Cut(format, "="); ok && name == "json" {
+		return newJsonPathFormat(output, path, false)
+	}
 	switch format {
 	case "json":
 		return newJsonFormat(output), nil
 	case "yaml":
 		return newYamlFormat(output), nil
+	case "jsonl", "ndjson":
+		return newJsonLinesFormat(output), nil
 	case "text":
 		return newTextFormat(output), nil
+	case "csv":
+		return newCsvFormat(output), nil
+	case "html":
+		return newHtmlFormat(output), nil
 	default:
-		return nil, &Usage{Err: fmt.Errorf("unsupported output format: %q", format)}
+		return nil, &Usage{Err: errorf("unsupported output format: %q", format)}
 	}
 }
 
@@ -79,6 +111,18 @@ func (p jsonFormat) Print(v interface{}) {
 
 func (p jsonFormat) Flush() {}
 
+type jsonLinesFormat struct{ *json.Encoder }
+
+func newJsonLinesFormat(w io.Writer) jsonLinesFormat {
+	return jsonLinesFormat{json.NewEncoder(w)}
+}
+
+func (p jsonLinesFormat) Print(v interface{}) {
+	p.Encode(normalizeValue(v))
+}
+
+func (p jsonLinesFormat) Flush() {}
+
 type yamlFormat struct{ *yaml.Encoder }
 
 func newYamlFormat(w io.Writer) yamlFormat {
@@ -147,7 +191,7 @@ func (p *textFormat) printStruct(v reflect.Value) {
 		if i != 0 {
 			io.WriteString(&p.tw, "\t")
 		}
-		io.WriteString(&p.tw, p.format(format, value))
+		io.WriteString(&p.tw, formatValue(format, value))
 		i++
 	})
 
@@ -170,7 +214,7 @@ func (p *textFormat) printMap(v reflect.Value) {
 			if i != 0 {
 				io.WriteString(&p.tw, "\t")
 			}
-			io.WriteString(&p.tw, normalizeColumnName(p.format("%v", k.Interface())))
+			io.WriteString(&p.tw, normalizeColumnName(formatValue("%v", k.Interface())))
 		}
 
 		io.WriteString(&p.tw, "\n")
@@ -180,7 +224,7 @@ func (p *textFormat) printMap(v reflect.Value) {
 		if i != 0 {
 			io.WriteString(&p.tw, "\t")
 		}
-		io.WriteString(&p.tw, p.format("%v", v.MapIndex(k).Interface()))
+		io.WriteString(&p.tw, formatValue("%v", v.MapIndex(k).Interface()))
 	}
 
 	io.WriteString(&p.tw, "\n")
@@ -201,10 +245,10 @@ func (p *textFormat) Flush() {
 
 func (p *textFormat) print(v interface{}) {
 	p.Flush() // in case there is buffered content
-	io.WriteString(p.w, p.format("%v\n", v))
+	io.WriteString(p.w, formatValue("%v\n", v))
 }
 
-func (p *textFormat) format(f string, v interface{}) string {
+func formatValue(f string, v interface{}) string {
 	switch m := v.(type) {
 	case fmt.Formatter, fmt.Stringer, error:
 		// Takes priority over encoding.TextMarshaler, handled by the call to
@@ -220,16 +264,16 @@ func (p *textFormat) format(f string, v interface{}) string {
 }
 
 func (p *textFormat) forEachStructFieldName(v reflect.Value, do func(string)) {
-	p.forEachStructField(v, func(name, _ string, _ reflect.Value) { do(name) })
+	forEachPrintedField(v, func(name, _ string, _ reflect.Value) { do(normalizeColumnName(name)) })
 }
 
 func (p *textFormat) forEachStructFieldValue(v reflect.Value, do func(string, interface{})) {
-	p.forEachStructField(v, func(_, format string, value reflect.Value) {
+	forEachPrintedField(v, func(_, format string, value reflect.Value) {
 		do(format, value.Interface())
 	})
 }
 
-func (p *textFormat) forEachStructField(v reflect.Value, do func(string, string, reflect.Value)) {
+func forEachPrintedField(v reflect.Value, do func(string, string, reflect.Value)) {
 	t := v.Type()
 	n := t.NumField()
 
@@ -241,7 +285,7 @@ func (p *textFormat) forEachStructField(v reflect.Value, do func(string, string,
 		}
 
 		if f.Anonymous {
-			p.forEachStructField(v.Field(i), do)
+			forEachPrintedField(v.Field(i), do)
 			continue
 		}
 
@@ -259,8 +303,124 @@ func (p *textFormat) forEachStructField(v reflect.Value, do func(string, string,
 			format = "%v"
 		}
 
-		do(normalizeColumnName(name), format, v.Field(i))
+		do(name, format, v.Field(i))
+	}
+}
+
+type csvFormat struct {
+	w      *csv.Writer
+	header []string // column names of the last record
+}
+
+func newCsvFormat(w io.Writer) *csvFormat {
+	return &csvFormat{w: csv.NewWriter(w)}
+}
+
+func (p *csvFormat) Print(x interface{}) {
+	forEachRecord(x, func(names, values []string) {
+		// Maps of the same type may have different keys, so the header is
+		// written again whenever the column names change.
+		if !equalColumns(names, p.header) {
+			p.header = names
+			if names != nil {
+				p.w.Write(names)
+			}
+		}
+		p.w.Write(values)
+	})
+}
+
+func (p *csvFormat) Flush() { p.w.Flush() }
+
+type htmlFormat struct {
+	w      io.Writer
+	header []string // column names of the open table
+	open   bool
+}
+
+func newHtmlFormat(w io.Writer) *htmlFormat {
+	return &htmlFormat{w: w}
+}
+
+func (p *htmlFormat) Print(x interface{}) {
+	forEachRecord(x, func(names, values []string) {
+		if !p.open || !equalColumns(names, p.header) {
+			p.Flush()
+			p.header, p.open = names, true
+			io.WriteString(p.w, "<table>\n")
+			if names != nil {
+				io.WriteString(p.w, "  <thead>\n")
+				p.row("th", names)
+				io.WriteString(p.w, "  </thead>\n")
+			}
+			io.WriteString(p.w, "  <tbody>\n")
+		}
+		p.row("td", values)
+	})
+}
+
+func (p *htmlFormat) row(tag string, cells []string) {
+	io.WriteString(p.w, "    <tr>")
+	for _, c := range cells {
+		fmt.Fprintf(p.w, "<%s>%s</%s>", tag, html.EscapeString(c), tag)
+	}
+	io.WriteString(p.w, "</tr>\n")
+}
+
+func (p *htmlFormat) Flush() {
+	if p.open {
+		p.header, p.open = nil, false
+		io.WriteString(p.w, "  </tbody>\n</table>\n")
+	}
+}
+
+// forEachRecord calls do with the column names and values of the records
+// formatted from x: one per struct or map, one per element of slices, and a
+// single value without column names for other values.
+func forEachRecord(x interface{}, do func(names, values []string)) {
+	switch x.(type) {
+	case encoding.TextMarshaler, encoding.BinaryMarshaler, fmt.Formatter, fmt.Stringer, error:
+		do(nil, []string{formatValue("%v", x)})
+		return
+	}
+	switch v := reflect.ValueOf(x); v.Kind() {
+	case reflect.Struct:
+		var names, values []string
+		forEachPrintedField(v, func(name, _ string, value reflect.Value) {
+			names = append(names, name)
+			values = append(values, formatValue("%v", value.Interface()))
+		})
+		do(names, values)
+	case reflect.Slice:
+		for i, n := 0, v.Len(); i < n; i++ {
+			forEachRecord(v.Index(i).Interface(), do)
+		}
+	case reflect.Map:
+		keys := sortedMapKeys(v)
+		names := make([]string, len(keys))
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = formatValue("%v", k.Interface())
+			values[i] = formatValue("%v", v.MapIndex(k).Interface())
+		}
+		do(names, values)
+	default:
+		do(nil, []string{formatValue("%v", x)})
+	}
+}
+
+// equalColumns returns true if the records with the column names a and b can
+// be printed under the same header.
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) || (a == nil) != (b == nil) {
+		return false
 	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func normalizeColumnName(name string) string {
@@ -271,7 +431,7 @@ func normalizeColumnName(name string) string {
 //
 // Typical usage looks like this:
 //
-//	p, err := cli.FormatList(config.Format, os.Stdout)
+//	p, err := cli.FormatList(config.Format, nil)
 //	if err != nil {
 //		return err
 //	}
@@ -281,24 +441,54 @@ func normalizeColumnName(name string) string {
 //	p.Print(v2)
 //	p.Print(v3)
 //
-// The package supports three formats: text, json, and yaml. All formats
-// einterpret the `json` struct tag to configure the names of the fields
-// and the behavior of the formatting operation.
+// The package supports six formats: text, json, jsonl, yaml, csv, and html.
+// All formats einterpret the `json` struct tag to configure the names of the
+// fields and the behavior of the formatting operation.
 //
 // The text format also interprets `fmt` tags as carrying the formatting
 // string passed in calls to functions of the `fmt` package.
 //
+// The csv format writes a header row with the names of the fields of structs,
+// or the keys of maps, followed by one record per value, quoted as described
+// in RFC 4180. The html format writes the same rows as an escaped <table>,
+// for example to publish reports as the artifacts of CI jobs.
+//
+// The jsonl format, also named ndjson, writes each value as a compact JSON
+// document on its own line, as expected by log pipelines. Unlike the json
+// format of FormatList, it does not buffer values until the printer is
+// flushed, so it streams well.
+//
+// The json format may be followed by a path selecting fields in the output,
+// in a subset of the jq syntax, so scripts can extract values without piping
+// the output to jq: "json=.items[0].id" prints the "id" field of the first
+// element of "items", and "json=.items[].id" the ones of all its elements.
+// The selected strings are printed without quotes, other values as JSON.
+//
+// When output is nil, the values are printed to Stdout().
+//
 // If the format name is not supported, the function returns a usage error.
 func FormatList(format string, output io.Writer) (PrintFlusher, error) {
+	if output == nil {
+		output = Stdout()
+	}
+	if name, path, ok := strings.Cut(format, "="); ok && name == "json" {
+		return newJsonPathFormat(output, path, true)
+	}
 	switch format {
 	case "json":
 		return newJsonFormatList(output), nil
 	case "yaml":
 		return newYamlFormatList(output), nil
+	case "jsonl", "ndjson":
+		return newJsonLinesFormat(output), nil
 	case "text":
 		return newTextFormat(output), nil
+	case "csv":
+		return newCsvFormat(output), nil
+	case "html":
+		return newHtmlFormat(output), nil
 	default:
-		return nil, &Usage{Err: fmt.Errorf("unsupported output format: %q", format)}
+		return nil, &Usage{Err: errorf("unsupported output format: %q", format)}
 	}
 }
 
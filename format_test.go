@@ -137,6 +137,150 @@ func ExampleFormat_text_map() {
 	// 9012  C     3
 }
 
+func ExampleFormat_csv() {
+	cmd := cli.Command(func() error {
+		p, err := cli.Format("csv", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		type output struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Value int    `json:"value"`
+		}
+
+		p.Print(output{"1234", "A", 1})
+		p.Print(output{"5678", "B, C", 2})
+		p.Print(output{"9012", `"D"`, 3})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// id,name,value
+	// 1234,A,1
+	// 5678,"B, C",2
+	// 9012,"""D""",3
+}
+
+func ExampleFormat_csv_maps() {
+	cmd := cli.Command(func() error {
+		p, err := cli.Format("csv", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		p.Print(map[string]string{"id": "1234", "name": "A"})
+		p.Print(map[string]string{"id": "5678", "name": "B"})
+		p.Print(map[string]string{"id": "9012", "owner": "C"})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// id,name
+	// 1234,A
+	// 5678,B
+	// id,owner
+	// 9012,C
+}
+
+func ExampleFormat_html() {
+	cmd := cli.Command(func() error {
+		p, err := cli.Format("html", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		type output struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+
+		p.Print(output{"1234", "A"})
+		p.Print(output{"5678", "<B & C>"})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// <table>
+	//   <thead>
+	//     <tr><th>id</th><th>name</th></tr>
+	//   </thead>
+	//   <tbody>
+	//     <tr><td>1234</td><td>A</td></tr>
+	//     <tr><td>5678</td><td>&lt;B &amp; C&gt;</td></tr>
+	//   </tbody>
+	// </table>
+}
+
+func ExampleFormat_html_maps() {
+	cmd := cli.Command(func() error {
+		p, err := cli.Format("html", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		p.Print(map[string]string{"id": "1234", "name": "A"})
+		p.Print(map[string]string{"id": "5678", "owner": "B"})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// <table>
+	//   <thead>
+	//     <tr><th>id</th><th>name</th></tr>
+	//   </thead>
+	//   <tbody>
+	//     <tr><td>1234</td><td>A</td></tr>
+	//   </tbody>
+	// </table>
+	// <table>
+	//   <thead>
+	//     <tr><th>id</th><th>owner</th></tr>
+	//   </thead>
+	//   <tbody>
+	//     <tr><td>5678</td><td>B</td></tr>
+	//   </tbody>
+	// </table>
+}
+
+func ExampleFormat_json_path() {
+	cmd := cli.Command(func() error {
+		p, err := cli.Format("json=.items[].name", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		type item struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+
+		type output struct {
+			Items []item `json:"items"`
+		}
+
+		p.Print(output{Items: []item{{1, "A"}, {2, "B"}}})
+		p.Print(output{Items: []item{{3, "C"}}})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// A
+	// B
+	// C
+}
+
 func ExampleFormatList_json() {
 	cmd := cli.Command(func() error {
 		p, err := cli.FormatList("json", os.Stdout)
@@ -184,3 +328,53 @@ func ExampleFormatList_yaml() {
 	// - value: 2
 	// - value: 3
 }
+
+func ExampleFormatList_json_path() {
+	cmd := cli.Command(func() error {
+		p, err := cli.FormatList("json=.[-1]", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		type output struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+
+		p.Print(output{1, "A"})
+		p.Print(output{2, "B"})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// {
+	//   "id": 2,
+	//   "name": "B"
+	// }
+}
+
+func ExampleFormatList_jsonl() {
+	cmd := cli.Command(func() error {
+		p, err := cli.FormatList("jsonl", os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer p.Flush()
+
+		type output struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+
+		p.Print(output{1, "A"})
+		p.Print(output{2, "B"})
+		return nil
+	})
+
+	cli.Call(cmd)
+	// Output:
+	// {"id":1,"name":"A"}
+	// {"id":2,"name":"B"}
+}
@@ -0,0 +1,24 @@
+package cli
+
+import "context"
+
+// CommandOf is a type-safe version of Command, the signature of fn ensures at
+// compile time that the command receives a context and a configuration value
+// of type T, and returns an error. T must be a struct type, like the first
+// argument of functions passed to Command.
+//
+// Unlike Command, the configuration struct is validated when CommandOf is
+// called, which panics if T is not a valid configuration struct.
+func CommandOf[T any](fn func(context.Context, T) error) Function {
+	cmd := &CommandFunc{Func: fn}
+	cmd.configure()
+	return cmd
+}
+
+// CommandWithArgsOf is like CommandOf but fn also receives the list of
+// positional arguments of the command.
+func CommandWithArgsOf[T any](fn func(context.Context, T, []string) error) Function {
+	cmd := &CommandFunc{Func: fn}
+	cmd.configure()
+	return cmd
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Group is a command set with a help message and a description, for example:
+//
+//	cmd := cli.Group{
+//		Help: "Manage containers",
+//		Desc: "Containers are isolated processes running from images.",
+//		Commands: cli.CommandSet{
+//			"start": start,
+//			"stop":  stop,
+//		},
+//	}
+//
+// Group supersedes the convention of registering a command under the special
+// key "_" of a CommandSet to carry its help message, which is still supported
+// for backward compatibility.
+type Group struct {
+	// A short help message describing what the commands do.
+	Help string
+
+	// A full description of the commands.
+	Desc string
+
+	// The set of commands that the group dispatches to.
+	Commands CommandSet
+}
+
+// Call satisfies the Function interface, dispatching the call to the commands
+// of the group (see CommandSet).
+func (g Group) Call(ctx context.Context, args, env []string) (int, error) {
+	code, err := g.Commands.Call(ctx, args, env)
+	// The errors of the command set itself are reported for the group, so
+	// their messages show its help and description.
+	switch e := callError(err).(type) {
+	case *Help:
+		switch c := e.Cmd.(type) {
+		case CommandSet:
+			e.Cmd = g
+		case *showAll:
+			if _, ok := c.cmd.(CommandSet); ok {
+				e.Cmd = &showAll{wrappedCommand{g}}
+			}
+		}
+	case *Usage:
+		if _, ok := e.Cmd.(CommandSet); ok || e.Cmd == nil {
+			e.Cmd = g
+		}
+	}
+	return code, err
+}
+
+// Format writes a human-readable representation of the group to w, using the
+// same formatting verbs as CommandSet.
+//
+// Format satisfies the fmt.Formatter interface.
+func (g Group) Format(w fmt.State, v rune) {
+	switch v {
+	case 'v':
+		if w.Flag('#') {
+			fmt.Fprintf(w, "cli.Group{Help: %q, Desc: %q, Commands: %#v}", g.Help, g.Desc, g.Commands)
+			return
+		}
+		if g.Desc != "" {
+			for _, line := range wrapText(g.Desc, helpWidthOf(w)-2) {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+			io.WriteString(w, "\n")
+		}
+	case 'x':
+		if g.Help != "" {
+			io.WriteString(w, g.Help)
+			return
+		}
+	}
+	g.Commands.Format(w, v)
+}
+
+func (g Group) desc() string { return g.Desc }
+
+func (g Group) envPrefix() (string, bool) { return g.Commands.envPrefix() }
@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// HelpTemplate is the template rendering the help and usage messages printed
+// by Exec and Call when a command returns a *Help or *Usage error. It may be
+// replaced to customize the help messages of a program, for example to add a
+// header or a footer:
+//
+//	cli.HelpTemplate = template.Must(template.New("help").Parse(`
+//	{{.Header "Usage:"}}
+//	  {{.Usage}}
+//
+//	{{.Body}}
+//	Report bugs at https://example.com/issues
+//	{{if .Error}}
+//	{{.Header "Error:"}}
+//	  {{.Error}}
+//	{{end}}`))
+//
+// The template is executed with a HelpData value.
+var HelpTemplate = template.Must(template.New("help").Parse(`{{if .Cmd}}
+{{.Header "Usage:"}}
+  {{.Usage}}
+
+{{.Body}}{{end}}{{if .Error}}
+{{.Header "Error:"}}
+  {{.Error}}
+
+{{end}}`))
+
+// CommandTemplate is the template rendering the body of the help messages of
+// commands created by Command, which may be customized to reorder or add
+// sections. The template is executed with a CommandHelp value. It may also be
+// overridden for a single command by setting the Template field of a
+// CommandFunc.
+var CommandTemplate = template.Must(template.New("command").Parse(`{{if .Desc}}{{.Desc}}
+{{end}}{{if .Arguments}}{{.Header "Arguments:"}}
+{{.Arguments}}
+{{end}}{{if .Options}}{{.Header "Options:"}}
+{{.Options}}{{end}}{{if .Examples}}
+{{.Header "Examples:"}}
+{{.Examples}}{{end}}{{if .Footer}}
+{{.Footer}}{{end}}`))
+
+// HideHelpFlag removes the -h and --help options from the options listed in
+// help messages, for programs which consider them noise or mention them
+// elsewhere, like in a footer of HelpTemplate. Commands still print their help
+// message when called with -h or --help.
+var HideHelpFlag = false
+
+// HelpData is the data that HelpTemplate is executed with.
+type HelpData struct {
+	// The command that the message is about, which may be nil in usage
+	// messages carrying only an error.
+	Cmd Function
+
+	// The usage line of the command, e.g. "prog get [options] <name>".
+	Usage string
+
+	// The short help message of the command.
+	Help string
+
+	// The body of the help message, rendered by the command; it contains the
+	// description and options of command functions, or the list of commands
+	// of command sets. It ends with a new line.
+	Body string
+
+	// The errors found on the command line, one per line, or an empty string
+	// if the help was requested.
+	Error string
+
+	colors colors
+}
+
+// Header formats s as the header of a section of the help message, which is
+// printed in bold when colors are enabled.
+func (d HelpData) Header(s string) string { return d.colors.header(s) }
+
+// CommandHelp is the data that CommandTemplate is executed with. The sections
+// are indented and end with a new line, they are empty when the command has
+// none.
+type CommandHelp struct {
+	// The command that the help message is about.
+	Cmd *CommandFunc
+
+	// The description of the command, wrapped to the terminal width.
+	Desc string
+
+	// The list of positional arguments of the command, with their types and
+	// descriptions. It is empty when none of the arguments have names.
+	Arguments string
+
+	// The list of options of the command, with their descriptions. It is
+	// empty if the command has no options other than -h and --help, and
+	// HideHelpFlag is set.
+	Options string
+
+	// The examples of invocations of the command.
+	Examples string
+
+	// The footer of the help message.
+	Footer string
+
+	colors colors
+}
+
+// Header formats s as the header of a section of the help message, which is
+// printed in bold when colors are enabled.
+func (d CommandHelp) Header(s string) string { return d.colors.header(s) }
+
+// writeHelp renders the help message of cmd to w with HelpTemplate, including
+// the error err when it is not nil, and wrapping text to width columns.
+func writeHelp(w io.Writer, cmd Function, err error, c colors, width int) {
+	data := HelpData{Cmd: cmd, colors: c}
+
+	if cmd != nil {
+		data.Usage = fmt.Sprintf("%s", cmd)
+		data.Help = fmt.Sprintf("%x", cmd)
+		data.Body = fmt.Sprintf(c.verb(width), cmd)
+	}
+
+	if err != nil {
+		msg := err.Error()
+		if _, ok := err.(errorList); ok {
+			// Indent each error of the list to align them under the header.
+			msg = strings.ReplaceAll(msg, "\n", "\n  ")
+		}
+		data.Error = c.err(msg)
+	}
+
+	executeTemplate(w, HelpTemplate, data)
+}
+
+// showHidden returns true if the hidden options and commands are included in
+// the help message formatted with w, which is requested with the "0" flag, as
+// in "%0v".
+func showHidden(w fmt.State) bool { return w.Flag('0') }
+
+// showAll is the command of the help messages requested with --help-all, which
+// formats the description of the command it wraps with the "0" flag.
+type showAll struct{ wrappedCommand }
+
+func (c *showAll) Format(w fmt.State, v rune) {
+	if v == 'v' && !w.Flag('#') {
+		fmt.Fprintf(w, strings.Replace(colorsOf(w).verb(helpWidthOf(w)), "%", "%0", 1), c.cmd)
+		return
+	}
+	c.wrappedCommand.Format(w, v)
+}
+
+// executeTemplate executes tmpl with data, writing the template errors to w
+// since formatters have no other way of reporting them.
+func executeTemplate(w io.Writer, tmpl *template.Template, data interface{}) {
+	if err := tmpl.Execute(w, data); err != nil {
+		fmt.Fprintf(w, "%%!(TEMPLATE ERROR: %s)", err)
+	}
+}
+
+// verboseHelp is the command of the help messages requested with the
+// --help-verbose option, which show the values that the options would have
+// and where they come from in place of their default values.
+type verboseHelp struct {
+	*CommandFunc
+	annotations map[string]string
+}
+
+func (h *verboseHelp) Format(w fmt.State, v rune) {
+	if v == 'v' && !w.Flag('#') {
+		h.formatHelp(w, colorsOf(w), helpWidthOf(w), h.annotations, showHidden(w))
+		return
+	}
+	h.CommandFunc.Format(w, v)
+}
+
+// valueAnnotations returns the notes describing the values of the options of
+// cmd, and their sources, for the verbose help.
+func (cmd *CommandFunc) valueAnnotations(ctx context.Context, options map[string][]string, info FlagInfo, env []string) map[string]string {
+	annotations := make(map[string]string, len(cmd.options))
+
+	for name := range cmd.options {
+		if name == "--help" {
+			continue
+		}
+		if value, source, ok := cmd.valueSource(ctx, name, options, info, env); ok {
+			annotations[name] = fmt.Sprintf(tr("(value: %s, from %s)"), value, source)
+		} else {
+			annotations[name] = tr("(not set)")
+		}
+	}
+
+	return annotations
+}
+
+// valueSource returns the value of the option name, with secrets masked, and
+// the description of where it was loaded from. The names of the environment
+// variables that the values were loaded from are shown with their prefix.
+// False is returned if the option was not set.
+func (cmd *CommandFunc) valueSource(ctx context.Context, name string, options map[string][]string, info FlagInfo, env []string) (value, source string, ok bool) {
+	field := cmd.options[name]
+	values, ok := options[name]
+	if !ok {
+		return "", "", false
+	}
+
+	value = strings.Join(values, ", ")
+	switch {
+	case field.secret:
+		value = "******"
+	case field.boolean && len(values) == 0:
+		value = "true"
+	}
+
+	switch s, ok := info.sources[name]; {
+	case !ok:
+		source = tr("default")
+	case s == FromEnv:
+		source = tr("environment")
+		for _, e := range field.envvars {
+			if _, ok := lookupEnvContext(ctx, e, env); ok {
+				source = fmt.Sprintf(tr("environment variable %s"), execOptionsOf(ctx).environPrefix+e)
+				break
+			}
+		}
+	case s == FromConfig:
+		source = tr("config file")
+	default:
+		source = tr(s.String())
+	}
+
+	return value, source, true
+}
+
+// UsagePrinter is the type of functions printing the *Help and *Usage errors
+// returned by the commands of a program, see WithUsagePrinter.
+type UsagePrinter func(w io.Writer, err error)
+
+// WithUsagePrinter sets the function printing the help and usage messages of
+// the program, in place of the full messages rendered by HelpTemplate. The
+// function writes the help requested by users to Out, and usage errors to Err.
+// The function receives either a *Help or a *Usage error, which it may still
+// print in full by formatting it with "%v":
+//
+//	cli.ExecWith(cmd, cli.WithUsagePrinter(cli.ShortUsage))
+func WithUsagePrinter(p UsagePrinter) ExecOption {
+	return func(o *execOptions) { o.usagePrinter = p }
+}
+
+// ShortUsage is a UsagePrinter which prints the help messages requested with
+// --help in full, but only the errors of usage messages, followed by a hint to
+// run the command with --help, for example:
+//
+//	error: unrecognized option: "--verbos" (did you mean "--verbose"?)
+//	Run "prog get --help" for usage.
+func ShortUsage(w io.Writer, err error) {
+	u, ok := err.(*Usage)
+	if !ok {
+		fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+
+	msg := fmt.Sprint(u.Err)
+	if _, ok := u.Err.(errorList); ok {
+		msg = "\n  " + strings.ReplaceAll(msg, "\n", "\n  ")
+	}
+	fmt.Fprintf(w, "%s: %s\n", tr("error"), msg)
+
+	if names := namesOf(u.Cmd); len(names) != 0 {
+		fmt.Fprintf(w, tr("Run %q for usage.")+"\n", strings.Join(names, " ")+" --help")
+	} else {
+		fmt.Fprintf(w, "%s\n", tr("Run with --help for usage."))
+	}
+}
+
+// namesOf returns the names of the commands leading to cmd, from the program
+// to the command itself.
+func namesOf(cmd Function) []string {
+	if x, ok := cmd.(interface{ names() []string }); ok {
+		return x.names()
+	}
+	return nil
+}
+
+func (c *namedCommand) names() []string { return append([]string{c.name}, namesOf(c.cmd)...) }
+
+func (w wrappedCommand) names() []string { return namesOf(w.cmd) }
+
+func (c *lazyCommand) names() []string { return c.get().names() }
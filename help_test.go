@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestHelpTemplate(t *testing.T) {
+	defer func(tmpl *template.Template) { HelpTemplate = tmpl }(HelpTemplate)
+	HelpTemplate = template.Must(template.New("help").Parse(
+		`usage: {{.Usage}}{{if .Error}} ({{.Error}}){{end}}`,
+	))
+
+	cmd := NamedCommand("prog", Command(func(struct{}) {}))
+
+	_, err := cmd.Call(context.TODO(), []string{"-h"}, nil)
+	if s := fmt.Sprint(err); s != "usage: prog [options]" {
+		t.Errorf("wrong help message: %q", s)
+	}
+
+	_, err = cmd.Call(context.TODO(), []string{"--nope"}, nil)
+	if s := fmt.Sprint(err); s != `usage: prog [options] (unrecognized option: "--nope")` {
+		t.Errorf("wrong usage message: %q", s)
+	}
+}
+
+func TestHideHelpFlag(t *testing.T) {
+	defer func(hide bool) { HideHelpFlag = hide }(HideHelpFlag)
+	HideHelpFlag = true
+
+	type config struct {
+		Verbose bool `flag:"-v,--verbose" help:"Enable verbose mode"`
+	}
+
+	tests := []struct {
+		scenario string
+		cmd      Function
+		help     string
+	}{
+		{
+			scenario: "command with options",
+			cmd:      NamedCommand("prog", Command(func(config) {})),
+			help: `
+Usage:
+  prog [options]
+
+Options:
+  -v, --verbose  Enable verbose mode
+`,
+		},
+		{
+			scenario: "command without options",
+			cmd:      NamedCommand("prog", Command(func(struct{}) {})),
+			help: `
+Usage:
+  prog [options]
+
+`,
+		},
+		{
+			scenario: "command set",
+			cmd:      CommandSet{"run": Command(func(struct{}) {})},
+			help: `
+Usage:
+  [command] [-h] [--help] ...
+
+Commands:
+  run
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			_, err := test.cmd.Call(context.TODO(), []string{"-h"}, nil)
+			if _, ok := err.(*Help); !ok {
+				t.Fatalf("expected a help error, got %v", err)
+			}
+			if s := fmt.Sprint(err); s != test.help {
+				t.Errorf("wrong help message:\n%q\nwant:\n%q", s, test.help)
+			}
+		})
+	}
+}
+
+func TestHelpOutput(t *testing.T) {
+	defer func(out, err io.Writer) { Out, Err = out, err }(Out, Err)
+	stdout, stderr := new(strings.Builder), new(strings.Builder)
+	Out, Err = stdout, stderr
+
+	cmd := NamedCommand("prog", Command(func(struct{}) {}))
+
+	if code := Call(cmd, "--help"); code != 0 {
+		t.Errorf("wrong exit code for requested help: %d", code)
+	}
+	if !strings.Contains(stdout.String(), "Usage:") || stderr.Len() != 0 {
+		t.Errorf("requested help was not printed to Out:\nout: %q\nerr: %q", stdout, stderr)
+	}
+
+	stdout.Reset()
+	if code := Call(cmd, "--nope"); code != 1 {
+		t.Errorf("wrong exit code for usage error: %d", code)
+	}
+	if !strings.Contains(stderr.String(), "Usage:") || stdout.Len() != 0 {
+		t.Errorf("usage error was not printed to Err:\nout: %q\nerr: %q", stdout, stderr)
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	defer func(out io.Writer) { Out = out }(Out)
+	stdout := new(strings.Builder)
+	Out = stdout
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		scenario string
+		cmd      Function
+		args     []string
+		output   string
+	}{
+		{
+			scenario: "returned values",
+			cmd:      Command(func(struct{}) (item, error) { return item{"a"}, nil }),
+			args:     []string{"-o", "yaml"},
+			output:   "name: a\n",
+		},
+		{
+			scenario: "default streams",
+			cmd:      Command(func(_ struct{}, s Streams) { io.WriteString(s.Stdout, "hello\n") }),
+			output:   "hello\n",
+		},
+		{
+			scenario: "printer with no writer",
+			cmd: Command(func(struct{}) error {
+				p, err := Format("yaml", nil)
+				if err != nil {
+					return err
+				}
+				defer p.Flush()
+				p.Print(item{"b"})
+				return nil
+			}),
+			output: "name: b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			stdout.Reset()
+			if code := Call(test.cmd, test.args...); code != 0 {
+				t.Fatalf("wrong exit code: %d", code)
+			}
+			if s := stdout.String(); s != test.output {
+				t.Errorf("wrong output: %q, want %q", s, test.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Invoke calls the function of cmd with a configuration and positional
+// arguments given as Go values, bypassing the parsing of the command line.
+// This lets other Go code and tests reuse the logic of commands without
+// building lists of arguments:
+//
+//	code, err := cli.Invoke(ctx, copyCommand, copyConfig{Recursive: true}, "src/", "dst/")
+//
+// The config value must be assignable to the configuration parameter of the
+// function, or be a pointer to such a value; it is ignored by functions which
+// do not have a configuration parameter and may be nil to pass a zero value.
+// The defaults declared in struct tags are not applied to it.
+//
+// Each positional argument is passed to the parameter following the
+// configuration, missing ones being set to their zero value, and the arguments
+// remaining when the function is variadic must be strings. The parameters
+// injected by the package, like Streams or provided values, are set the same
+// way they are when the command is called.
+//
+// The function returned values are converted to a code and an error like they
+// are by Call, and values returned by commands with an --output option are
+// printed as text.
+//
+// The command may be wrapped by NamedCommand, InCategory, Lazy, and the
+// other functions preserving its settings, but only its function is called:
+// middleware, pre-run hooks, and deprecation warnings are bypassed.
+//
+// The function panics if cmd was not created by Command, or if the values do
+// not match the parameters of its function.
+func Invoke(ctx context.Context, cmd Function, config interface{}, args ...interface{}) (int, error) {
+	c := commandFuncOf(cmd)
+	if c == nil {
+		panic(fmt.Sprintf("cli.Invoke: %T is not backed by a command function", cmd))
+	}
+	c.configure()
+
+	var params []reflect.Value
+	t := c.function.Type()
+	n := t.NumIn()
+	x := 0
+
+	if c.context {
+		params = append(params, reflect.ValueOf(ctx))
+		x++
+	}
+
+	if c.variadic {
+		n--
+	}
+
+	if x < n && !c.forward {
+		params = append(params, invokeValue(t.In(x), config, "configuration"))
+		x++
+	}
+
+	for i := x; i < n; i++ {
+		p := t.In(i)
+
+		switch {
+		case p == flagInfoType:
+			params = append(params, reflect.ValueOf(FlagInfo{}))
+		case p == streamsType:
+			params = append(params, reflect.ValueOf(streamsOf(ctx)))
+		case isInjectedType(p):
+			v, err := provide(ctx, p)
+			if err != nil {
+				return 1, err
+			}
+			params = append(params, v)
+		case len(args) == 0:
+			params = append(params, reflect.Zero(p))
+		default:
+			params = append(params, invokeValue(p, args[0], "positional argument"))
+			args = args[1:]
+		}
+	}
+
+	var r []reflect.Value
+	if c.variadic {
+		command := make([]string, len(args))
+		for i, arg := range args {
+			s, ok := arg.(string)
+			if !ok {
+				panic(fmt.Sprintf("cli.Invoke: variadic arguments must be strings, got %T", arg))
+			}
+			command[i] = s
+		}
+		r = c.function.CallSlice(append(params, reflect.ValueOf(command)))
+	} else {
+		if len(args) != 0 {
+			panic(fmt.Sprintf("cli.Invoke: too many positional arguments: %d", len(args)))
+		}
+		r = c.function.Call(params)
+	}
+
+	return c.results(ctx, r, "text")
+}
+
+// invokeValue returns the value of parameter type t for v, which may also be a
+// pointer to a value of type t.
+func invokeValue(t reflect.Type, v interface{}, what string) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Type().Elem().AssignableTo(t) && !rv.Type().AssignableTo(t) {
+		if rv.IsNil() {
+			return reflect.Zero(t)
+		}
+		rv = rv.Elem()
+	}
+	if !rv.Type().AssignableTo(t) {
+		panic(fmt.Sprintf("cli.Invoke: %s of type %s cannot be passed as %s", what, rv.Type(), t))
+	}
+	return rv
+}
+
+// commandFuncOf returns the command function backing cmd, or nil if there are
+// none or more than one.
+func commandFuncOf(cmd Function) *CommandFunc {
+	if x, ok := cmd.(interface{ commandFunc() *CommandFunc }); ok {
+		return x.commandFunc()
+	}
+	return nil
+}
+
+func (cmd *CommandFunc) commandFunc() *CommandFunc { return cmd }
+
+func (c *namedCommand) commandFunc() *CommandFunc { return commandFuncOf(c.cmd) }
+
+func (w wrappedCommand) commandFunc() *CommandFunc { return commandFuncOf(w.cmd) }
+
+func (c *lazyCommand) commandFunc() *CommandFunc { return c.get().commandFunc() }
+
+func (c *chainCommand) commandFunc() *CommandFunc { return nil }
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInvoke(t *testing.T) {
+	type config struct {
+		Flag string `flag:"--flag" default:"-"`
+	}
+
+	var got []string
+	cmd := Lazy(func() Function {
+		return Command(func(ctx context.Context, config config, a string, b int, rest ...string) error {
+			got = append([]string{config.Flag, a}, rest...)
+			if b != 0 {
+				return Exit(b, errors.New("failed"))
+			}
+			return nil
+		})
+	})
+
+	code, err := Invoke(context.TODO(), cmd, config{Flag: "f"}, "a", 0, "x", "y")
+	if code != 0 || err != nil {
+		t.Fatalf("unexpected result: %d, %v", code, err)
+	}
+	if want := []string{"f", "a", "x", "y"}; !equalStrings(got, want) {
+		t.Errorf("wrong values: got %q, want %q", got, want)
+	}
+
+	code, err = Invoke(context.TODO(), cmd, nil, "", 2)
+	if code != 1 || err == nil {
+		t.Errorf("unexpected result: %d, %v", code, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for a configuration of the wrong type")
+		}
+	}()
+	Invoke(context.TODO(), cmd, "config")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
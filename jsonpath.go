@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a selector of values in JSON documents, in a subset of the jq
+// syntax: ".items[0].id" selects the "id" field of the first element of the
+// "items" array, ".items[].id" the "id" fields of all its elements, and "."
+// the document itself. Fields with special characters are selected with a
+// quoted name, like .["content-type"].
+type jsonPath []jsonPathStep
+
+type jsonPathStep struct {
+	field string
+	index int
+	kind  jsonPathKind
+}
+
+type jsonPathKind int
+
+const (
+	jsonPathField jsonPathKind = iota
+	jsonPathIndex
+	jsonPathIter
+)
+
+// parseJSONPath parses the selector s, returning a usage error if its syntax
+// is invalid.
+func parseJSONPath(s string) (jsonPath, error) {
+	if !strings.HasPrefix(s, ".") {
+		return nil, &Usage{Err: fmt.Errorf("invalid path: %q must start with '.'", s)}
+	}
+
+	var path jsonPath
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+			j := i
+			for j < len(s) && s[j] != '.' && s[j] != '[' {
+				j++
+			}
+			if j > i {
+				path = append(path, jsonPathStep{field: s[i:j], kind: jsonPathField})
+			} else if i < len(s) && s[i] == '.' {
+				return nil, &Usage{Err: fmt.Errorf("invalid path: %q has an empty field name", s)}
+			}
+			i = j
+		case '[':
+			j := strings.IndexByte(s[i:], ']')
+			if j < 0 {
+				return nil, &Usage{Err: fmt.Errorf("invalid path: %q is missing a closing ']'", s)}
+			}
+			step, err := parseJSONPathBracket(s[i+1 : i+j])
+			if err != nil {
+				return nil, &Usage{Err: fmt.Errorf("invalid path: %q: %w", s, err)}
+			}
+			path = append(path, step)
+			i += j + 1
+		default:
+			return nil, &Usage{Err: fmt.Errorf("invalid path: %q has an unexpected %q", s, s[i])}
+		}
+	}
+	return path, nil
+}
+
+func parseJSONPathBracket(s string) (jsonPathStep, error) {
+	if s == "" {
+		return jsonPathStep{kind: jsonPathIter}, nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		field, err := strconv.Unquote(s)
+		return jsonPathStep{field: field, kind: jsonPathField}, err
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("%q is not an index", s)
+	}
+	return jsonPathStep{index: index, kind: jsonPathIndex}, nil
+}
+
+// eval returns the values selected by the path in v, which is a value decoded
+// from JSON. Missing fields and indexes select null values, like in jq.
+func (path jsonPath) eval(v interface{}) []interface{} {
+	values := []interface{}{v}
+
+	for _, step := range path {
+		next := make([]interface{}, 0, len(values))
+
+		for _, v := range values {
+			switch step.kind {
+			case jsonPathField:
+				m, _ := v.(map[string]interface{})
+				next = append(next, m[step.field])
+			case jsonPathIndex:
+				a, _ := v.([]interface{})
+				i := step.index
+				if i < 0 {
+					i += len(a)
+				}
+				if i >= 0 && i < len(a) {
+					next = append(next, a[i])
+				} else {
+					next = append(next, nil)
+				}
+			case jsonPathIter:
+				switch x := v.(type) {
+				case []interface{}:
+					next = append(next, x...)
+				case map[string]interface{}:
+					keys := make([]string, 0, len(x))
+					for k := range x {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, x[k])
+					}
+				}
+			}
+		}
+
+		values = next
+	}
+
+	return values
+}
+
+// jsonPathFormat is the printer of the "json=<path>" formats, which prints the
+// values selected by the path in the JSON representation of printed values.
+// Strings are printed without quotes so they can be used as-is by scripts,
+// other values are printed as JSON.
+type jsonPathFormat struct {
+	writer io.Writer
+	path   jsonPath
+	list   bool
+	values []json.RawMessage
+}
+
+func newJsonPathFormat(w io.Writer, path string, list bool) (PrintFlusher, error) {
+	p, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonPathFormat{writer: w, path: p, list: list}, nil
+}
+
+func (p *jsonPathFormat) Print(v interface{}) {
+	b, _ := json.Marshal(normalizeValue(v))
+	if p.list {
+		p.values = append(p.values, json.RawMessage(b))
+	} else {
+		p.print(b)
+	}
+}
+
+func (p *jsonPathFormat) Flush() {
+	if p.list {
+		b, _ := json.Marshal(normalizeValue(p.values))
+		p.print(b)
+		p.values = nil
+	}
+}
+
+func (p *jsonPathFormat) print(b []byte) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	d.Decode(&v)
+
+	e := json.NewEncoder(p.writer)
+	e.SetIndent("", "  ")
+
+	for _, v := range p.path.eval(v) {
+		if s, ok := v.(string); ok {
+			fmt.Fprintln(p.writer, s)
+		} else {
+			e.Encode(v)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	const doc = `{"items":[{"id":1,"tags":["a","b"]},{"id":2,"tags":[]}],"content-type":"text/plain"}`
+
+	tests := []struct {
+		path   string
+		values []interface{}
+	}{
+		{".items[0].id", []interface{}{1.0}},
+		{".items[-1].id", []interface{}{2.0}},
+		{".items[].id", []interface{}{1.0, 2.0}},
+		{".items[].tags[]", []interface{}{"a", "b"}},
+		{".items[2].id", []interface{}{nil}},
+		{".missing.field", []interface{}{nil}},
+		{`.["content-type"]`, []interface{}{"text/plain"}},
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if path, _ := parseJSONPath("."); !reflect.DeepEqual(path.eval(v), []interface{}{v}) {
+		t.Error("the . path must select the whole document")
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := parseJSONPath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values := path.eval(v); !reflect.DeepEqual(values, test.values) {
+				t.Errorf("wrong values: got %v, want %v", values, test.values)
+			}
+		})
+	}
+}
+
+func TestJSONPathError(t *testing.T) {
+	for _, path := range []string{"items", "..items", ".items[0", ".items[x]", `.["a]`} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("%q: expected an error", path)
+		} else if _, ok := err.(*Usage); !ok {
+			t.Errorf("%q: expected a usage error, got %T", path, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WithLock returns a middleware which acquires an exclusive lock on the file
+// at path before calling commands, and releases it when they return, for
+// programs which mutate shared local state and must not run concurrently:
+//
+//	cmd := cli.Wrap(cli.Command(sync), cli.WithLock("/var/lib/prog/lock"))
+//
+// When another process holds the lock, the command is not called and the
+// error is a *LockError carrying the process id of the other invocation. The
+// file is created if it does not exist, and contains the process id of the
+// program holding the lock.
+//
+// On Unix systems the lock is released by the operating system when the
+// program exits. On other platforms the lock is the existence of the file,
+// which must be removed by hand if the program was killed while holding it.
+func WithLock(path string) func(Function) Function {
+	return func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			unlock, err := lockFile(path)
+			if err != nil {
+				return 1, err
+			}
+			defer unlock()
+			return next.Call(ctx, args, env)
+		})
+	}
+}
+
+// LockError is returned by commands wrapped with WithLock when the lock is
+// held by another process.
+type LockError struct {
+	// The path of the lock file.
+	Path string
+	// The process id of the program holding the lock, or zero if it is not
+	// known.
+	PID int
+}
+
+// Error satisfies the error interface.
+func (e *LockError) Error() string {
+	if e.PID != 0 {
+		return fmt.Sprintf(tr("another instance is already running (pid %d), lock held on %s"), e.PID, e.Path)
+	}
+	return fmt.Sprintf(tr("another instance is already running, lock held on %s"), e.Path)
+}
+
+// readLockPID returns the process id written to the lock file f, or zero if
+// it could not be read.
+func readLockPID(f *os.File) int {
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b[:n])))
+	return pid
+}
+
+// writeLockPID writes the process id of the program to the lock file f.
+func writeLockPID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
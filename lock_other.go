@@ -0,0 +1,37 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package cli
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// lockFile acquires an exclusive lock on the file at path by creating it,
+// returning a function which releases the lock by removing the file.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			lockErr := &LockError{Path: path}
+			if f, err := os.Open(path); err == nil {
+				lockErr.PID = readLockPID(f)
+				f.Close()
+			}
+			return nil, lockErr
+		}
+		return nil, err
+	}
+
+	if err := writeLockPID(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+
+	return func() error {
+		f.Close()
+		return os.Remove(path)
+	}, nil
+}
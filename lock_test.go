@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+
+	var nested error
+	cmd := Wrap(Command(func(ctx context.Context) {
+		// The lock is held while the command runs, so calling it again from
+		// the same process must fail.
+		_, nested = Wrap(Command(func() {}), WithLock(path)).Call(ctx, nil, nil)
+	}), WithLock(path))
+
+	if _, err := cmd.Call(context.TODO(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var lockErr *LockError
+	if !errors.As(nested, &lockErr) {
+		t.Fatalf("expected a lock error, got %v", nested)
+	}
+	if lockErr.Path != path || lockErr.PID != os.Getpid() {
+		t.Errorf("wrong lock error: %+v", lockErr)
+	}
+
+	// The lock is released when the command returns.
+	if _, err := cmd.Call(context.TODO(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive lock on the file at path, returning a
+// function which releases it.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, &LockError{Path: path, PID: readLockPID(f)}
+		}
+		return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+
+	if err := writeLockPID(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		// Closing the file releases the lock.
+		f.Truncate(0)
+		return f.Close()
+	}, nil
+}
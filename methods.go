@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// CommandsOf constructs a CommandSet from the exported methods of v, which
+// makes it possible to organize the commands of a program as methods of a type
+// holding their shared dependencies, for example:
+//
+//	type app struct {
+//		db *sql.DB
+//	}
+//
+//	func (a *app) CreateUser(config createConfig, name string) error {
+//		...
+//	}
+//
+//	func (a *app) DeleteUser(config deleteConfig, name string) error {
+//		...
+//	}
+//
+//	cmd := cli.CommandsOf(&app{db: db}) // "create-user" and "delete-user"
+//
+// The commands are keyed by the kebab-case form of the method names. Methods
+// returning nothing, an error, or a value and an error look like commands, and
+// must have one of the signatures supported by Command, otherwise CommandsOf
+// panics; other methods (for example String() string) are not registered as
+// commands.
+//
+// When v has a Setup(context.Context) error method, it is called before the
+// command runs, after its arguments were successfully parsed, so the shared
+// dependencies are only initialized once per invocation and not when the help
+// message is printed; the command is not run if Setup returns an error. When
+// v has a Teardown(error) method, it is called with the error of the command
+// (or of Setup) after it returned, so resources are cleaned up even when it
+// failed. These two methods are not registered as commands.
+func CommandsOf(v interface{}) CommandSet {
+	cmds := make(CommandSet)
+	value := reflect.ValueOf(v)
+	setup, hasSetup := v.(interface{ Setup(context.Context) error })
+	teardown, hasTeardown := v.(interface{ Teardown(error) })
+
+	for i, n := 0, value.NumMethod(); i < n; i++ {
+		m := value.Type().Method(i)
+		f := value.Method(i)
+
+		if (hasSetup && m.Name == "Setup") || (hasTeardown && m.Name == "Teardown") {
+			continue
+		}
+
+		if isCommandFunc(f.Type()) {
+			var cmd Function = commandOfMethod(m.Name, f)
+			if hasSetup || hasTeardown {
+				cmd = &lifecycleCommand{wrappedCommand{cmd}, setup, teardown}
+			}
+			cmds[kebabcase(m.Name)] = cmd
+		}
+	}
+
+	return cmds
+}
+
+// lifecycleCommand wraps the commands of containers which have Setup or
+// Teardown methods to call them around the command.
+type lifecycleCommand struct {
+	wrappedCommand
+	setup    interface{ Setup(context.Context) error }
+	teardown interface{ Teardown(error) }
+}
+
+func (c *lifecycleCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	started := false
+	hook := func(ctx context.Context, _ []string) error {
+		started = true
+		if c.setup != nil {
+			return c.setup.Setup(ctx)
+		}
+		return nil
+	}
+
+	code, err := (&preRunCommand{c.wrappedCommand, hook}).Call(ctx, args, env)
+
+	if started && c.teardown != nil {
+		c.teardown.Teardown(err)
+	}
+	return code, err
+}
+
+// commandOfMethod constructs the command of the method f, panicking with the
+// method name if its signature is not supported by Command.
+func commandOfMethod(name string, f reflect.Value) *CommandFunc {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("cli.CommandsOf: method %s: %v", name, r))
+		}
+	}()
+	cmd := &CommandFunc{Func: f.Interface()}
+	cmd.configure()
+	return cmd
+}
+
+// isCommandFunc returns true if t looks like the type of a function accepted by
+// Command: a function returning nothing, an error, or an exit code or value
+// and an error. Whether its parameters are supported is checked when the
+// command is configured.
+func isCommandFunc(t reflect.Type) bool {
+	switch t.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return t.Out(0) == errorType
+	case 2:
+		return t.Out(1) == errorType
+	default:
+		return false
+	}
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
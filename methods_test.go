@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+type methodsService struct{}
+
+func (methodsService) Get(config struct{}) (string, error) { return "value", nil }
+
+func (methodsService) Many(config struct{}) (int, error) { return 0, nil }
+
+func (methodsService) Plain(config struct{}) {}
+
+func (methodsService) Only(args ...string) error { return nil }
+
+func (methodsService) Name() string { return "service" }
+
+type variadicService struct{ args *[]string }
+
+func (s *variadicService) Only(args ...string) error {
+	*s.args = args
+	return nil
+}
+
+type invalidMethodsService struct{}
+
+func (invalidMethodsService) Lookup(key string) (string, error) { return key, nil }
+
+func TestCommandsOfSignatures(t *testing.T) {
+	cmds := CommandsOf(methodsService{})
+
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if got := strings.Join(names, ","); got != "get,many,only,plain" {
+		t.Errorf("wrong commands: %s", got)
+	}
+
+	var out strings.Builder
+	if code := CallWith(cmds, []string{"get"}, WithStdout(&out)); code != 0 {
+		t.Fatal("exit code:", code)
+	}
+	if out.String() != "value\n" {
+		t.Errorf("wrong output: %q", out.String())
+	}
+}
+
+func TestCommandsOfVariadic(t *testing.T) {
+	var args []string
+	cmds := CommandsOf(&variadicService{args: &args})
+
+	if code := Call(cmds, "only", "--flag", "a"); code != 0 {
+		t.Fatal("exit code:", code)
+	}
+	if got := strings.Join(args, " "); got != "--flag a" {
+		t.Errorf("wrong arguments: %q", got)
+	}
+}
+
+func TestCommandsOfInvalidMethod(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("no panic for a method with an invalid command signature")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, "method Lookup") {
+			t.Errorf("the panic does not name the method: %v", r)
+		}
+	}()
+	CommandsOf(invalidMethodsService{})
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// panicExitCode is the exit code of programs whose command panicked when
+// panic recovery is enabled, it is EX_SOFTWARE from sysexits.h.
+const panicExitCode = 70
+
+// WithPanicRecovery recovers the panics of the commands called by the program,
+// printing the panic value and a stack trace trimmed to the frames of the
+// command to Err instead of the full dump of the Go runtime. The program then
+// exits with code 70 (EX_SOFTWARE), which is distinct from the codes of failed
+// commands and invalid command lines.
+func WithPanicRecovery() ExecOption {
+	return func(o *execOptions) { o.recoverPanics = true }
+}
+
+// panicError is the error returned in place of the panics recovered from
+// commands.
+type panicError struct {
+	value interface{}
+	stack string
+}
+
+func (e *panicError) Error() string { return fmt.Sprintf("panic: %v", e.value) }
+
+func (e *panicError) ExitCode() int { return panicExitCode }
+
+// callRecover calls cmd, converting the panics which occur during the call to
+// a *panicError.
+func callRecover(ctx context.Context, cmd Function, args, env []string) (code int, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			code, err = panicExitCode, &panicError{value: v, stack: trimStack(string(debug.Stack()))}
+		}
+	}()
+	return cmd.Call(ctx, args, env)
+}
+
+// trimStack removes the frames of the recovery and of the Go runtime from the
+// top of a stack trace, and the frames which led to calling the command
+// function from its bottom.
+func trimStack(stack string) string {
+	lines := strings.Split(strings.TrimSuffix(stack, "\n"), "\n")
+
+	// Frames are made of two lines, one with the function and one with its
+	// location; the frames below "panic(...)" start where the panic occurred.
+	for i, line := range lines {
+		if strings.HasPrefix(line, "panic(") && i+2 <= len(lines) {
+			lines = lines[i+2:]
+			break
+		}
+	}
+
+	// Command functions are called via reflection, the frames which follow
+	// belong to the cli package.
+	for i, line := range lines {
+		if strings.HasPrefix(line, "reflect.") {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
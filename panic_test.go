@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCallPanicRecovery(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	b := new(bytes.Buffer)
+	Err = b
+
+	cmd := NamedCommand("prog", Command(func() { panicking() }))
+	options := []ExecOption{WithPanicRecovery()}
+
+	if code := call(context.TODO(), cmd, nil, makeExecOptions(options)); code != panicExitCode {
+		t.Errorf("wrong exit code: got %d, want %d", code, panicExitCode)
+	}
+
+	out := b.String()
+	if !strings.HasPrefix(out, "panic: oops\n\ngithub.com/segmentio/cli.panicking(") {
+		t.Errorf("wrong output:\n%s", out)
+	}
+	for _, frame := range []string{"runtime/debug.Stack", "reflect.Value.call", "cli.callRecover"} {
+		if strings.Contains(out, frame) {
+			t.Errorf("the stack trace was not trimmed, it contains %s:\n%s", frame, out)
+		}
+	}
+}
+
+func panicking() { panic("oops") }
@@ -1,28 +1,47 @@
 package cli
 
 import (
-	"fmt"
+	"sort"
 	"strings"
 )
 
 type option struct {
-	boolean bool
+	boolean  bool
+	repeated bool // boolean flag which may be repeated, like -vvv
+	hidden   bool // not suggested for mistyped flags
 }
 
 type parser struct {
 	aliases map[string]string
 	options map[string]option
+	// Names of the positional arguments, in the order they are expected on
+	// the command line.
+	args []string
+	// When true, option parsing stops at the first positional argument.
+	optionsFirst bool
+	// When true, long flags may be abbreviated to unambiguous prefixes.
+	abbrev bool
+	// When true, long flags may also be written with a single dash.
+	singleDash bool
 }
 
 func makeParser() parser {
 	return parser{
 		aliases: map[string]string{"-h": "--help"},
-		options: map[string]option{"--help": {boolean: true}},
+		options: map[string]option{
+			"--help":         {boolean: true},
+			"--help-all":     {boolean: true, hidden: true},
+			"--help-verbose": {boolean: true, hidden: true},
+		},
 	}
 }
 
+// parseCommandLine parses args, returning the values of options, the positional
+// values, and the command following a "--" separator. When the command line
+// has problems, the returned *Usage error lists all of them.
 func (p parser) parseCommandLine(args []string) (options map[string][]string, values, command []string, err error) {
 	options = make(map[string][]string)
+	var errs []error
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
@@ -33,11 +52,30 @@ func (p parser) parseCommandLine(args []string) (options map[string][]string, va
 		}
 
 		if !isOption(arg) { // positional argument
+			if p.optionsFirst {
+				values = append(values, args[i:]...)
+				break
+			}
 			values = append(values, arg)
 			continue
 		}
 
 		name, value, hasValue := splitNameValue(arg)
+		flag := name
+
+		if p.singleDash && !isLongFlag(name) && len(name) > 2 && !p.has(name) {
+			name = "-" + name
+		}
+
+		if p.abbrev {
+			n, err := p.expandAbbrev(name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			name = n
+		}
+
 		// If the argument is an alias, overwrite with the main option name to
 		// ensure that all values given for that option are combined.
 		alias, ok := p.aliases[name]
@@ -47,17 +85,21 @@ func (p parser) parseCommandLine(args []string) (options map[string][]string, va
 
 		option, ok := p.options[name]
 		if !ok {
-			err = &Usage{Err: fmt.Errorf("unrecognized option: %q", arg)}
-			return
+			if target, n := p.repeatedFlag(flag); n != 0 && !hasValue {
+				for j := 0; j < n; j++ {
+					options[target] = append(options[target], "true")
+				}
+				continue
+			}
+			errs = append(errs, &ErrUnknownFlag{Flag: flag, Suggestions: p.suggest(flag)})
+			continue
 		}
 
 		if option.boolean {
 			if hasValue {
-				switch value {
-				case "true", "false":
-				default:
-					err = &Usage{Err: fmt.Errorf("unexpected boolean value: %q", value)}
-					return
+				if _, err := parseBool(value); err != nil {
+					errs = append(errs, errorf("unexpected boolean value: %q", value))
+					continue
 				}
 			} else {
 				value, hasValue = "true", true
@@ -69,17 +111,124 @@ func (p parser) parseCommandLine(args []string) (options map[string][]string, va
 			continue
 		}
 
-		if i++; i == len(args) || isOption(args[i]) {
-			err = &Usage{Err: fmt.Errorf("missing option value: %q", arg)}
-			return
+		if i+1 == len(args) || isOption(args[i+1]) {
+			errs = append(errs, &ErrMissingValue{Flag: arg})
+			continue
 		}
+		i++
 
 		options[name] = append(options[name], args[i])
 	}
 
+	if len(errs) != 0 {
+		err = &Usage{Err: joinErrors(errs)}
+	}
 	return
 }
 
+// repeatedFlag returns the option set by name when it repeats the letter of a
+// short flag which may be repeated, like -vvv for -v, and the number of times
+// it is repeated. Zero is returned if name is not such a flag.
+func (p parser) repeatedFlag(name string) (string, int) {
+	if len(name) < 3 || name[0] != '-' || name[1] == '-' || strings.Trim(name[1:], name[1:2]) != "" {
+		return "", 0
+	}
+	target := name[:2]
+	if alias, ok := p.aliases[target]; ok {
+		target = alias
+	}
+	if !p.options[target].repeated {
+		return "", 0
+	}
+	return target, len(name) - 1
+}
+
+// has returns true if name is an option or an alias of the parser.
+func (p parser) has(name string) bool {
+	if _, ok := p.options[name]; ok {
+		return true
+	}
+	_, ok := p.aliases[name]
+	return ok
+}
+
+// suggest returns the sorted list of flags which are the closest to the
+// unknown flag name, or nil if none of them are similar enough.
+func (p parser) suggest(name string) []string {
+	var matches []string
+	minDistance := -1
+
+	for _, flags := range []map[string]string{p.aliases, p.optionNames()} {
+		for flag, target := range flags {
+			if p.options[target].hidden {
+				continue
+			}
+			d := levenshtein(name, flag)
+			if !similarEnough(name, flag, d) || (minDistance >= 0 && d > minDistance) {
+				continue
+			}
+			if d != minDistance {
+				matches, minDistance = matches[:0], d
+			}
+			matches = append(matches, flag)
+		}
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+// expandAbbrev returns the long flag that name is a prefix of, or name itself
+// if it is not an abbreviation. An error is returned if more than one flag
+// starts with name. Hidden flags are not expanded, so they don't make the
+// abbreviations of visible flags ambiguous.
+func (p parser) expandAbbrev(name string) (string, error) {
+	if !isLongFlag(name) || p.has(name) {
+		return name, nil
+	}
+
+	var matches []string
+	for _, flags := range []map[string]string{p.aliases, p.optionNames()} {
+		for flag, target := range flags {
+			if p.options[target].hidden {
+				continue
+			}
+			if isLongFlag(flag) && strings.HasPrefix(flag, name) && !contains(matches, target) {
+				matches = append(matches, target)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", errorf("ambiguous option: %q could be %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// optionNames returns a map of the option names to themselves, which makes it
+// possible to treat them like the aliases when resolving abbreviations.
+func (p parser) optionNames() map[string]string {
+	names := make(map[string]string, len(p.options))
+	for name := range p.options {
+		names[name] = name
+	}
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
 func isOption(s string) bool {
 	return len(s) > 1 && s[0] == '-'
 }
@@ -96,11 +245,29 @@ func splitNameValue(s string) (string, string, bool) {
 	return s[:i], s[i+1:], true
 }
 
-func lookupEnv(name string, env []string) (string, bool) {
+func lookupEnv(name string, env []string, fold bool) (string, bool) {
 	for _, e := range env {
-		if k, v, _ := splitNameValue(e); k == name {
+		if k, v, _ := splitNameValue(e); envNameEqual(k, name, fold) {
 			return v, true
 		}
 	}
 	return "", false
 }
+
+// envNameEqual compares the names of environment variables, ignoring their
+// case if fold is true.
+func envNameEqual(a, b string, fold bool) bool {
+	if fold {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// cutEnvPrefix returns e without prefix, and true if e started with prefix,
+// ignoring the case of the prefix if fold is true.
+func cutEnvPrefix(e, prefix string, fold bool) (string, bool) {
+	if len(e) < len(prefix) || !envNameEqual(e[:len(prefix)], prefix, fold) {
+		return e, false
+	}
+	return e[len(prefix):], true
+}
@@ -38,3 +38,168 @@ func TestParseCommandLine(t *testing.T) {
 		t.Error("command mismatch:", command)
 	}
 }
+
+func TestParseCommandLineOptionsFirst(t *testing.T) {
+	parser := parser{
+		options: map[string]option{
+			"--bool": {boolean: true},
+		},
+		optionsFirst: true,
+	}
+
+	args := []string{
+		"--bool", "run", "--its-flag", "value", "--", "command",
+	}
+
+	options, values, command, err := parser.parseCommandLine(args)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(options, map[string][]string{
+		"--bool": {"true"},
+	}) {
+		t.Error("options mismatch:", options)
+	}
+
+	if !reflect.DeepEqual(values, []string{"run", "--its-flag", "value", "--", "command"}) {
+		t.Error("values mismatch:", values)
+	}
+
+	if len(command) != 0 {
+		t.Error("command mismatch:", command)
+	}
+}
+
+func TestParseCommandLineAbbrev(t *testing.T) {
+	parser := parser{
+		aliases: map[string]string{"--last-name": "--surname"},
+		options: map[string]option{
+			"--verbose": {boolean: true},
+			"--version": {boolean: true},
+			"--surname": {boolean: false},
+			"--name":    {boolean: false},
+		},
+		abbrev: true,
+	}
+
+	options, _, _, err := parser.parseCommandLine([]string{"--verb", "--last=Skywalker", "--name", "Luke"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(options, map[string][]string{
+		"--verbose": {"true"},
+		"--surname": {"Skywalker"},
+		"--name":    {"Luke"},
+	}) {
+		t.Error("options mismatch:", options)
+	}
+
+	_, _, _, err = parser.parseCommandLine([]string{"--ver"})
+	if err == nil {
+		t.Fatal("expected an error for an ambiguous abbreviation")
+	}
+	if msg := err.(*Usage).Err.Error(); msg != `ambiguous option: "--ver" could be --verbose, --version` {
+		t.Error("wrong error message:", msg)
+	}
+}
+
+func TestParseCommandLineAbbrevHidden(t *testing.T) {
+	parser := parser{
+		options: map[string]option{
+			"--help":         {boolean: true},
+			"--help-all":     {boolean: true, hidden: true},
+			"--help-verbose": {boolean: true, hidden: true},
+		},
+		abbrev: true,
+	}
+
+	options, _, _, err := parser.parseCommandLine([]string{"--he"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(options, map[string][]string{"--help": {"true"}}) {
+		t.Error("options mismatch:", options)
+	}
+}
+
+func TestParseCommandLineSingleDash(t *testing.T) {
+	parser := parser{
+		aliases: map[string]string{"-v": "--verbose"},
+		options: map[string]option{
+			"--verbose": {boolean: true},
+			"--name":    {boolean: false},
+		},
+		singleDash: true,
+	}
+
+	options, _, _, err := parser.parseCommandLine([]string{"-verbose", "-name=Luke", "-v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(options, map[string][]string{
+		"--verbose": {"true", "true"},
+		"--name":    {"Luke"},
+	}) {
+		t.Error("options mismatch:", options)
+	}
+
+	_, _, _, err = parser.parseCommandLine([]string{"-unknown"})
+	if msg := err.(*Usage).Err.Error(); msg != `unrecognized option: "-unknown"` {
+		t.Error("wrong error message:", msg)
+	}
+}
+
+func TestParseCommandLineRepeated(t *testing.T) {
+	parser := parser{
+		aliases: map[string]string{"-v": "--verbose"},
+		options: map[string]option{
+			"--verbose": {boolean: true, repeated: true},
+			"-d":        {boolean: true},
+		},
+	}
+
+	options, _, _, err := parser.parseCommandLine([]string{"-vv", "--verbose", "-v"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(options, map[string][]string{
+		"--verbose": {"true", "true", "true", "true"},
+	}) {
+		t.Error("options mismatch:", options)
+	}
+
+	for _, arg := range []string{"-dd", "-vd", "-vv=true"} {
+		if _, _, _, err := parser.parseCommandLine([]string{arg}); err == nil {
+			t.Errorf("%s: expected an error", arg)
+		}
+	}
+}
+
+func TestParserSuggest(t *testing.T) {
+	parser, _, _ := makeStructDecoder(reflect.TypeOf(struct {
+		Verbose bool   `flag:"-v,--verbose"`
+		Version bool   `flag:"--version"`
+		Secret  string `flag:"--secret" hidden:"true"`
+	}{}))
+
+	tests := []struct {
+		flag string
+		want []string
+	}{
+		{"--verbos", []string{"--verbose"}},
+		{"--versio", []string{"--version"}},
+		{"--verzion", []string{"--version"}},
+		{"--vers", nil},
+		{"--secrt", nil},
+		{"--nope", nil},
+	}
+
+	for _, test := range tests {
+		if got := parser.suggest(test.flag); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: wrong suggestions: got %q, want %q", test.flag, got, test.want)
+		}
+	}
+}
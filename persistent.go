@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Persistent returns a Function which parses the flags declared by the struct
+// pointed to by config before calling cmd, leaving the remaining arguments to
+// cmd. This is mostly useful to declare flags shared by all the commands of a
+// command set:
+//
+//	type globals struct {
+//		Region string `flag:"--region" help:"Region to send requests to" default:"us-west-2"`
+//	}
+//
+//	var g globals
+//
+//	cmd := cli.Persistent(&g, cli.CommandSet{
+//		"s3": cli.CommandSet{
+//			"ls": cli.Command(func(config config) {
+//				// g.Region is set when the command is called
+//				...
+//			}),
+//		},
+//	})
+//
+// The flags are recognized anywhere on the command line before the "--"
+// separator, for example both "prog --region us-east-1 s3 ls" and
+// "prog s3 ls --region us-east-1" are valid. They are loaded from the
+// environment, and assigned their default values, with the same rules as the
+// flags of commands created by Command. The help messages of cmd and its
+// sub-commands list them in a "Global Options" section.
+//
+// When the command that the arguments are dispatched to declares a flag of the
+// same name, the flag is left to the command and the global option keeps its
+// value from the environment or its default.
+//
+// The function panics if config is not a pointer to a struct.
+func Persistent(config interface{}, cmd Function) Function {
+	globals := assignCommand("cli.Persistent", config)
+	globals.configure()
+	return &persistentCommand{wrappedCommand{cmd}, globals}
+}
+
+type persistentCommand struct {
+	wrappedCommand
+	globals *CommandFunc
+}
+
+func (c *persistentCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	own, rest := c.split(args)
+
+	// When help is requested, the global options may be missing or invalid,
+	// which must not prevent the help message from being displayed.
+	if !helpRequested(rest) {
+		if _, err := c.globals.Call(ctx, own, env); err != nil {
+			if e, ok := callError(err).(*Usage); ok {
+				e.Cmd = c
+			}
+			return 1, err
+		}
+	}
+
+	code, err := c.cmd.Call(ctx, rest, env)
+	switch e := callError(err).(type) {
+	case *Help:
+		if e.Cmd == nil {
+			e.Cmd = c
+		} else {
+			e.Cmd = &globalOptions{wrappedCommand{e.Cmd}, c.globals}
+		}
+	case *Usage:
+		if e.Cmd == nil {
+			e.Cmd = c
+		} else {
+			e.Cmd = &globalOptions{wrappedCommand{e.Cmd}, c.globals}
+		}
+	}
+	return code, err
+}
+
+// split separates the flags of the global options (with their values) from
+// the other arguments. The flags which are also declared by the command that
+// args are dispatched to are left to the command, so global options never
+// shadow the options of commands.
+func (c *persistentCommand) split(args []string) (own, rest []string) {
+	p := c.globals.parser
+	target := c.target(args)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if isCommandSeparator(arg) {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, _, hasValue := splitNameValue(arg)
+		if alias, ok := p.aliases[name]; ok {
+			name = alias
+		}
+
+		flag := arg
+		option, ok := p.options[name]
+		if _, n := p.repeatedFlag(arg); n != 0 {
+			option.boolean, ok = true, true
+			flag = arg[:2]
+		}
+		if !isOption(arg) || !ok || name == "--help" || target.declares(flag) {
+			rest = append(rest, arg)
+			continue
+		}
+
+		own = append(own, arg)
+		if !option.boolean && !hasValue && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+
+	return own, rest
+}
+
+// target returns the command function that args are dispatched to, which is
+// found by following the names of sub-commands in args, or nil if it could
+// not be determined.
+func (c *persistentCommand) target(args []string) *CommandFunc {
+	cmd := c.cmd
+
+	for {
+		if fn := commandFuncOf(cmd); fn != nil {
+			fn.configure()
+			return fn
+		}
+
+		cmds := commandsOf(cmd)
+		if cmds == nil {
+			return nil
+		}
+
+		next := Function(nil)
+		for i, arg := range args {
+			if isCommandSeparator(arg) {
+				break
+			}
+			if sub, ok := cmds[arg]; ok && arg != "_" && !isOption(arg) {
+				next, args = sub, args[i+1:]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		cmd = next
+	}
+}
+
+// flag returns the option set by arg, which is a flag of cmd given on the
+// command line, and true if cmd declares it.
+func (cmd *CommandFunc) flag(arg string) (option, bool) {
+	p := cmd.parser
+	name, _, _ := splitNameValue(arg)
+	if alias, ok := p.aliases[name]; ok {
+		name = alias
+	}
+	if option, ok := p.options[name]; ok {
+		return option, true
+	}
+	if _, n := p.repeatedFlag(name); n != 0 {
+		return option{boolean: true, repeated: true}, true
+	}
+	return option{}, false
+}
+
+// declares returns true if flag is one of the options of cmd, which may be
+// nil.
+func (cmd *CommandFunc) declares(flag string) bool {
+	if cmd == nil {
+		return false
+	}
+	name, _, _ := splitNameValue(flag)
+	_, ok := cmd.option(name)
+	return ok
+}
+
+func (c *persistentCommand) Format(w fmt.State, v rune) {
+	(&globalOptions{c.wrappedCommand, c.globals}).Format(w, v)
+}
+
+// globalOptions is a wrapper for the commands of a Persistent command, which
+// adds the list of global options to their help messages.
+type globalOptions struct {
+	wrappedCommand
+	globals *CommandFunc
+}
+
+func (c *globalOptions) Format(w fmt.State, v rune) {
+	c.wrappedCommand.Format(w, v)
+
+	if v == 'v' && !w.Flag('#') {
+		options := make(structDecoder, len(c.globals.options))
+		for name, field := range c.globals.options {
+			switch name {
+			case "--help", "--help-all", "--help-verbose":
+			default:
+				options[name] = field
+			}
+		}
+		if len(options) != 0 {
+			io.WriteString(w, "\n"+colorsOf(w).bold(tr("Global Options:"))+"\n")
+			writeOptions(w, options, nil, showHidden(w), colorsOf(w), helpWidthOf(w))
+		}
+	}
+}
+
+// helpRequested returns true if args contain one of the help options before
+// the "--" separator.
+func helpRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "--help", "--help-all", "--help-verbose":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPersistentContext(t *testing.T) {
+	type globals struct {
+		Region string `flag:"--region" default:"us-west-2"`
+	}
+
+	type key struct{}
+	var g globals
+	var value interface{}
+
+	cmd := Persistent(&g, CommandSet{
+		"ls": Command(func(ctx context.Context) { value = ctx.Value(key{}) }),
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	if code := CallContext(ctx, cmd, "ls", "--region", "eu-west-1"); code != 0 {
+		t.Fatalf("wrong exit code: %d", code)
+	}
+	if g.Region != "eu-west-1" {
+		t.Errorf("wrong region: %q", g.Region)
+	}
+	if value != "value" {
+		t.Errorf("the context was not passed to the command: %v", value)
+	}
+}
+
+func TestPersistentSharedFlag(t *testing.T) {
+	type globals struct {
+		Verbose bool   `flag:"-v,--verbose"`
+		Region  string `flag:"--region" default:"us-west-2"`
+	}
+
+	type config struct {
+		Version string `flag:"-v,--version" default:"-"`
+	}
+
+	var g globals
+	var version string
+
+	cmd := Persistent(&g, CommandSet{
+		"ls":  Command(func(config config) { version = config.Version }),
+		"get": Command(func(struct{}) {}),
+	})
+
+	// The command declares -v, so the flag is left to it.
+	if _, err := CallErr(context.TODO(), cmd, "--region", "eu-west-1", "ls", "-v", "1.2"); err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.2" || g.Verbose || g.Region != "eu-west-1" {
+		t.Errorf("wrong configuration: version=%q globals=%+v", version, g)
+	}
+
+	// Other commands still receive the global -v.
+	g = globals{}
+	if _, err := CallErr(context.TODO(), cmd, "get", "-v"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Verbose {
+		t.Error("the global -v flag was not set")
+	}
+}
+
+func TestVerbosityFlagsSharedFlag(t *testing.T) {
+	type config struct {
+		Value string `flag:"-v,--value"`
+	}
+
+	var value string
+	var level int
+	cmd := CommandSet{
+		"set": Command(func(ctx context.Context, config config) {
+			value, level = config.Value, Verbosity(ctx)
+		}),
+	}
+
+	code := call(context.TODO(), cmd, []string{"set", "-v", "x"}, makeExecOptions([]ExecOption{WithVerbosityFlags()}))
+	if code != 0 {
+		t.Fatalf("wrong exit code: %d", code)
+	}
+	if value != "x" || level != 0 {
+		t.Errorf("wrong values: value=%q level=%d", value, level)
+	}
+}
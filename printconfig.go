@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// WithPrintConfigFlag adds the --print-config option to the program, which
+// prints the configuration that the command would be called with, once the
+// values of its options were loaded from the command line, the environment,
+// configuration files, and defaults, then exits without calling the command.
+// Each option is listed with its value, and where the value came from, which
+// helps understanding why a value is not taking effect:
+//
+//	$ PROG_NAME=Luke prog --print-config
+//	--name     Luke       (from environment variable PROG_NAME)
+//	--token    ******     (from config file)
+//	--verbose  (not set)
+//
+// The values of options with the "secret" tag are masked. The required options
+// which were not set are listed as such, instead of resulting in usage errors.
+func WithPrintConfigFlag() ExecOption {
+	return func(o *execOptions) { o.printConfig = true }
+}
+
+type printConfigKey struct{}
+
+type printConfigConfig struct {
+	PrintConfig bool `flag:"--print-config" help:"Print the resolved configuration of the command and exit"`
+}
+
+// withPrintConfigFlag returns a version of cmd which supports the
+// --print-config option.
+func withPrintConfigFlag(cmd Function) Function {
+	config := new(printConfigConfig)
+	return Persistent(config, Wrap(cmd, func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			if config.PrintConfig {
+				ctx = withValue(ctx, printConfigKey{}, true)
+			}
+			return next.Call(ctx, args, env)
+		})
+	}))
+}
+
+// printConfigOf returns true if the configuration of the command called with
+// ctx must be printed instead of calling the command.
+func printConfigOf(ctx context.Context) bool {
+	if ctx != nil {
+		printConfig, _ := ctx.Value(printConfigKey{}).(bool)
+		return printConfig
+	}
+	return false
+}
+
+// printConfig writes the values of the options of cmd to w, with their
+// sources, in the order of the help message.
+func (cmd *CommandFunc) printConfig(ctx context.Context, w io.Writer, options map[string][]string, info FlagInfo, env []string) {
+	cw := newColumnWriter(w)
+	defer cw.Flush()
+
+	for _, fieldName := range sortedMapKeys(reflect.ValueOf(cmd.options)) {
+		name := fieldName.String()
+		switch name {
+		case "--help", "--help-all", "--help-verbose":
+			continue
+		}
+
+		if value, source, ok := cmd.valueSource(ctx, name, options, info, env); ok {
+			fmt.Fprintf(cw, "%s\t  %s\t  (%s)\n", name, value, fmt.Sprintf(tr("from %s"), source))
+		} else {
+			fmt.Fprintf(cw, "%s\t  %s\t\n", name, tr("(not set)"))
+		}
+	}
+}
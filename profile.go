@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	rtrace "runtime/trace"
+)
+
+// WithProfilingFlags adds the hidden --cpuprofile, --memprofile, and --trace
+// options to the program, which write a CPU profile, a heap profile, and an
+// execution trace of the command to the files that they are set to. The files
+// can be analyzed with "go tool pprof" and "go tool trace":
+//
+//	$ prog --cpuprofile=cpu.out ...
+//	$ go tool pprof cpu.out
+//
+// The profiles cover the execution of the command, the heap profile being
+// written after it returned. The options are hidden from the help messages,
+// unless the help is shown with --help-all.
+func WithProfilingFlags() ExecOption {
+	return func(o *execOptions) { o.profiling = true }
+}
+
+type profilingConfig struct {
+	CPUProfile string `flag:"--cpuprofile" help:"Write a CPU profile of the command to a file" default:"-" hidden:"true"`
+	MemProfile string `flag:"--memprofile" help:"Write a heap profile to a file after the command returned" default:"-" hidden:"true"`
+	Trace      string `flag:"--trace"      help:"Write an execution trace of the command to a file" default:"-" hidden:"true"`
+}
+
+// withProfilingFlags returns a version of cmd which supports the profiling
+// options.
+func withProfilingFlags(cmd Function) Function {
+	config := new(profilingConfig)
+	return Persistent(config, Wrap(cmd, func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (code int, err error) {
+			stop, err := startProfiling(config)
+			if err != nil {
+				return 1, err
+			}
+			defer func() {
+				if e := stop(); e != nil && err == nil {
+					code, err = 1, e
+				}
+			}()
+			return next.Call(ctx, args, env)
+		})
+	}))
+}
+
+// startProfiling starts the profiles requested in config, returning a function
+// which stops them and writes the heap profile.
+func startProfiling(config *profilingConfig) (stop func() error, err error) {
+	var stops []func() error
+	stopAll := func() error {
+		var errs []error
+		for i := len(stops) - 1; i >= 0; i-- {
+			if err := stops[i](); err != nil {
+				errs = appendErrors(errs, err)
+			}
+		}
+		return joinErrors(errs)
+	}
+	defer func() {
+		if err != nil {
+			stopAll()
+		}
+	}()
+
+	if config.CPUProfile != "" {
+		f, err := os.Create(config.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("--cpuprofile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("--cpuprofile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+
+	if config.Trace != "" {
+		f, err := os.Create(config.Trace)
+		if err != nil {
+			return nil, fmt.Errorf("--trace: %w", err)
+		}
+		if err := rtrace.Start(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("--trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			rtrace.Stop()
+			return f.Close()
+		})
+	}
+
+	if config.MemProfile != "" {
+		f, err := os.Create(config.MemProfile)
+		if err != nil {
+			return nil, fmt.Errorf("--memprofile: %w", err)
+		}
+		stops = append(stops, func() error {
+			runtime.GC() // get up-to-date statistics
+			err := pprof.WriteHeapProfile(f)
+			if e := f.Close(); err == nil {
+				err = e
+			}
+			if err != nil {
+				return fmt.Errorf("--memprofile: %w", err)
+			}
+			return nil
+		})
+	}
+
+	return stopAll, nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfilingFlags(t *testing.T) {
+	dir := t.TempDir()
+	cpu := filepath.Join(dir, "cpu.out")
+	mem := filepath.Join(dir, "mem.out")
+	trace := filepath.Join(dir, "trace.out")
+
+	called := false
+	cmd := NamedCommand("prog", Command(func(struct{}) { called = true }))
+	options := makeExecOptions([]ExecOption{WithProfilingFlags()})
+
+	args := []string{"--cpuprofile", cpu, "--memprofile", mem, "--trace", trace}
+	if code := call(context.TODO(), cmd, args, options); code != 0 {
+		t.Fatalf("wrong exit code: %d", code)
+	}
+	if !called {
+		t.Error("the command was not called")
+	}
+
+	for _, path := range []string{cpu, mem, trace} {
+		if info, err := os.Stat(path); err != nil {
+			t.Error(err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s: the profile is empty", filepath.Base(path))
+		}
+	}
+}
+
+func TestProfilingFlagsError(t *testing.T) {
+	cmd := NamedCommand("prog", Command(func(struct{}) {
+		t.Error("the command was called despite the profiling error")
+	}))
+	options := makeExecOptions([]ExecOption{WithProfilingFlags(), WithStderr(io.Discard)})
+
+	path := filepath.Join(t.TempDir(), "missing", "cpu.out")
+	if code := call(context.TODO(), cmd, []string{"--cpuprofile", path}, options); code != 1 {
+		t.Errorf("wrong exit code: %d", code)
+	}
+}
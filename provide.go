@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Provide registers a function providing values of its return type to the
+// command functions which accept parameters of that type, for example:
+//
+//	func init() {
+//		cli.Provide(func(ctx context.Context) (*sql.DB, error) {
+//			return sql.Open("postgres", os.Getenv("DATABASE_URL"))
+//		})
+//	}
+//
+//	cmd := cli.Command(func(config config, db *sql.DB) error {
+//		...
+//	})
+//
+// The provider may accept a context.Context, which is the one the command is
+// called with, and may return an error after the value, which aborts the call
+// to the command. Providers are called each time a command needing their value
+// is called, after its arguments were successfully parsed.
+//
+// Parameters of provided types do not consume positional arguments, which means
+// that providers must be registered before the commands using them are called
+// for the first time, usually in init functions. Registering a provider for a
+// type which already had one replaces it.
+//
+// The function panics if provider is not a function with one of the supported
+// signatures.
+func Provide(provider interface{}) {
+	if provider == nil {
+		panic("cli.Provide: expected a function as argument but got nil")
+	}
+
+	v := reflect.ValueOf(provider)
+	t := v.Type()
+
+	if t.Kind() != reflect.Func {
+		panic("cli.Provide: expected a function as argument but got " + t.String())
+	}
+
+	switch t.NumIn() {
+	case 0:
+	case 1:
+		if t.In(0) != contextType {
+			panic("cli.Provide: the provider may only accept a context.Context but got " + t.String())
+		}
+	default:
+		panic("cli.Provide: the provider may only accept a context.Context but got " + t.String())
+	}
+
+	switch t.NumOut() {
+	case 1:
+	case 2:
+		if t.Out(1) != errorType {
+			panic("cli.Provide: expected a function returning (T) or (T, error) but got " + t.String())
+		}
+	default:
+		panic("cli.Provide: expected a function returning (T) or (T, error) but got " + t.String())
+	}
+
+	if r := t.Out(0); r == errorType || r == contextType || r == flagInfoType || r == streamsType {
+		panic("cli.Provide: values of type " + r.String() + " cannot be provided")
+	}
+
+	providers.Store(t.Out(0), v)
+}
+
+var providers sync.Map // reflect.Type => reflect.Value
+
+// providerOf returns the provider of values of type t, or nil if there are
+// none.
+func providerOf(t reflect.Type) *reflect.Value {
+	if v, ok := providers.Load(t); ok {
+		f := v.(reflect.Value)
+		return &f
+	}
+	return nil
+}
+
+// provide calls the provider of values of type t.
+func provide(ctx context.Context, t reflect.Type) (reflect.Value, error) {
+	f := providerOf(t)
+	if f == nil {
+		panic("cli: no providers for values of type " + t.String())
+	}
+
+	var in []reflect.Value
+	if f.Type().NumIn() != 0 {
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+	}
+
+	out := f.Call(in)
+	if len(out) == 2 {
+		if err, _ := out[1].Interface().(error); err != nil {
+			return reflect.Value{}, fmt.Errorf("providing %s: %w", t, err)
+		}
+	}
+	return out[0], nil
+}
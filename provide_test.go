@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type testService struct{ name string }
+
+type testFailingService struct{}
+
+func TestProvide(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	Err = io.Discard
+
+	calls := 0
+	provideForTest(t, func(ctx context.Context) *testService {
+		calls++
+		return &testService{name: "test"}
+	})
+	provideForTest(t, func() (*testFailingService, error) {
+		return nil, errors.New("unavailable")
+	})
+
+	type config struct {
+		Flag string `flag:"--flag"`
+	}
+
+	var name string
+	cmd := Command(func(config config, s *testService, arg string) {
+		name = s.name + ":" + arg
+	})
+
+	if code := Call(cmd, "--flag", "value", "A"); code != 0 {
+		t.Fatal("wrong exit code:", code)
+	}
+	if name != "test:A" {
+		t.Error("wrong service name:", name)
+	}
+
+	if code := Call(cmd, "A"); code == 0 {
+		t.Error("expected the call to fail with a missing flag")
+	}
+	if calls != 1 {
+		t.Error("the provider was called when the arguments were invalid")
+	}
+
+	failing := Command(func(config config, s *testFailingService) {
+		t.Error("the command was called when the provider failed")
+	})
+	if code := Call(failing, "--flag", "value"); code != 1 {
+		t.Error("wrong exit code:", code)
+	}
+}
+
+func TestProvidePanics(t *testing.T) {
+	for _, provider := range []interface{}{
+		nil,
+		"hello",
+		func(int) string { return "" },
+		func() {},
+		func() (string, int) { return "", 0 },
+		func() FlagInfo { return FlagInfo{} },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%T: expected a panic", provider)
+				}
+			}()
+			Provide(provider)
+		}()
+	}
+}
+
+// provideForTest registers provider for the duration of the test t, restoring
+// the previous provider of its type when the test ends, so the registry shared
+// by the package does not leak providers between tests.
+func provideForTest(t *testing.T, provider interface{}) {
+	typ := reflect.TypeOf(provider).Out(0)
+	prev, hadPrev := providers.Load(typ)
+	Provide(provider)
+	t.Cleanup(func() {
+		if hadPrev {
+			providers.Store(typ, prev)
+		} else {
+			providers.Delete(typ)
+		}
+	})
+}
+
+func TestProvideCleanup(t *testing.T) {
+	t.Run("register", func(t *testing.T) {
+		provideForTest(t, func() *testService { return &testService{} })
+		if providerOf(reflect.TypeOf(&testService{})) == nil {
+			t.Error("the provider was not registered")
+		}
+	})
+	if providerOf(reflect.TypeOf(&testService{})) != nil {
+		t.Error("the provider outlived the test which registered it")
+	}
+}
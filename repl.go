@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReplOption is the type of functional options accepted by Repl.
+type ReplOption func(*replOptions)
+
+type replOptions struct {
+	prompt  string
+	streams Streams
+}
+
+// ReplPrompt sets the prompt printed by Repl before reading each line. The
+// default prompt is "> ".
+func ReplPrompt(prompt string) ReplOption {
+	return func(o *replOptions) { o.prompt = prompt }
+}
+
+// ReplStreams sets the streams that Repl reads lines from and writes outputs
+// to, instead of the standard streams of the process. The streams are also
+// passed to the commands accepting a Streams parameter.
+func ReplStreams(s Streams) ReplOption {
+	return func(o *replOptions) { o.streams = s }
+}
+
+// Repl runs an interactive session reading command lines from stdin, and
+// dispatching them to cmds. Lines are split into arguments following the
+// quoting rules of POSIX shells. This is useful to build administration
+// consoles on the same commands as a program:
+//
+//	err := cli.Repl(ctx, cli.CommandSet{
+//		"status": status,
+//		"reload": reload,
+//	}, cli.ReplPrompt("admin> "))
+//
+// The session supports two built-in commands (unless cmds has commands of the
+// same names): "help" prints the help of cmds, or the help of a command when
+// followed by its name, and "exit" terminates the session. Errors returned by
+// the commands are printed to stderr and do not terminate the session.
+//
+// Like CallContext, commands must accept a context.Context as first argument
+// when ctx is not context.TODO(). The commands receive the environment
+// variables prefixed with the prefix of cmds, if it has one.
+//
+// Repl returns nil when the session was terminated by "exit" or by reaching the
+// end of stdin, or the error which occurred reading the input.
+func Repl(ctx context.Context, cmds CommandSet, options ...ReplOption) error {
+	exec := *execOptionsOf(ctx)
+	o := replOptions{prompt: "> ", streams: exec.streams}
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	exec.streams = o.streams
+	o.streams = exec.programStreams()
+	exec.streams = o.streams
+	ctx = context.WithValue(ctx, execOptionsKey{}, &exec)
+
+	var env []string
+	if prefix, ok := cmds.envPrefix(); ok {
+		if env = exec.env; env == nil {
+			env = os.Environ()
+		}
+		env = environ(env, prefix, exec.caseInsensitiveEnv())
+	}
+
+	input := bufio.NewScanner(o.streams.Stdin)
+
+	for {
+		io.WriteString(o.streams.Stdout, o.prompt)
+
+		if !input.Scan() {
+			io.WriteString(o.streams.Stdout, "\n")
+			return input.Err()
+		}
+
+		args, err := splitArgs(input.Text())
+		if err != nil {
+			fmt.Fprintln(o.streams.Stderr, err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		if _, exists := cmds[args[0]]; !exists {
+			switch args[0] {
+			case "exit":
+				return nil
+			case "help":
+				args = append(args[1:], "--help")
+			}
+		}
+
+		switch _, err := cmds.Call(ctx, args, env); e := callError(err).(type) {
+		case nil:
+		case *Help:
+			fmt.Fprintln(o.streams.Stdout, strings.TrimLeft(fmt.Sprint(e), "\n"))
+		case *Usage:
+			fmt.Fprintln(o.streams.Stderr, e)
+		default:
+			fmt.Fprintf(o.streams.Stderr, "%s: %s\n", tr("error"), e)
+		}
+	}
+}
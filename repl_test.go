@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReplCommandPath(t *testing.T) {
+	o := makeExecOptions(nil)
+	ctx := context.WithValue(context.TODO(), execOptionsKey{}, o)
+
+	Repl(ctx, CommandSet{
+		"status": Command(func(struct{}) {}),
+	}, ReplStreams(Streams{
+		Stdin:  strings.NewReader("status\n"),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}))
+
+	// The signal handler of the program reads the path from its options, so
+	// it must see the commands run by the session.
+	if path := o.commandPath(); !reflect.DeepEqual(path, []string{"status"}) {
+		t.Errorf("wrong command path: %q", path)
+	}
+}
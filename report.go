@@ -0,0 +1,52 @@
+package cli
+
+import "time"
+
+// Report carries the outcome of the execution of a program, which is passed
+// to the function set with WithReporter.
+type Report struct {
+	// The path of the commands which were dispatched to, starting with the
+	// program name, for example []string{"prog", "sub", "command"}.
+	Path []string
+
+	// The duration of the execution.
+	Duration time.Duration
+
+	// The exit code of the program.
+	Code int
+
+	// The error returned by the command, or nil if it succeeded.
+	Err error
+
+	// The category of the error: empty if there were no errors, "help" when
+	// the help was requested, "usage" when the command line was invalid, and
+	// "error" when the command failed, or "panic" when it panicked and panics
+	// were recovered (see WithPanicRecovery).
+	Category string
+}
+
+// WithReporter sets a function called with a report of the execution of the
+// program after the command returned, which may be used to feed usage
+// analytics or metrics without wrapping every command:
+//
+//	cli.ExecWith(cmd, cli.WithReporter(func(r cli.Report) {
+//		stats.Observe(strings.Join(r.Path, "."), r.Duration, "code", strconv.Itoa(r.Code))
+//	}))
+func WithReporter(reporter func(Report)) ExecOption {
+	return func(o *execOptions) { o.reporter = reporter }
+}
+
+func errorCategoryOf(err error) string {
+	switch callError(err).(type) {
+	case nil:
+		return ""
+	case *Help:
+		return "help"
+	case *Usage:
+		return "usage"
+	case *panicError:
+		return "panic"
+	default:
+		return "error"
+	}
+}
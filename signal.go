@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WithSignals makes the program stop gracefully when it receives one of the
+// signals, which default to os.Interrupt and SIGTERM: the first signal cancels
+// the context passed to the command, giving it a chance to clean up before it
+// returns. If a second signal is received before the command returned, the
+// program prints "force quit" to its standard error and exits immediately,
+// with the exit code 128 plus the signal number (130 for Ctrl-C), or through
+// the function set with WithExit. The time that commands have to return after
+// the first signal may be bounded with WithShutdownTimeout.
+//
+// Only commands which accept a context.Context as first argument, and honor its
+// cancellation, are stopped by the first signal.
+func WithSignals(signals ...os.Signal) ExecOption {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return func(o *execOptions) { o.signals = signals }
+}
+
+// shutdownExitCode is the exit code of programs whose command did not return
+// within the shutdown timeout, it is the code used by timeout(1).
+const shutdownExitCode = 124
+
+// WithShutdownTimeout bounds the time that commands have to return after the
+// first signal set with WithSignals canceled their context. When the timeout
+// expires, the program prints the command which is still running to its
+// standard error and exits with code 124, or through the function set with
+// WithExit. This helps stopping before environments which follow SIGTERM with
+// SIGKILL, like CI systems and container orchestrators, kill the program.
+//
+// The option enables the handling of the default signals when WithSignals was
+// not used.
+func WithShutdownTimeout(timeout time.Duration) ExecOption {
+	return func(o *execOptions) {
+		o.shutdownTimeout = timeout
+		if o.signals == nil {
+			WithSignals()(o)
+		}
+	}
+}
+
+// notifySignals returns a context which is canceled when the program receives
+// one of the signals set in o, and a function releasing the resources of the
+// signal handler, which must be called when the command returned.
+func notifySignals(ctx context.Context, o *execOptions) (context.Context, func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, o.signals...)
+	ctx, stop := handleSignals(ctx, o, ch)
+	return ctx, func() {
+		signal.Stop(ch)
+		stop()
+	}
+}
+
+// handleSignals cancels the returned context on the first value received from
+// signals, and force quits the program on the second one. Signals are not
+// handled anymore after the returned function was called.
+func handleSignals(ctx context.Context, o *execOptions, signals <-chan os.Signal) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done, exited := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-signals:
+			cancel()
+		case <-done:
+			return
+		}
+
+		var timeout <-chan time.Time
+		if o.shutdownTimeout > 0 {
+			timer := time.NewTimer(o.shutdownTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		_, stderr := o.outputs()
+		select {
+		case sig := <-signals:
+			fmt.Fprintln(stderr, tr("force quit"))
+			o.exitProgram(signalExitCode(sig))
+		case <-timeout:
+			name := tr("the command")
+			if path := o.commandPath(); len(path) != 0 {
+				name = strconv.Quote(strings.Join(path, " "))
+			}
+			fmt.Fprintf(stderr, tr("shutdown timeout: %s is still running after %s")+"\n", name, o.shutdownTimeout)
+			o.exitProgram(shutdownExitCode)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		close(done)
+		<-exited
+		cancel()
+	}
+}
+
+// exitProgram terminates the program with code, using the function set with
+// WithExit if there was one.
+func (o *execOptions) exitProgram(code int) {
+	if o.exit != nil {
+		o.exit(code)
+	} else {
+		os.Exit(code)
+	}
+}
+
+// signalExitCode returns the exit code of programs terminated by sig, which is
+// 128 plus the signal number by convention of shells.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
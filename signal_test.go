@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	stderr := new(strings.Builder)
+	exit := make(chan int, 1)
+	o := &execOptions{stderr: stderr, exit: func(code int) { exit <- code }}
+
+	signals := make(chan os.Signal)
+	ctx, stop := handleSignals(context.Background(), o, signals)
+	defer stop()
+
+	signals <- os.Interrupt
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the context was not canceled by the first signal")
+	}
+
+	select {
+	case code := <-exit:
+		t.Fatalf("the program exited after the first signal with code %d", code)
+	default:
+	}
+
+	signals <- syscall.SIGTERM
+	select {
+	case code := <-exit:
+		if code != 143 {
+			t.Errorf("wrong exit code: %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the program did not exit on the second signal")
+	}
+
+	if s := stderr.String(); s != "force quit\n" {
+		t.Errorf("wrong message: %q", s)
+	}
+}
+
+func TestHandleSignalsStop(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	ctx, stop := handleSignals(context.Background(), &execOptions{}, signals)
+	stop()
+
+	if ctx.Err() == nil {
+		t.Error("the context was not canceled when stopping the signal handler")
+	}
+	// The handler is not listening anymore, so the signal remains buffered.
+	signals <- os.Interrupt
+	time.Sleep(10 * time.Millisecond)
+	if len(signals) != 1 {
+		t.Error("the signal was received after stopping the signal handler")
+	}
+}
+
+func TestHandleSignalsShutdownTimeout(t *testing.T) {
+	stderr := new(strings.Builder)
+	exit := make(chan int, 1)
+	o := &execOptions{stderr: stderr, exit: func(code int) { exit <- code }, path: new(atomic.Value)}
+	WithShutdownTimeout(10 * time.Millisecond)(o)
+	o.path.Store([]string{"prog", "deploy"})
+
+	signals := make(chan os.Signal)
+	_, stop := handleSignals(context.Background(), o, signals)
+	defer stop()
+
+	signals <- os.Interrupt
+	select {
+	case code := <-exit:
+		if code != shutdownExitCode {
+			t.Errorf("wrong exit code: %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the program did not exit after the shutdown timeout")
+	}
+
+	const want = "shutdown timeout: \"prog deploy\" is still running after 10ms\n"
+	if s := stderr.String(); s != want {
+		t.Errorf("wrong message: %q", s)
+	}
+}
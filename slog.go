@@ -0,0 +1,66 @@
+//go:build go1.21
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WithLogging adds the --log-level and --log-format options to the program,
+// which configure a *slog.Logger writing to stderr. The logger is attached to
+// the context passed to the command, and retrieved with Logger:
+//
+//	cmd := cli.Command(func(ctx context.Context, config config) {
+//		cli.Logger(ctx).Info("starting", "name", config.Name)
+//	})
+//
+//	cli.ExecWith(cmd, cli.WithLogging())
+//
+// The log level is one of debug, info (the default), warn, or error, and the
+// format is either text (the default) or json.
+func WithLogging() ExecOption {
+	return func(o *execOptions) { o.middleware = append(o.middleware, withLogging) }
+}
+
+// Logger returns the logger attached to ctx by the program, or the default
+// logger of the slog package if there are none.
+func Logger(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+type loggerKey struct{}
+
+type loggingConfig struct {
+	LogLevel  slog.Level `flag:"--log-level"  help:"Minimum level of log messages (debug, info, warn, error)" default:"info"`
+	LogFormat string     `flag:"--log-format" help:"Format of log messages (text, json)"                      default:"text"`
+}
+
+// withLogging returns a version of cmd which supports the logging options.
+func withLogging(cmd Function) Function {
+	config := new(loggingConfig)
+	return Persistent(config, Wrap(cmd, func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			w := streamsOf(ctx).Stderr
+			o := &slog.HandlerOptions{Level: config.LogLevel}
+
+			var handler slog.Handler
+			switch config.LogFormat {
+			case "text":
+				handler = slog.NewTextHandler(w, o)
+			case "json":
+				handler = slog.NewJSONHandler(w, o)
+			default:
+				return 1, &Usage{Err: fmt.Errorf("unsupported log format: %q", config.LogFormat)}
+			}
+
+			return next.Call(withValue(ctx, loggerKey{}, slog.New(handler)), args, env)
+		})
+	}))
+}
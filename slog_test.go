@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCallLogging(t *testing.T) {
+	cmd := NamedCommand("prog", Command(func(ctx context.Context) {
+		Logger(ctx).Debug("debug message")
+		Logger(ctx).Info("info message")
+	}))
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"level=INFO msg=\"info message\""}},
+		{[]string{"--log-level", "debug"}, []string{"level=DEBUG msg=\"debug message\"", "level=INFO msg=\"info message\""}},
+		{[]string{"--log-level=warn"}, nil},
+		{[]string{"--log-format", "json"}, []string{`"level":"INFO","msg":"info message"`}},
+	}
+
+	for _, test := range tests {
+		stderr := new(bytes.Buffer)
+		options := []ExecOption{WithLogging(), WithStreams(Streams{Stderr: stderr})}
+
+		if code := call(context.TODO(), cmd, test.args, makeExecOptions(options)); code != 0 {
+			t.Errorf("%q: wrong exit code: %d", test.args, code)
+		}
+
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if len(test.want) == 0 {
+			if stderr.Len() != 0 {
+				t.Errorf("%q: unexpected output: %q", test.args, stderr.String())
+			}
+			continue
+		}
+		if len(lines) != len(test.want) {
+			t.Errorf("%q: wrong output: %q", test.args, stderr.String())
+			continue
+		}
+		for i, want := range test.want {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("%q: line %q does not contain %q", test.args, lines[i], want)
+			}
+		}
+	}
+}
+
+func TestLoggerDefault(t *testing.T) {
+	if Logger(context.TODO()) != slog.Default() {
+		t.Error("the default logger was not returned when none were attached to the context")
+	}
+}
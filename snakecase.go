@@ -1,5 +1,7 @@
 package cli
 
+import "strings"
+
 func snakecase(s string) string {
 	b := make([]byte, 0, 64)
 	i := len(s) - 1
@@ -72,3 +74,7 @@ func isUpper(c byte) bool {
 func isLower(c byte) bool {
 	return c >= 'a' && c <= 'z'
 }
+
+func kebabcase(s string) string {
+	return strings.ReplaceAll(strings.ToLower(snakecase(s)), "_", "-")
+}
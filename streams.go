@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Streams carries the standard input and outputs of a program.
+//
+// Command functions may accept a parameter of type Streams, which does not
+// consume positional arguments, to read and write through the streams that
+// the program was called with instead of using os.Stdin, os.Stdout, and
+// os.Stderr directly. This makes the commands testable, since the streams can
+// be replaced with the WithStreams option:
+//
+//	cmd := cli.Command(func(config config, s cli.Streams) {
+//		fmt.Fprintln(s.Stdout, "Hello World!")
+//	})
+//
+//	var out bytes.Buffer
+//	cli.CallWith(cmd, nil, cli.WithStreams(cli.Streams{Stdout: &out}))
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// WithStreams sets the streams passed to commands accepting a Streams
+// parameter. The nil fields of s default to os.Stdin and to the outputs of the
+// program (see WithStdout and WithStderr).
+func WithStreams(s Streams) ExecOption {
+	return func(o *execOptions) { o.streams = s }
+}
+
+// CallWith is like Call, but accepts a list of options to configure how the
+// command is called.
+func CallWith(cmd Function, args []string, options ...ExecOption) int {
+	return call(context.TODO(), cmd, args, makeExecOptions(options))
+}
+
+// streamsOf returns the streams of the program called with ctx.
+func streamsOf(ctx context.Context) Streams {
+	return execOptionsOf(ctx).programStreams()
+}
+
+// programStreams returns the streams set with WithStreams, where the nil
+// outputs are replaced by the outputs of the program.
+func (o *execOptions) programStreams() Streams {
+	s := o.streams
+	if s.Stdout == nil {
+		s.Stdout = o.stdout
+	}
+	if s.Stderr == nil {
+		s.Stderr = o.stderr
+	}
+	return s.withDefaults()
+}
+
+// withDefaults returns a copy of s where the nil streams are replaced by the
+// standard input of the process and the outputs of the package.
+func (s Streams) withDefaults() Streams {
+	if s.Stdin == nil {
+		s.Stdin = os.Stdin
+	}
+	if s.Stdout == nil {
+		s.Stdout = Stdout()
+	}
+	if s.Stderr == nil {
+		s.Stderr = Stderr()
+	}
+	return s
+}
+
+var streamsType = reflect.TypeOf(Streams{})
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minWrapWidth is the narrowest column that help text is wrapped to; text is
+// left on long lines rather than being squeezed in narrower columns.
+const minWrapWidth = 20
+
+// helpWidthOf returns the width that the help messages formatted with w are
+// wrapped to, which is passed as the width of the verb, as in "%80v". Zero is
+// returned when no width was set, in which case the text is not wrapped.
+func helpWidthOf(w fmt.State) int {
+	n, _ := w.Width()
+	return n
+}
+
+// helpWidth returns the width that the help messages printed to w are wrapped
+// to. When w is a terminal, the COLUMNS environment variable takes precedence
+// over the size of the terminal. Zero is returned when w is not a terminal,
+// unless the environment of the program was set with WithEnv and defines
+// COLUMNS, which lets programs choose the width of their help messages.
+func (o *execOptions) helpWidth(w io.Writer) int {
+	env := o.env
+	if env == nil {
+		if !isTerminal(w) {
+			return 0
+		}
+		env = os.Environ()
+	}
+	if v, ok := lookupEnv("COLUMNS", env, o.caseInsensitiveEnv()); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	if f, ok := w.(*os.File); ok {
+		return ioctlWidth(f)
+	}
+	return 0
+}
+
+// wrapText splits s in lines of at most width columns, breaking lines
+// between words. Line breaks in s are preserved, and words longer than width
+// are left on their own line. The text is only split on line breaks when the
+// width is narrower than minWrapWidth.
+func wrapText(s string, width int) []string {
+	if s == "" {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if width < minWrapWidth || displayWidth(line) <= width {
+			lines = append(lines, line)
+			continue
+		}
+
+		// Leading spaces are kept so indented lines remain indented.
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		words := strings.Fields(line)
+		b := new(strings.Builder)
+		b.WriteString(indent)
+		n := displayWidth(indent)
+
+		for i, word := range words {
+			wordLen := displayWidth(word)
+			if i != 0 {
+				if n+1+wordLen > width {
+					lines = append(lines, b.String())
+					b.Reset()
+					b.WriteString(indent)
+					n = displayWidth(indent)
+				} else {
+					b.WriteByte(' ')
+					n++
+				}
+			}
+			b.WriteString(word)
+			n += wordLen
+		}
+
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// displayWidth returns the number of columns that s occupies when printed to a
+// terminal. Wide characters, like CJK ideographs and most emoji, take two
+// columns, while combining marks, zero-width characters, and ANSI escape
+// sequences take none.
+func displayWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			// Skip the parameters of the sequence up to its final byte.
+			for i += 2; i < len(s) && (s[i] < '@' || s[i] > '~'); i++ {
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n += runeWidth(r)
+		i += size - 1
+	}
+	return n
+}
+
+// runeWidth returns the number of columns that r occupies on a terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200B, unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWideRune(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRunes are the ranges of characters displayed on two columns, which are
+// the East Asian wide and fullwidth characters, and the emoji presentation
+// characters of the Unicode standard.
+var wideRunes = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x23E9, 0x23EC},   // media controls
+	{0x23F0, 0x23F3},   // alarm clock, timers
+	{0x25FD, 0x25FE},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac signs
+	{0x26A1, 0x26A1},   // high voltage
+	{0x26AA, 0x26AB},   // circles
+	{0x26BD, 0x26BE},   // soccer ball, baseball
+	{0x26C4, 0x26C5},   // snowman, sun behind cloud
+	{0x26D4, 0x26D4},   // no entry
+	{0x26EA, 0x26EA},   // church
+	{0x26F2, 0x26F5},   // fountain, golf, sailboat
+	{0x26FA, 0x26FD},   // tent, fuel pump
+	{0x2705, 0x2705},   // check mark button
+	{0x270A, 0x270B},   // raised fist, raised hand
+	{0x2728, 0x2728},   // sparkles
+	{0x274C, 0x274C},   // cross mark
+	{0x2753, 0x2755},   // question and exclamation marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // plus, minus, divide
+	{0x27B0, 0x27B0},   // curly loop
+	{0x27BF, 0x27BF},   // double curly loop
+	{0x2B1B, 0x2B1C},   // large squares
+	{0x2B50, 0x2B50},   // star
+	{0x2B55, 0x2B55},   // circle
+	{0x2E80, 0x303E},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK unified ideographs extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xA960, 0xA97F},   // Hangul Jamo extended A
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE10, 0xFE19},   // vertical forms
+	{0xFE30, 0xFE6F},   // CJK compatibility forms, small form variants
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x16FE0, 0x18CFF}, // Tangut, Khitan
+	{0x1B000, 0x1B2FF}, // Kana supplement and extensions, Nushu
+	{0x1F004, 0x1F004}, // mahjong tile
+	{0x1F0CF, 0x1F0CF}, // playing card
+	{0x1F18E, 0x1F18E}, // AB button
+	{0x1F191, 0x1F19A}, // squared words
+	{0x1F200, 0x1F2FF}, // enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // pictographs, emoticons
+	{0x1F680, 0x1F6FF}, // transport and map symbols
+	{0x1F7E0, 0x1F7EB}, // colored circles and squares
+	{0x1F90C, 0x1F9FF}, // supplemental symbols and pictographs
+	{0x1FA70, 0x1FAFF}, // symbols and pictographs extended A
+	{0x20000, 0x2FFFD}, // CJK unified ideographs extensions
+	{0x30000, 0x3FFFD}, // CJK unified ideographs extension G
+}
+
+func isWideRune(r rune) bool {
+	if r < wideRunes[0][0] {
+		return false
+	}
+	i := sort.Search(len(wideRunes), func(i int) bool { return wideRunes[i][1] >= r })
+	return i < len(wideRunes) && wideRunes[i][0] <= r
+}
+
+// columnWriter aligns the cells of the lines written to it, like a
+// tabwriter.Writer, but measures the cells with displayWidth so that columns
+// containing wide characters or color escape sequences line up on terminals.
+//
+// Cells are terminated by tab characters, the text after the last tab of a
+// line is not part of a column. Unlike tabwriter, each column is padded to the
+// width of its widest cell across all the lines written before Flush, so lines
+// without cells, like section headers, do not break the alignment.
+type columnWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func newColumnWriter(w io.Writer) *columnWriter { return &columnWriter{w: w} }
+
+func (cw *columnWriter) Write(b []byte) (int, error) { return cw.buf.Write(b) }
+
+// Flush writes the aligned lines to the underlying writer.
+func (cw *columnWriter) Flush() error {
+	lines := strings.SplitAfter(cw.buf.String(), "\n")
+	cw.buf.Reset()
+
+	var width []int
+	cells := make([][]string, len(lines))
+	for i, line := range lines {
+		cells[i] = strings.Split(line, "\t")
+		for j, cell := range cells[i][:len(cells[i])-1] {
+			if j == len(width) {
+				width = append(width, 0)
+			}
+			if n := displayWidth(cell); n > width[j] {
+				width[j] = n
+			}
+		}
+	}
+
+	b := new(strings.Builder)
+	for _, line := range cells {
+		last := len(line) - 1
+		for j, cell := range line[:last] {
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", width[j]-displayWidth(cell)))
+		}
+		b.WriteString(line[last])
+	}
+	_, err := io.WriteString(cw.w, b.String())
+	return err
+}
+
+// isTerminal returns true if w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && ioctlWidth(f) > 0
+}
@@ -0,0 +1,9 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package cli
+
+import "os"
+
+// ioctlWidth returns zero since the size of terminals cannot be queried on
+// this platform.
+func ioctlWidth(f *os.File) int { return 0 }
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		lines []string
+	}{
+		{"", 40, nil},
+		{"short text", 40, []string{"short text"}},
+		{"short text", 0, []string{"short text"}},
+		{
+			"the quick brown fox jumps over the lazy dog",
+			20,
+			[]string{"the quick brown fox", "jumps over the lazy", "dog"},
+		},
+		{
+			"first paragraph\n\n  an indented line which is too long",
+			24,
+			[]string{"first paragraph", "", "  an indented line which", "  is too long"},
+		},
+		{
+			"a supercalifragilisticexpialidocious word",
+			20,
+			[]string{"a", "supercalifragilisticexpialidocious", "word"},
+		},
+	}
+
+	for _, test := range tests {
+		if lines := wrapText(test.text, test.width); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("wrapText(%q, %d): got %q, want %q", test.text, test.width, lines, test.lines)
+		}
+	}
+}
+
+func TestHelpWrapping(t *testing.T) {
+	type config struct {
+		Name string `flag:"-n,--name" help:"The name of the person to greet, which may be long" default:"Luke"`
+	}
+
+	cmd := Command(func(config config) {})
+	stdout := new(strings.Builder)
+	CallWith(cmd, []string{"--help"}, WithEnv("COLUMNS=50"), WithStdout(stdout))
+	help := stdout.String()
+
+	const want = `
+Usage:
+  [options]
+
+Options:
+  -h, --help         Show this help message
+  -n, --name string  The name of the person to
+                     greet, which may be long
+                     (default: Luke)
+
+`
+	if help != want {
+		t.Errorf("wrong help message:\n%s\nwant:\n%s", help, want)
+	}
+
+	for _, line := range strings.Split(help, "\n") {
+		if len(line) > 50 {
+			t.Errorf("line is longer than the terminal: %q", line)
+		}
+	}
+
+	// The COLUMNS variable of the process only applies to terminals.
+	t.Setenv("COLUMNS", "50")
+	stdout.Reset()
+	CallWith(cmd, []string{"--help"}, WithStdout(stdout))
+	if help := stdout.String(); strings.Contains(help, "to\n") {
+		t.Errorf("help message printed to a buffer was wrapped:\n%s", help)
+	}
+
+	// The width is passed to formatters with the width of the verb.
+	_, err := cmd.Call(context.TODO(), []string{"--help"}, nil)
+	if help := fmt.Sprintf("%50v", err); help+"\n" != want {
+		t.Errorf("wrong help message:\n%s\nwant:\n%s", help, want)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"héllo", 5},
+		{"héllo", 5},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"deploy 🚀", 9},
+		{"\x1b[36m--name\x1b[0m", 6},
+		{"\x1b[1m名前\x1b[0m", 4},
+	}
+
+	for _, test := range tests {
+		if n := displayWidth(test.text); n != test.width {
+			t.Errorf("displayWidth(%q): got %d, want %d", test.text, n, test.width)
+		}
+	}
+}
+
+func TestHelpWideCharacters(t *testing.T) {
+	type config struct {
+		Name   string `flag:"-n,--名前"   help:"名前を設定する"`
+		Deploy bool   `flag:"--deploy" help:"Deploy the 🚀"`
+	}
+
+	cmd := CommandSet{
+		"日本":     &CommandFunc{Help: "日本語のコマンド", Func: func(config config) {}},
+		"deploy": &CommandFunc{Help: "Deploy the application", Func: func(config config) {}},
+	}
+
+	_, err := cmd.Call(context.TODO(), []string{"日本", "--help"}, nil)
+	const wantCommand = `
+Usage:
+  日本 [options]
+
+Options:
+      --deploy       Deploy the 🚀
+  -h, --help         Show this help message
+  -n, --名前 string  名前を設定する (required)
+`
+	if help := fmt.Sprintf("%v", err); help != wantCommand {
+		t.Errorf("wrong help message:\n%s\nwant:\n%s", help, wantCommand)
+	}
+
+	_, err = cmd.Call(context.TODO(), []string{"--help"}, nil)
+	const wantSet = `
+Usage:
+  [command] [-h] [--help] ...
+
+Commands:
+  deploy  Deploy the application
+  日本    日本語のコマンド
+
+Options:
+  -h, --help  Show this help message
+`
+	if help := fmt.Sprintf("%v", err); help != wantSet {
+		t.Errorf("wrong help message:\n%s\nwant:\n%s", help, wantSet)
+	}
+}
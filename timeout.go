@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeoutFlag adds a --timeout option to the program, which sets the
+// maximum duration of the command execution: the context passed to the command
+// is canceled when the timeout expires. The option accepts the same values as
+// fields of type time.Duration, and has no effect when omitted or zero.
+//
+// Only commands which accept a context.Context as first argument, and honor its
+// cancellation, are interrupted by the timeout.
+func WithTimeoutFlag() ExecOption {
+	return func(o *execOptions) { o.timeout = true }
+}
+
+type timeoutConfig struct {
+	Timeout time.Duration `flag:"--timeout" help:"Maximum duration of the command execution" default:"-"`
+}
+
+// withTimeoutFlag returns a version of cmd which supports the --timeout option.
+func withTimeoutFlag(cmd Function) Function {
+	config := new(timeoutConfig)
+	return Persistent(config, Wrap(cmd, func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			if config.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+				defer cancel()
+			}
+			return next.Call(ctx, args, env)
+		})
+	}))
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"strings"
+)
+
+// Tracer is the interface of tracing systems recording the execution of
+// commands. It is implemented by adapters of tracing libraries, for example
+// with OpenTelemetry:
+//
+//	type tracer struct{ trace.Tracer }
+//
+//	func (t tracer) StartSpan(ctx context.Context, name string) (context.Context, cli.Span) {
+//		ctx, span := t.Start(ctx, name)
+//		return ctx, spanAdapter{span}
+//	}
+//
+//	type spanAdapter struct{ trace.Span }
+//
+//	func (s spanAdapter) End(code int, err error) {
+//		s.SetAttributes(attribute.Int("exit.code", code))
+//		if err != nil {
+//			s.RecordError(err)
+//			s.SetStatus(codes.Error, err.Error())
+//		}
+//		s.Span.End()
+//	}
+type Tracer interface {
+	// StartSpan starts a span with the given name, returning a context
+	// carrying the span, which is passed to the command.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the interface of spans created by a Tracer.
+type Span interface {
+	// End ends the span, recording the exit code and error returned by the
+	// command.
+	End(code int, err error)
+}
+
+// WithTracer sets the tracer which records a span for each invocation of a
+// command of the program. The spans are named after the path of the commands
+// which led to the command, like "prog sub command", and cover both the
+// parsing of arguments and the execution of the command. A single span is
+// recorded per command that the program dispatches to, the commands composing
+// it, like the ones of Chain, do not start spans of their own.
+func WithTracer(tracer Tracer) ExecOption {
+	return func(o *execOptions) { o.tracer = tracer }
+}
+
+type traceKey struct{}
+
+// trace calls fn within a span of the tracer attached to ctx, if any. It is
+// called when the program dispatches to a command, and does not start another
+// span if ctx already carries one started by trace.
+func trace(ctx context.Context, fn func(context.Context) (int, error)) (int, error) {
+	o := execOptionsOf(ctx)
+	if o.tracer == nil {
+		return fn(ctx)
+	}
+	if traced, _ := ctx.Value(traceKey{}).(bool); traced {
+		return fn(ctx)
+	}
+
+	name := strings.Join(commandPathOf(ctx), " ")
+	if name == "" {
+		name = o.program
+	}
+
+	ctx, span := o.tracer.StartSpan(ctx, name)
+	code, err := fn(withValue(ctx, traceKey{}, true))
+	span.End(code, err)
+	return code, err
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type testTracer struct{ spans []string }
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+}
+
+type testSpanKey struct{}
+
+func (t *testTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return context.WithValue(ctx, testSpanKey{}, name), &testSpan{t, name}
+}
+
+func (s *testSpan) End(code int, err error) {
+	s.tracer.spans = append(s.tracer.spans, s.name+": "+spanStatus(code, err))
+}
+
+func spanStatus(code int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+func TestCallTracer(t *testing.T) {
+	defer func(w io.Writer) { Err = w }(Err)
+	Err = io.Discard
+
+	var span interface{}
+	cmd := NamedCommand("prog", CommandSet{
+		"ok": Command(func(ctx context.Context) {
+			span = ctx.Value(testSpanKey{})
+		}),
+		"fail": Command(func(ctx context.Context) error {
+			return errors.New("failed")
+		}),
+	})
+
+	tracer := new(testTracer)
+	options := []ExecOption{WithTracer(tracer)}
+
+	call(context.TODO(), cmd, []string{"ok"}, makeExecOptions(options))
+	call(context.TODO(), cmd, []string{"fail"}, makeExecOptions(options))
+
+	if span != "prog ok" {
+		t.Errorf("the command was not given the context of the span: %v", span)
+	}
+
+	if !reflect.DeepEqual(tracer.spans, []string{"prog ok: ok", "prog fail: failed"}) {
+		t.Errorf("wrong spans: %q", tracer.spans)
+	}
+}
+
+func TestCallTracerSingleSpan(t *testing.T) {
+	tests := []struct {
+		scenario string
+		cmd      Function
+		args     []string
+		options  []ExecOption
+		spans    []string
+	}{
+		{
+			scenario: "timeout flag",
+			cmd:      NamedCommand("prog", CommandSet{"ok": Command(func(ctx context.Context) {})}),
+			args:     []string{"ok", "--timeout", "1m"},
+			options:  []ExecOption{WithTimeoutFlag()},
+			spans:    []string{"prog ok: ok"},
+		},
+		{
+			scenario: "single command",
+			cmd:      NamedCommand("prog", Command(func(ctx context.Context) {})),
+			options:  []ExecOption{WithTimeoutFlag(), WithVerbosityFlags()},
+			spans:    []string{"prog: ok"},
+		},
+		{
+			scenario: "chain",
+			cmd: NamedCommand("prog", CommandSet{
+				"both": Chain(Command(func(struct{}) {}), Command(func(struct{}) {})),
+			}),
+			args:  []string{"both"},
+			spans: []string{"prog both: ok"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			tracer := new(testTracer)
+			options := append([]ExecOption{WithTracer(tracer)}, test.options...)
+
+			if code := call(context.TODO(), test.cmd, test.args, makeExecOptions(options)); code != 0 {
+				t.Fatalf("wrong exit code: %d", code)
+			}
+			if !reflect.DeepEqual(tracer.spans, test.spans) {
+				t.Errorf("wrong spans: got %q, want %q", tracer.spans, test.spans)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package cli
+
+import "fmt"
+
+// Messages translates the messages printed by the package, like the headers of
+// help messages or the descriptions of errors found on the command line, which
+// are in English by default. Programs may set it to localize them:
+//
+//	cli.Messages = cli.Catalog{
+//		"Usage:":                  "Utilisation :",
+//		"Options:":                "Options :",
+//		"Show this help message":  "Afficher ce message d'aide",
+//		"unrecognized option: %q": "option inconnue : %q",
+//	}
+//
+// The messages are identified by their English text. Messages which contain
+// formatting verbs, like "unrecognized option: %q", must be translated to
+// strings with the same verbs. The help messages of options and the names of
+// sections are also passed to the translator, so the catalog of a program may
+// include its own messages.
+var Messages Translator
+
+// Translator is the interface of values translating the messages of the
+// package, see Messages.
+type Translator interface {
+	// Translate returns the translation of message, or message itself if it
+	// has no translation.
+	Translate(message string) string
+}
+
+// Catalog is a Translator which looks up the translations of messages in a
+// map, keyed by the English messages.
+type Catalog map[string]string
+
+// Translate satisfies the Translator interface.
+func (c Catalog) Translate(message string) string {
+	if s, ok := c[message]; ok {
+		return s
+	}
+	return message
+}
+
+// tr returns the translation of message by Messages.
+func tr(message string) string {
+	if Messages == nil || message == "" {
+		return message
+	}
+	return Messages.Translate(message)
+}
+
+// errorf is like fmt.Errorf, but translates the format first.
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(tr(format), args...)
+}
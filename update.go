@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithUpdateCheck makes the program check whether a newer version of it was
+// released, and print a notice to its standard error after the command
+// returned when one is available:
+//
+//	A new version of prog is available: v1.2.3 -> v1.4.0
+//
+// The check function receives the current version of the program (see
+// WithVersion) and returns the version of the latest release, typically
+// retrieved from a release API. The notice is printed when the latest version
+// is newer than the current one, comparing them like semantic versions, and
+// errors of the function are ignored.
+//
+// The check runs concurrently with the command, at most once a day, or at the
+// interval set with WithUpdateCheckInterval; the time of the last check is
+// cached in the user cache directory of the program (under XDG_CACHE_HOME or
+// HOME/.cache), including when it failed, so unreachable release APIs do not
+// slow down every run of the program. The context passed to the function
+// expires after a few seconds, which bounds the time that the program waits
+// for it after the command returned.
+//
+// The check is skipped when the standard error is not a terminal, and when the
+// CI environment variable is set, so it does not interfere with scripts.
+func WithUpdateCheck(check func(ctx context.Context, current string) (latest string, err error)) ExecOption {
+	return func(o *execOptions) { o.updateCheck = check }
+}
+
+// WithUpdateCheckInterval sets the minimum time between two update checks
+// enabled by WithUpdateCheck, which defaults to 24 hours.
+func WithUpdateCheckInterval(interval time.Duration) ExecOption {
+	return func(o *execOptions) { o.updateInterval = interval }
+}
+
+const (
+	defaultUpdateCheckInterval = 24 * time.Hour
+	updateCheckTimeout         = 3 * time.Second
+)
+
+// updateCheckState is the content of the file caching the last update check.
+type updateCheckState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+// startUpdateCheck runs the update check of program in the background if the
+// last one is older than the interval, returning a channel which receives the
+// notice to print, if any, and is closed when the check is done. A nil channel
+// is returned if the check was skipped.
+func startUpdateCheck(ctx context.Context, o *execOptions, program string) <-chan string {
+	current := ""
+	if o.version != nil {
+		current = *o.version
+	}
+	if current, _, _ = buildInfo(current); current == "(unknown)" {
+		return nil
+	}
+
+	path := o.updateCheckPath(program)
+	if path == "" {
+		return nil
+	}
+
+	interval := o.updateInterval
+	if interval <= 0 {
+		interval = defaultUpdateCheckInterval
+	}
+
+	var state updateCheckState
+	if b, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(b, &state)
+	}
+	now := time.Now()
+	if now.Sub(state.CheckedAt) < interval {
+		return nil
+	}
+
+	notices := make(chan string, 1)
+	go func() {
+		defer close(notices)
+
+		ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+		defer cancel()
+
+		latest, err := o.updateCheck(ctx, current)
+		if err != nil {
+			latest = state.Latest
+		}
+
+		// Failed checks are recorded as well, so they are not retried
+		// before the interval elapsed.
+		state = updateCheckState{CheckedAt: now, Latest: latest}
+		if b, err := json.Marshal(state); err == nil {
+			os.MkdirAll(filepath.Dir(path), 0755)
+			os.WriteFile(path, b, 0644)
+		}
+
+		if err == nil && newerVersion(latest, current) {
+			notices <- fmt.Sprintf(tr("A new version of %s is available: %s -> %s"), program, current, latest)
+		}
+	}()
+	return notices
+}
+
+// printUpdateNotice waits for the update check started by startUpdateCheck,
+// and prints its notice to w.
+func printUpdateNotice(w io.Writer, notices <-chan string) {
+	if notice, ok := <-notices; ok {
+		fmt.Fprintf(w, "\n%s\n", notice)
+	}
+}
+
+// newerVersion returns true if the version latest is newer than current. The
+// versions are compared like semantic versions, with or without a "v" prefix:
+// their numeric components are compared in order, then their pre-release
+// identifiers, which make versions older than the release they precede. False
+// is returned if either version cannot be parsed.
+func newerVersion(latest, current string) bool {
+	l, lpre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, cpre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var x, y int
+		if i < len(l) {
+			x = l[i]
+		}
+		if i < len(c) {
+			y = c[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+
+	switch {
+	case lpre == cpre:
+		return false
+	case lpre == "":
+		return true
+	case cpre == "":
+		return false
+	}
+
+	a, b := strings.Split(lpre, "."), strings.Split(cpre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		switch {
+		case errX == nil && errY == nil:
+			return x > y
+		case errX == nil || errY == nil:
+			// Numeric identifiers have lower precedence.
+			return errY == nil
+		default:
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+// parseVersion splits the version s in its numeric components and pre-release
+// identifiers, ignoring its build metadata.
+func parseVersion(s string) (nums []int, pre string, ok bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ = strings.Cut(s, "-")
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre, true
+}
+
+// updateCheckPath returns the path of the file caching the last update check
+// of program, or an empty string if the user cache directory is unknown. The
+// directory depends on the XDG_CACHE_HOME and HOME environment variables of the
+// program.
+func (o *execOptions) updateCheckPath(program string) string {
+	if program == "" {
+		return ""
+	}
+
+	dir := o.getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		if o.env == nil {
+			dir, _ = os.UserCacheDir()
+		} else if home := o.getenv("HOME"); home != "" {
+			dir = filepath.Join(home, ".cache")
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, program, "update-check.json")
+}
+
+// updateCheckEnabled returns true if the update check of the program may run,
+// which requires stderr to be a terminal, outside of CI environments.
+func updateCheckEnabled(o *execOptions, stderr io.Writer) bool {
+	if o.updateCheck == nil || !isTerminal(stderr) {
+		return false
+	}
+	_, ci := lookupEnv("CI", o.programEnv(), o.caseInsensitiveEnv())
+	return !ci
+}
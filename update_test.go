@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateCheck(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	calls := 0
+	latest := "v1.4.0"
+	options := makeExecOptions([]ExecOption{
+		WithVersion("v1.2.3"),
+		WithUpdateCheck(func(ctx context.Context, current string) (string, error) {
+			if current != "v1.2.3" {
+				t.Errorf("wrong current version: %q", current)
+			}
+			calls++
+			return latest, nil
+		}),
+	})
+
+	w := new(strings.Builder)
+	printUpdateNotice(w, startUpdateCheck(context.TODO(), options, "prog"))
+	if want := "\nA new version of prog is available: v1.2.3 -> v1.4.0\n"; w.String() != want {
+		t.Errorf("wrong update notice: got %q, want %q", w.String(), want)
+	}
+
+	// The last check is cached, so the next one is skipped.
+	if startUpdateCheck(context.TODO(), options, "prog") != nil || calls != 1 {
+		t.Error("the update check was not rate limited")
+	}
+
+	// No notice is printed when the program is up to date.
+	WithUpdateCheckInterval(time.Nanosecond)(options)
+	latest = "v1.2.3"
+	w.Reset()
+	printUpdateNotice(w, startUpdateCheck(context.TODO(), options, "prog"))
+	if w.String() != "" || calls != 2 {
+		t.Errorf("unexpected update notice after %d calls: %q", calls, w.String())
+	}
+
+	// No notice is printed when the latest release is older.
+	latest = "v1.1.0"
+	w.Reset()
+	printUpdateNotice(w, startUpdateCheck(context.TODO(), options, "prog"))
+	if w.String() != "" || calls != 3 {
+		t.Errorf("unexpected update notice after %d calls: %q", calls, w.String())
+	}
+
+	// The check never runs when the output is not a terminal.
+	if updateCheckEnabled(options, w) {
+		t.Error("the update check was enabled on a non-terminal output")
+	}
+}
+
+func TestUpdateCheckFailure(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	calls := 0
+	options := makeExecOptions([]ExecOption{
+		WithVersion("v1.2.3"),
+		WithUpdateCheck(func(ctx context.Context, current string) (string, error) {
+			calls++
+			return "", errors.New("unreachable")
+		}),
+	})
+
+	w := new(strings.Builder)
+	printUpdateNotice(w, startUpdateCheck(context.TODO(), options, "prog"))
+	if w.String() != "" {
+		t.Errorf("unexpected update notice: %q", w.String())
+	}
+
+	// Failed checks are cached, so the next one is skipped.
+	if startUpdateCheck(context.TODO(), options, "prog") != nil || calls != 1 {
+		t.Error("the failed update check was not rate limited")
+	}
+}
+
+func TestUpdateCheckPathEnv(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	home, cache := t.TempDir(), t.TempDir()
+
+	tests := []struct {
+		env  []string
+		want string
+	}{
+		{[]string{"HOME=" + home}, filepath.Join(home, ".cache", "prog", "update-check.json")},
+		{[]string{"HOME=" + home, "XDG_CACHE_HOME=" + cache}, filepath.Join(cache, "prog", "update-check.json")},
+		{[]string{}, ""},
+	}
+
+	for _, test := range tests {
+		options := makeExecOptions([]ExecOption{WithEnv(test.env...)})
+		if path := options.updateCheckPath("prog"); path != test.want {
+			t.Errorf("%q: wrong path: got %q, want %q", test.env, path, test.want)
+		}
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		newer           bool
+	}{
+		{"v1.4.0", "v1.2.3", true},
+		{"1.2.4", "v1.2.3", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.1.0", "v1.2.3", false},
+		{"v1.2", "v1.2.0", false},
+		{"v1.2.3", "v1.2.3-rc.1", true},
+		{"v1.2.3-rc.1", "v1.2.3", false},
+		{"v1.2.3-rc.10", "v1.2.3-rc.9", true},
+		{"v1.2.3-rc.1", "v1.2.3-beta", true},
+		{"v1.2.3+build.2", "v1.2.3+build.1", false},
+		{"latest", "v1.2.3", false},
+		{"v1.2.3", "(devel)", false},
+	}
+
+	for _, test := range tests {
+		if newer := newerVersion(test.latest, test.current); newer != test.newer {
+			t.Errorf("newerVersion(%q, %q): got %t, want %t", test.latest, test.current, newer, test.newer)
+		}
+	}
+}
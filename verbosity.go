@@ -0,0 +1,60 @@
+package cli
+
+import "context"
+
+// WithVerbosityFlags adds the -v, --verbose and -q, --quiet options to the
+// program, which set the verbosity level of the command: the level is the
+// number of times that -v was given (-v -v and -vv both set it to 2), or -1
+// with --quiet, and zero by default. Commands retrieve the level with
+// Verbosity:
+//
+//	cmd := cli.Command(func(ctx context.Context, config config) {
+//		if cli.Verbosity(ctx) > 0 {
+//			...
+//		}
+//	})
+//
+//	cli.ExecWith(cmd, cli.WithVerbosityFlags())
+//
+// At -q, the program does not print the output which is not essential, like
+// the warnings of deprecated commands. Errors are always printed.
+func WithVerbosityFlags() ExecOption {
+	return func(o *execOptions) { o.verbosity = true }
+}
+
+// Verbosity returns the verbosity level of the program called with ctx, which
+// is negative when the program must be quiet, and positive when it may print
+// more details than usual. The level is zero when the program was not called
+// with WithVerbosityFlags.
+func Verbosity(ctx context.Context) int {
+	if ctx != nil {
+		level, _ := ctx.Value(verbosityKey{}).(int)
+		return level
+	}
+	return 0
+}
+
+type verbosityKey struct{}
+
+type verbosityConfig struct {
+	Verbose []bool `flag:"-v,--verbose" help:"Print more details, may be repeated"`
+	Quiet   bool   `flag:"-q,--quiet"   help:"Print only the essential output and errors"`
+}
+
+// withVerbosityFlags returns a version of cmd which supports the verbosity
+// options.
+func withVerbosityFlags(cmd Function) Function {
+	config := new(verbosityConfig)
+	return Persistent(config, Wrap(cmd, func(next Function) Function {
+		return CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+			if config.Quiet && len(config.Verbose) != 0 {
+				return 1, &Usage{Err: errorf("--quiet and --verbose cannot be used together")}
+			}
+			level := len(config.Verbose)
+			if config.Quiet {
+				level = -1
+			}
+			return next.Call(withValue(ctx, verbosityKey{}, level), args, env)
+		})
+	}))
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// WithVersion sets the version of the program, which enables the --version
+// option of the program and, when it is a command set without a "version"
+// command, adds a "version" command to it. Both print the version, the
+// revision and time of the commit that the program was built from, and the
+// version of the Go runtime, for example:
+//
+//	$ prog --version
+//	prog version v1.2.3
+//	commit: 2b4f6a1e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a
+//	date:   2023-06-01T12:00:00Z
+//	go:     go1.20.4 linux/amd64
+//
+// The commit information is read from the build information embedded in the
+// program by the Go toolchain. When version is empty, the version of the main
+// module is used instead, which is set when the program was installed with
+// "go install".
+func WithVersion(version string) ExecOption {
+	return func(o *execOptions) { o.version = &version }
+}
+
+// withVersion returns a version of cmd which supports the --version option and
+// "version" command.
+func withVersion(cmd Function, program, version string) Function {
+	cmd = withCommand(cmd, "version", &CommandFunc{
+		Help: "Print version information",
+		Func: func(_ struct{}, s Streams) { printVersion(s.Stdout, program, version) },
+	})
+
+	named, _ := cmd.(*namedCommand)
+	if named != nil {
+		cmd = named.cmd
+	}
+
+	cmd = &versionCommand{wrappedCommand{cmd}, program, version}
+	if named != nil {
+		cmd = NamedCommand(named.name, cmd)
+	}
+	return cmd
+}
+
+type versionCommand struct {
+	wrappedCommand
+	program string
+	version string
+}
+
+func (c *versionCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	if len(args) != 0 && args[0] == "--version" {
+		printVersion(streamsOf(ctx).Stdout, c.program, c.version)
+		return 0, nil
+	}
+	return c.cmd.Call(ctx, args, env)
+}
+
+func printVersion(w io.Writer, program, version string) {
+	version, commit, date := buildInfo(version)
+
+	fmt.Fprintf(w, "%s version %s\n", strings.TrimSpace(program), version)
+	if commit != "" {
+		fmt.Fprintf(w, "commit: %s\n", commit)
+	}
+	if date != "" {
+		fmt.Fprintf(w, "date:   %s\n", date)
+	}
+	fmt.Fprintf(w, "go:     %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// buildInfo returns the version of the program, which defaults to the version
+// of the main module when empty, and the revision and time of the commit that
+// it was built from, read from the build information of the program.
+func buildInfo(version string) (string, string, string) {
+	var commit, date string
+	var modified bool
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.time":
+				date = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+
+	if version == "" {
+		version = "(unknown)"
+	}
+	if modified {
+		commit += " (modified)"
+	}
+	return version, commit, date
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPrintVersion(t *testing.T) {
+	b := new(bytes.Buffer)
+	printVersion(b, "prog", "v1.2.3")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if lines[0] != "prog version v1.2.3" {
+		t.Error("wrong version line:", lines[0])
+	}
+	if last := lines[len(lines)-1]; last != "go:     "+runtime.Version()+" "+runtime.GOOS+"/"+runtime.GOARCH {
+		t.Error("wrong runtime line:", last)
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	called := false
+	cmd := withVersion(NamedCommand("prog", CommandSet{
+		"run": Command(func(struct{}) { called = true }),
+	}), "prog", "v1.2.3")
+
+	if _, ok := cmd.(*namedCommand); !ok {
+		t.Errorf("the command name was not preserved: %T", cmd)
+	}
+
+	set := cmd.(*namedCommand).cmd.(*versionCommand).cmd.(CommandSet)
+	if _, ok := set["version"]; !ok {
+		t.Error("the version command was not added to the command set")
+	}
+
+	if code, err := cmd.Call(context.TODO(), []string{"run"}, nil); code != 0 || err != nil {
+		t.Error("calling the command failed:", code, err)
+	}
+	if !called {
+		t.Error("the command was not called")
+	}
+}
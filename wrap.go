@@ -0,0 +1,461 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// wrappedCommand is embedded in the types which wrap a Function to forward the
+// optional methods of the wrapped command, so the wrappers preserve its help
+// messages and configuration.
+type wrappedCommand struct {
+	cmd Function
+}
+
+// Call satisfies the Function interface, calling the wrapped command.
+func (w wrappedCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	return w.cmd.Call(ctx, args, env)
+}
+
+// Format satisfies the fmt.Formatter interface, formatting the wrapped command.
+func (w wrappedCommand) Format(f fmt.State, v rune) {
+	if x, ok := w.cmd.(fmt.Formatter); ok {
+		x.Format(f, v)
+	}
+}
+
+// Name returns the name of the wrapped command, if it has one.
+func (w wrappedCommand) Name() string { return nameOf(w.cmd) }
+
+func (w wrappedCommand) desc() string { return descOf(w.cmd) }
+
+func (w wrappedCommand) configure() {
+	if x, ok := w.cmd.(interface{ configure() }); ok {
+		x.configure()
+	}
+}
+
+func (w wrappedCommand) envPrefix() (string, bool) { return envPrefixOf(w.cmd) }
+
+func (w wrappedCommand) category() string { return categoryOf(w.cmd) }
+
+func (w wrappedCommand) deprecated() string { return deprecatedOf(w.cmd) }
+
+func (w wrappedCommand) hidden() bool { return hiddenOf(w.cmd) }
+
+// InCategory returns a Function which behaves like cmd, and is listed in a
+// section named after category in the help of the command sets it belongs to,
+// for example:
+//
+//	cmd := cli.CommandSet{
+//		"run":       run,
+//		"container": cli.InCategory("Management Commands", container),
+//		"image":     cli.InCategory("Management Commands", image),
+//	}
+//
+// Commands without a category are listed first, in the "Commands" section.
+func InCategory(category string, cmd Function) Function {
+	return &categoryCommand{wrappedCommand{cmd}, category}
+}
+
+type categoryCommand struct {
+	wrappedCommand
+	name string
+}
+
+func (c *categoryCommand) category() string { return c.name }
+
+func categoryOf(cmd Function) string {
+	if x, ok := cmd.(interface{ category() string }); ok {
+		return x.category()
+	}
+	return ""
+}
+
+// Lazy returns a Function which constructs the command by calling fn the first
+// time it is needed, which is either when it is called or when its help message
+// is formatted.
+//
+// Command sets configure all their commands before dispatching, which involves
+// inspecting the types of their configuration with reflection. Wrapping the
+// commands with Lazy defers this work for the ones which are not used, which
+// makes a difference for programs with hundreds of commands:
+//
+//	cmd := cli.CommandSet{
+//		"deploy": cli.Lazy(func() cli.Function {
+//			return cli.Command(deploy)
+//		}),
+//	}
+//
+// Formatting the help of a command set constructs all of its commands. The
+// category, deprecation, and visibility of lazy commands are not read from the
+// constructed commands, so they are known without constructing them: they are
+// set by wrapping the lazy command with InCategory, Deprecated, or Hidden, for
+// example:
+//
+//	cmd := cli.CommandSet{
+//		"debug": cli.Hidden(cli.Lazy(func() cli.Function {
+//			return cli.Command(debug)
+//		})),
+//	}
+func Lazy(fn func() Function) Function {
+	return &lazyCommand{fn: fn}
+}
+
+type lazyCommand struct {
+	once sync.Once
+	fn   func() Function
+	cmd  wrappedCommand
+}
+
+func (c *lazyCommand) get() wrappedCommand {
+	c.once.Do(func() { c.cmd.cmd = c.fn() })
+	return c.cmd
+}
+
+// Call satisfies the Function interface, constructing the command on the first
+// call.
+func (c *lazyCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	cmd := c.get()
+	cmd.configure()
+	return cmd.Call(ctx, args, env)
+}
+
+// Format satisfies the fmt.Formatter interface, constructing the command if it
+// was not used yet.
+func (c *lazyCommand) Format(f fmt.State, v rune) { c.get().Format(f, v) }
+
+// Name returns the name of the command, if it has one.
+func (c *lazyCommand) Name() string { return c.get().Name() }
+
+// The configuration of lazy commands is deferred until they are called.
+func (c *lazyCommand) configure() {}
+
+func (c *lazyCommand) envPrefix() (string, bool) { return c.get().envPrefix() }
+
+func (c *lazyCommand) desc() string { return c.get().desc() }
+
+// CallFunc is an adapter to allow the use of ordinary functions as Function
+// values, which is mostly useful to write middleware for Wrap.
+type CallFunc func(ctx context.Context, args, env []string) (int, error)
+
+// Call satisfies the Function interface, calling f.
+func (f CallFunc) Call(ctx context.Context, args, env []string) (int, error) {
+	return f(ctx, args, env)
+}
+
+// Wrap returns a Function which calls cmd through the given middleware, for
+// example to check credentials, record telemetry, or acquire a lock around the
+// execution of commands:
+//
+//	func timing(next cli.Function) cli.Function {
+//		return cli.CallFunc(func(ctx context.Context, args, env []string) (int, error) {
+//			defer func(start time.Time) { log.Println(time.Since(start)) }(time.Now())
+//			return next.Call(ctx, args, env)
+//		})
+//	}
+//
+//	cmd := cli.Wrap(cli.Command(run), timing)
+//
+// The first middleware is the outermost, it is the first one to see the call.
+// Only the calls go through the middleware, the help messages and settings of
+// the returned Function are still the ones of cmd.
+func Wrap(cmd Function, middleware ...func(Function) Function) Function {
+	call := cmd
+	for i := len(middleware) - 1; i >= 0; i-- {
+		call = middleware[i](call)
+	}
+	return &middlewareCommand{wrappedCommand{cmd}, call}
+}
+
+type middlewareCommand struct {
+	wrappedCommand
+	call Function
+}
+
+func (c *middlewareCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	return c.call.Call(ctx, args, env)
+}
+
+// Use wraps all the commands of the set with the given middleware (see Wrap).
+// Nested command sets are wrapped as a whole, and the commands added to the set
+// after calling Use are not wrapped.
+func (cmds CommandSet) Use(middleware ...func(Function) Function) {
+	for name, cmd := range cmds {
+		if name != "_" {
+			cmds[name] = Wrap(cmd, middleware...)
+		}
+	}
+}
+
+// Deprecated returns a Function which behaves like cmd, but is deprecated with
+// the given message, like commands with a Deprecated field set in CommandFunc.
+// This is mostly useful to deprecate command sets:
+//
+//	cmd := cli.CommandSet{
+//		"legacy": cli.Deprecated(`use "prog get" instead`, cli.CommandSet{
+//			...
+//		}),
+//	}
+func Deprecated(message string, cmd Function) Function {
+	return &deprecatedCommand{wrappedCommand{cmd}, message}
+}
+
+type deprecatedCommand struct {
+	wrappedCommand
+	message string
+}
+
+func (c *deprecatedCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	warnDeprecated(ctx, c.message)
+	return c.cmd.Call(ctx, args, env)
+}
+
+func (c *deprecatedCommand) deprecated() string { return c.message }
+
+func deprecatedOf(cmd Function) string {
+	if x, ok := cmd.(interface{ deprecated() string }); ok {
+		return x.deprecated()
+	}
+	return ""
+}
+
+// warnDeprecated prints the warning of a deprecated command called with ctx,
+// unless the program is quiet.
+func warnDeprecated(ctx context.Context, message string) {
+	if Verbosity(ctx) < 0 {
+		return
+	}
+	name := tr("this command")
+	if path := commandPathOf(ctx); len(path) != 0 {
+		name = strconv.Quote(strings.Join(path, " "))
+	}
+	_, w := execOptionsOf(ctx).outputs()
+	if message == "" {
+		fmt.Fprintf(w, tr("warning: %s is deprecated")+"\n", name)
+	} else {
+		fmt.Fprintf(w, tr("warning: %s is deprecated, %s")+"\n", name, message)
+	}
+}
+
+// Hidden returns a Function which behaves like cmd, but is not listed in the
+// help of the command sets it belongs to, nor proposed by shell completions.
+// It is useful for internal or experimental commands, which users may still
+// discover with the --help-all option:
+//
+//	cmd := cli.CommandSet{
+//		"get":   get,
+//		"debug": cli.Hidden(debug),
+//	}
+func Hidden(cmd Function) Function {
+	return &hiddenCommand{wrappedCommand{cmd}}
+}
+
+type hiddenCommand struct{ wrappedCommand }
+
+func (c *hiddenCommand) hidden() bool { return true }
+
+func hiddenOf(cmd Function) bool {
+	if x, ok := cmd.(interface{ hidden() bool }); ok {
+		return x.hidden()
+	}
+	return false
+}
+
+// Chain returns a Function which calls the commands in order, stopping at the
+// first one which returns a non-zero code or an error. This is useful to
+// compose commands, like a "build-and-push" command made of the "build" and
+// "push" commands:
+//
+//	buildAndPush := cli.Chain(build, push)
+//
+// Each command created by Command receives the flags that it declares, with
+// their values, and all the positional arguments, so the commands of a chain
+// may have different configuration structs; flags declared by several
+// commands are given to each of them. Flags declared by none of the commands
+// are reported as usage errors of the first one. Other commands, like command
+// sets, receive all the arguments. All the commands are called with the same
+// environment.
+//
+// The help message of the chain is the one of its first command, listing the
+// options of all the commands.
+//
+// The function panics if no commands are given.
+func Chain(cmds ...Function) Function {
+	if len(cmds) == 0 {
+		panic("cli.Chain: expected at least one command")
+	}
+	return &chainCommand{wrappedCommand{cmds[0]}, cmds}
+}
+
+type chainCommand struct {
+	wrappedCommand
+	cmds []Function
+}
+
+func (c *chainCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	for i, args := range c.split(args) {
+		code, err := c.cmds[i].Call(ctx, args, env)
+		// The errors of the commands themselves are reported for the
+		// chain, so their messages list the options of all the commands.
+		switch e := callError(err).(type) {
+		case *Help:
+			switch x := e.Cmd.(type) {
+			case *showAll:
+				if c.owns(i, x.cmd) {
+					e.Cmd = &showAll{wrappedCommand{c}}
+				}
+			default:
+				if c.owns(i, x) {
+					e.Cmd = c
+				}
+			}
+		case *Usage:
+			if c.owns(i, e.Cmd) {
+				e.Cmd = c
+			}
+		}
+		if code != 0 || err != nil {
+			return code, err
+		}
+	}
+	return 0, nil
+}
+
+// owns returns true if cmd is the command function of the i-th command of the
+// chain, rather than one of its sub-commands.
+func (c *chainCommand) owns(i int, cmd Function) bool {
+	fn := commandFuncOf(cmd)
+	return fn != nil && fn == commandFuncOf(c.cmds[i])
+}
+
+// split returns the arguments of each command of the chain: the flags that
+// they declare with their values, the positional arguments, and the arguments
+// following the "--" separator.
+func (c *chainCommand) split(args []string) [][]string {
+	fns := make([]*CommandFunc, len(c.cmds))
+	for i, cmd := range c.cmds {
+		if fns[i] = commandFuncOf(cmd); fns[i] != nil {
+			fns[i].configure()
+		}
+	}
+
+	argv := make([][]string, len(c.cmds))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if isCommandSeparator(arg) {
+			for j := range argv {
+				argv[j] = append(argv[j], args[i:]...)
+			}
+			break
+		}
+
+		owned := []string{arg}
+		owners := make([]bool, len(fns))
+		declared := false
+
+		for j, fn := range fns {
+			if fn == nil || !isOption(arg) {
+				owners[j] = true
+				continue
+			}
+			if option, ok := fn.flag(arg); ok {
+				_, _, hasValue := splitNameValue(arg)
+				if !declared && !option.boolean && !hasValue && i+1 < len(args) && !isOption(args[i+1]) {
+					owned = append(owned, args[i+1])
+				}
+				owners[j], declared = true, true
+			}
+		}
+
+		if isOption(arg) && !declared {
+			owners[0] = true
+		}
+		i += len(owned) - 1
+
+		for j := range argv {
+			if owners[j] {
+				argv[j] = append(argv[j], owned...)
+			}
+		}
+	}
+	return argv
+}
+
+func (c *chainCommand) configure() {
+	for _, cmd := range c.cmds {
+		configure(cmd)
+	}
+}
+
+func (c *chainCommand) Format(w fmt.State, v rune) {
+	if v == 'v' && !w.Flag('#') {
+		if fn := c.merged(); fn != nil {
+			fn.Format(w, v)
+			return
+		}
+	}
+	c.wrappedCommand.Format(w, v)
+}
+
+// merged returns a copy of the first command of the chain which declares the
+// options of all the commands, for its help message, or nil if the first
+// command was not created by Command.
+func (c *chainCommand) merged() *CommandFunc {
+	first := commandFuncOf(c.cmds[0])
+	if first == nil {
+		return nil
+	}
+	first.configure()
+
+	merged := *first
+	merged.options = make(structDecoder, len(first.options))
+	for _, cmd := range c.cmds {
+		fn := commandFuncOf(cmd)
+		if fn == nil {
+			continue
+		}
+		fn.configure()
+		for name, field := range fn.options {
+			if _, exists := merged.options[name]; !exists {
+				merged.options[name] = field
+			}
+		}
+	}
+	return &merged
+}
+
+// ArgAlias returns a Function which calls target with the arguments rewritten
+// by rewrite, which may be used to keep supporting legacy invocations of a
+// command, like old flag names or positional arguments in a different order:
+//
+//	cmd := cli.CommandSet{
+//		"get": get,
+//		// "fetch <name> <namespace>" is the legacy form of
+//		// "get --namespace <namespace> <name>".
+//		"fetch": cli.ArgAlias(get, func(args []string) []string {
+//			if len(args) == 2 {
+//				return []string{"--namespace", args[1], args[0]}
+//			}
+//			return args
+//		}),
+//	}
+//
+// The help messages and settings of the returned Function are the ones of
+// target.
+func ArgAlias(target Function, rewrite func(args []string) []string) Function {
+	return &argAliasCommand{wrappedCommand{target}, rewrite}
+}
+
+type argAliasCommand struct {
+	wrappedCommand
+	rewrite func([]string) []string
+}
+
+func (c *argAliasCommand) Call(ctx context.Context, args, env []string) (int, error) {
+	return c.cmd.Call(ctx, c.rewrite(append([]string(nil), args...)), env)
+}